@@ -0,0 +1,107 @@
+package analytics
+
+import (
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/danilsgit/gym-stats-backend/e1rm"
+	"github.com/danilsgit/gym-stats-backend/models"
+	"github.com/danilsgit/gym-stats-backend/records"
+)
+
+// Períodos de la progresión de un ejercicio
+const (
+	BucketDay   = "day"
+	BucketWeek  = "week" // Semana ISO
+	BucketMonth = "month"
+)
+
+var ErrInvalidBucket = errors.New("bucket debe ser day, week o month")
+
+// ProgressPoint resume los sets de un ejercicio en un período
+type ProgressPoint struct {
+	Period       string   `json:"period"` // 2024-02-12, 2024-W07 o 2024-02 según el período
+	Start        string   `json:"start"`  // Primer día del período (AAAA-MM-DD)
+	Sets         int      `json:"sets"`
+	TopSetWeight float64  `json:"topSetWeight"` // Mayor peso levantado
+	TopSetReps   int      `json:"topSetReps"`   // Repeticiones del set con mayor peso
+	Volume       float64  `json:"volume"`       // Suma de reps x peso
+	BestE1RM     *float64 `json:"bestE1rm"`     // Mejor 1RM estimado (Epley); null si todos los sets son sin peso
+	RepPRs       int      `json:"repPrs"`       // Récords de repeticiones, con las mismas reglas que /users/records
+}
+
+// ValidBucket indica si el período existe
+func ValidBucket(bucket string) bool {
+	switch bucket {
+	case BucketDay, BucketWeek, BucketMonth:
+		return true
+	}
+	return false
+}
+
+// bucketStart devuelve el primer día del período de t y su nombre
+func bucketStart(t time.Time, bucket string) (time.Time, string) {
+	t = t.UTC()
+	switch bucket {
+	case BucketWeek:
+		start := WeekStart(t)
+		return start, isoWeek(start)
+	case BucketMonth:
+		start := time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+		return start, start.Format("2006-01")
+	default:
+		start := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+		return start, start.Format("2006-01-02")
+	}
+}
+
+// Progress agrupa los sets de un ejercicio por período, en orden cronológico. Solo se devuelven
+// los períodos con sets. Los récords de repeticiones se detectan recorriendo todos los sets en
+// orden, así que entries debe incluir el historial desde el principio para que sean exactos.
+func Progress(entries []SetEntry, bucket string, from time.Time, to time.Time) []ProgressPoint {
+	sorted := append([]SetEntry(nil), entries...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].PerformedAt.Before(sorted[j].PerformedAt)
+	})
+
+	var points []ProgressPoint
+	var current []models.PersonalRecord
+	for _, entry := range sorted {
+		if entry.Reps <= 0 {
+			continue
+		}
+
+		// Récords de repeticiones contra todo el historial anterior
+		repPRs := 0
+		beaten := records.Detect(current, models.WorkoutSet{Reps: entry.Reps, Weight: entry.Weight, PerformedAt: entry.PerformedAt})
+		for _, record := range beaten {
+			if record.Type == records.TypeReps {
+				repPRs++
+			}
+		}
+		current = records.Current(append(current, beaten...))
+
+		if entry.PerformedAt.Before(from) || entry.PerformedAt.After(to) {
+			continue
+		}
+
+		start, period := bucketStart(entry.PerformedAt, bucket)
+		if len(points) == 0 || points[len(points)-1].Period != period {
+			points = append(points, ProgressPoint{Period: period, Start: start.Format("2006-01-02")})
+		}
+		point := &points[len(points)-1]
+		point.Sets++
+		point.Volume += float64(entry.Reps) * entry.Weight
+		point.RepPRs += repPRs
+		if entry.Weight > point.TopSetWeight || (entry.Weight == point.TopSetWeight && entry.Reps > point.TopSetReps) {
+			point.TopSetWeight, point.TopSetReps = entry.Weight, entry.Reps
+		}
+		if estimate, err := e1rm.Estimate(e1rm.Epley, entry.Reps, entry.Weight, 0); err == nil {
+			if point.BestE1RM == nil || estimate > *point.BestE1RM {
+				point.BestE1RM = &estimate
+			}
+		}
+	}
+	return points
+}
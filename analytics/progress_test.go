@@ -0,0 +1,92 @@
+package analytics
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func float(value float64) *float64 {
+	return &value
+}
+
+// progressEntries son los sets de las pruebas de Progress, desordenados a propósito
+var progressEntries = []SetEntry{
+	{PerformedAt: date("2024-02-18T23:30:00Z"), Reps: 3, Weight: 120}, // Récord: primer set con 120
+	{PerformedAt: date("2024-02-05T10:00:00Z"), Reps: 5, Weight: 100}, // Antes de from: solo cuenta para los récords
+	{PerformedAt: date("2024-02-20T10:00:00Z"), Reps: 10, Weight: 0},  // Récord: primer set sin peso
+	{PerformedAt: date("2024-02-12T10:00:00Z"), Reps: 5, Weight: 100}, // Iguala el récord de 2024-02-05, no lo supera
+	{PerformedAt: date("2024-02-19T10:00:00Z"), Reps: 0, Weight: 100}, // Sin repeticiones: no cuenta
+	{PerformedAt: date("2024-02-26T10:00:00Z"), Reps: 8, Weight: 100}, // Después de to
+	{PerformedAt: date("2024-02-18T23:00:00Z"), Reps: 6, Weight: 100}, // Récord: supera los 5 con 100
+}
+
+func TestProgress(t *testing.T) {
+	from, to := date("2024-02-12T00:00:00Z"), date("2024-02-25T23:59:59Z")
+	tests := []struct {
+		bucket string
+		points []ProgressPoint
+	}{
+		{BucketWeek, []ProgressPoint{
+			{Period: "2024-W07", Start: "2024-02-12", Sets: 3, TopSetWeight: 120, TopSetReps: 3, Volume: 1460, BestE1RM: float(132), RepPRs: 2},
+			{Period: "2024-W08", Start: "2024-02-19", Sets: 1, TopSetWeight: 0, TopSetReps: 10, Volume: 0, BestE1RM: nil, RepPRs: 1},
+		}},
+		{BucketDay, []ProgressPoint{
+			{Period: "2024-02-12", Start: "2024-02-12", Sets: 1, TopSetWeight: 100, TopSetReps: 5, Volume: 500, BestE1RM: float(116.7), RepPRs: 0},
+			{Period: "2024-02-18", Start: "2024-02-18", Sets: 2, TopSetWeight: 120, TopSetReps: 3, Volume: 960, BestE1RM: float(132), RepPRs: 2},
+			{Period: "2024-02-20", Start: "2024-02-20", Sets: 1, TopSetWeight: 0, TopSetReps: 10, Volume: 0, BestE1RM: nil, RepPRs: 1},
+		}},
+		{BucketMonth, []ProgressPoint{
+			{Period: "2024-02", Start: "2024-02-01", Sets: 4, TopSetWeight: 120, TopSetReps: 3, Volume: 1460, BestE1RM: float(132), RepPRs: 3},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.bucket, func(t *testing.T) {
+			points := Progress(progressEntries, tt.bucket, from, to)
+			if !reflect.DeepEqual(points, tt.points) {
+				t.Fatalf("puntos:\n%s\nse esperaban:\n%s", formatPoints(points), formatPoints(tt.points))
+			}
+		})
+	}
+}
+
+// El historial anterior a from cuenta para los récords aunque no se devuelva: sin él, el set del
+// 2024-02-12 sería un récord
+func TestProgressRecordsNeedHistory(t *testing.T) {
+	from, to := date("2024-02-12T00:00:00Z"), date("2024-02-12T23:59:59Z")
+
+	points := Progress(progressEntries, BucketDay, from, to)
+	if len(points) != 1 || points[0].RepPRs != 0 {
+		t.Fatalf("con historial: %s", formatPoints(points))
+	}
+
+	var recent []SetEntry
+	for _, entry := range progressEntries {
+		if !entry.PerformedAt.Before(from) {
+			recent = append(recent, entry)
+		}
+	}
+	points = Progress(recent, BucketDay, from, to)
+	if len(points) != 1 || points[0].RepPRs != 1 {
+		t.Fatalf("sin historial: %s", formatPoints(points))
+	}
+}
+
+func TestProgressWithoutSets(t *testing.T) {
+	if points := Progress(nil, BucketWeek, date("2024-02-12T00:00:00Z"), date("2024-02-25T23:59:59Z")); len(points) != 0 {
+		t.Fatalf("se esperaba una serie vacía: %+v", points)
+	}
+}
+
+// formatPoints muestra los puntos con el valor de BestE1RM en lugar del puntero
+func formatPoints(points []ProgressPoint) string {
+	var out string
+	for _, point := range points {
+		e1rm := "nil"
+		if point.BestE1RM != nil {
+			e1rm = fmt.Sprint(*point.BestE1RM)
+		}
+		out += fmt.Sprintf("  %+v bestE1rm=%s\n", point, e1rm)
+	}
+	return out
+}
@@ -0,0 +1,168 @@
+// Package analytics agrega los sets de un usuario en estadísticas de entrenamiento: el volumen
+// por semana ISO y grupo muscular, y la progresión de un ejercicio.
+package analytics
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Agrupaciones del volumen semanal
+const (
+	GroupByMuscle   = "muscle"   // Por músculo principal del ejercicio del catálogo
+	GroupByExercise = "exercise" // Por nombre del ejercicio
+	GroupByTotal    = "total"    // Solo los totales de la semana
+)
+
+// Origen de los sets
+const (
+	SourceLogged  = "logged"  // Los sets registrados en las sesiones de entrenamiento
+	SourcePlanned = "planned" // Los sets de la rutina de cada sesión, tal como están planificados
+)
+
+// MuscleUnassigned agrupa los sets de ejercicios que no están vinculados al catálogo o no tienen
+// músculos principales
+const MuscleUnassigned = "unassigned"
+
+var (
+	ErrInvalidGroupBy = errors.New("groupBy debe ser muscle, exercise o total")
+	ErrInvalidSource  = errors.New("source debe ser logged o planned")
+)
+
+// SetEntry es un set para las estadísticas, venga de un WorkoutSet o de un Set de la rutina
+type SetEntry struct {
+	PerformedAt time.Time
+	Exercise    string
+	Muscles     []string // Músculos principales según el catálogo
+	Reps        int
+	Weight      float64
+}
+
+// GroupVolume es el volumen de un músculo o ejercicio en una semana
+type GroupVolume struct {
+	Key     string  `json:"key"`
+	Sets    int     `json:"sets"` // Sets con al menos una repetición
+	Reps    int     `json:"reps"`
+	Tonnage float64 `json:"tonnage"` // Suma de reps x peso
+}
+
+// WeekVolume es el volumen de una semana ISO. Los totales cuentan cada set una vez, aunque
+// trabaje varios músculos.
+type WeekVolume struct {
+	Week    string        `json:"week"`  // Semana ISO, por ejemplo 2024-W07
+	Start   string        `json:"start"` // Lunes de la semana (AAAA-MM-DD)
+	Sets    int           `json:"sets"`
+	Reps    int           `json:"reps"`
+	Tonnage float64       `json:"tonnage"`
+	Groups  []GroupVolume `json:"groups,omitempty"`
+}
+
+// ValidGroupBy indica si la agrupación existe
+func ValidGroupBy(groupBy string) bool {
+	switch groupBy {
+	case GroupByMuscle, GroupByExercise, GroupByTotal:
+		return true
+	}
+	return false
+}
+
+// ValidSource indica si el origen existe
+func ValidSource(source string) bool {
+	return source == SourceLogged || source == SourcePlanned
+}
+
+// WeekStart devuelve el lunes a las 00:00 (UTC) de la semana ISO de t
+func WeekStart(t time.Time) time.Time {
+	t = t.UTC()
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	offset := (int(day.Weekday()) + 6) % 7 // Lunes = 0
+	return day.AddDate(0, 0, -offset)
+}
+
+// isoWeek devuelve la semana ISO de t como 2024-W07
+func isoWeek(t time.Time) string {
+	year, week := t.UTC().ISOWeek()
+	return fmt.Sprintf("%d-W%02d", year, week)
+}
+
+// WeeklyVolume agrega los sets por semana ISO entre from y to (incluidas las semanas sin sets,
+// para poder graficarlas) y, según groupBy, por músculo o ejercicio. Solo cuentan los sets con al
+// menos una repetición; no se distingue su esfuerzo, así que no son sets efectivos (hard sets).
+func WeeklyVolume(entries []SetEntry, from time.Time, to time.Time, groupBy string) []WeekVolume {
+	var weeks []WeekVolume
+	index := map[string]int{}
+	for start := WeekStart(from); !start.After(to); start = start.AddDate(0, 0, 7) {
+		index[start.Format("2006-01-02")] = len(weeks)
+		weeks = append(weeks, WeekVolume{Week: isoWeek(start), Start: start.Format("2006-01-02")})
+	}
+
+	groups := make([]map[string]*GroupVolume, len(weeks))
+	for _, entry := range entries {
+		if entry.Reps <= 0 || entry.PerformedAt.Before(from) || entry.PerformedAt.After(to) {
+			continue
+		}
+		i, ok := index[WeekStart(entry.PerformedAt).Format("2006-01-02")]
+		if !ok {
+			continue
+		}
+		tonnage := float64(entry.Reps) * entry.Weight
+		weeks[i].Sets++
+		weeks[i].Reps += entry.Reps
+		weeks[i].Tonnage += tonnage
+
+		if groupBy == GroupByTotal {
+			continue
+		}
+		if groups[i] == nil {
+			groups[i] = map[string]*GroupVolume{}
+		}
+		for _, key := range groupKeys(entry, groupBy) {
+			group := groups[i][key]
+			if group == nil {
+				group = &GroupVolume{Key: key}
+				groups[i][key] = group
+			}
+			group.Sets++
+			group.Reps += entry.Reps
+			group.Tonnage += tonnage
+		}
+	}
+
+	for i := range weeks {
+		for _, group := range groups[i] {
+			weeks[i].Groups = append(weeks[i].Groups, *group)
+		}
+		// De mayor a menor volumen; el nombre desempata para que el orden sea estable
+		sort.Slice(weeks[i].Groups, func(a, b int) bool {
+			ga, gb := weeks[i].Groups[a], weeks[i].Groups[b]
+			if ga.Sets != gb.Sets {
+				return ga.Sets > gb.Sets
+			}
+			return ga.Key < gb.Key
+		})
+	}
+	return weeks
+}
+
+// groupKeys devuelve los grupos en los que cuenta el set: uno por cada músculo principal, o el
+// nombre del ejercicio
+func groupKeys(entry SetEntry, groupBy string) []string {
+	if groupBy == GroupByExercise {
+		return []string{strings.TrimSpace(entry.Exercise)}
+	}
+	var keys []string
+	seen := map[string]bool{}
+	for _, muscle := range entry.Muscles {
+		if muscle != "" && !seen[muscle] {
+			seen[muscle] = true
+			keys = append(keys, muscle)
+		}
+	}
+	if len(keys) == 0 {
+		return []string{MuscleUnassigned}
+	}
+	return keys
+}
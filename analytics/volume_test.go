@@ -0,0 +1,127 @@
+package analytics
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func date(value string) time.Time {
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+// Cada set cae en la semana ISO de su fecha en UTC, incluso en los cambios de año
+func TestWeeklyVolumeISOWeeks(t *testing.T) {
+	bogota := time.FixedZone("UTC-5", -5*60*60)
+	tests := []struct {
+		name        string
+		performedAt time.Time
+		from, to    time.Time
+		week        string
+		start       string
+	}{
+		{"domingo al final del día", date("2024-02-18T23:59:59Z"), date("2024-02-05T00:00:00Z"), date("2024-02-25T23:59:59Z"), "2024-W07", "2024-02-12"},
+		{"lunes al inicio del día", date("2024-02-19T00:00:00Z"), date("2024-02-05T00:00:00Z"), date("2024-02-25T23:59:59Z"), "2024-W08", "2024-02-19"},
+		{"domingo en otra zona horaria ya es lunes en UTC", time.Date(2024, 2, 18, 22, 0, 0, 0, bogota), date("2024-02-05T00:00:00Z"), date("2024-02-25T23:59:59Z"), "2024-W08", "2024-02-19"},
+		{"último domingo de 2024", date("2024-12-29T12:00:00Z"), date("2024-12-16T00:00:00Z"), date("2025-01-12T23:59:59Z"), "2024-W52", "2024-12-23"},
+		{"30 de diciembre de 2024 es de 2025", date("2024-12-30T12:00:00Z"), date("2024-12-16T00:00:00Z"), date("2025-01-12T23:59:59Z"), "2025-W01", "2024-12-30"},
+		{"3 de enero de 2021 es de 2020", date("2021-01-03T12:00:00Z"), date("2020-12-21T00:00:00Z"), date("2021-01-10T23:59:59Z"), "2020-W53", "2020-12-28"},
+		{"1 de enero de 2027 es de 2026", date("2027-01-01T12:00:00Z"), date("2026-12-21T00:00:00Z"), date("2027-01-10T23:59:59Z"), "2026-W53", "2026-12-28"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entries := []SetEntry{{PerformedAt: tt.performedAt, Exercise: "Sentadilla", Reps: 5, Weight: 100}}
+			weeks := WeeklyVolume(entries, tt.from, tt.to, GroupByTotal)
+
+			found := false
+			for _, week := range weeks {
+				if week.Week != tt.week {
+					if week.Sets != 0 {
+						t.Fatalf("el set cayó en %s, se esperaba %s", week.Week, tt.week)
+					}
+					continue
+				}
+				found = true
+				if week.Start != tt.start || week.Sets != 1 || week.Reps != 5 || week.Tonnage != 500 {
+					t.Fatalf("semana inesperada: %+v", week)
+				}
+			}
+			if !found {
+				t.Fatalf("falta la semana %s en %+v", tt.week, weeks)
+			}
+		})
+	}
+}
+
+// Las semanas sin sets se incluyen y los sets fuera del rango o sin repeticiones no cuentan
+func TestWeeklyVolumeRange(t *testing.T) {
+	from, to := date("2024-12-23T00:00:00Z"), date("2025-01-12T23:59:59Z")
+	entries := []SetEntry{
+		{PerformedAt: date("2024-12-22T12:00:00Z"), Reps: 5, Weight: 100}, // Antes de from
+		{PerformedAt: date("2024-12-24T12:00:00Z"), Reps: 5, Weight: 100},
+		{PerformedAt: date("2024-12-24T12:05:00Z"), Reps: 0, Weight: 100}, // Sin repeticiones
+		{PerformedAt: date("2025-01-13T00:00:00Z"), Reps: 5, Weight: 100}, // Después de to
+	}
+
+	var got []string
+	for _, week := range WeeklyVolume(entries, from, to, GroupByTotal) {
+		got = append(got, week.Week)
+		if week.Week == "2024-W52" && week.Sets != 1 {
+			t.Fatalf("2024-W52 tiene %d sets, se esperaba 1", week.Sets)
+		}
+		if week.Week != "2024-W52" && week.Sets != 0 {
+			t.Fatalf("%s tiene %d sets, se esperaban 0", week.Week, week.Sets)
+		}
+		if week.Groups != nil {
+			t.Fatalf("groupBy total no devuelve grupos: %+v", week.Groups)
+		}
+	}
+	if want := []string{"2024-W52", "2025-W01", "2025-W02"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("semanas %v, se esperaban %v", got, want)
+	}
+}
+
+// Un set cuenta en cada uno de sus músculos principales, pero una sola vez en los totales
+func TestWeeklyVolumeGroups(t *testing.T) {
+	from, to := date("2024-02-12T00:00:00Z"), date("2024-02-18T23:59:59Z")
+	performedAt := date("2024-02-14T12:00:00Z")
+	entries := []SetEntry{
+		{PerformedAt: performedAt, Exercise: "Sentadilla", Muscles: []string{"quadriceps", "glutes", "quadriceps"}, Reps: 5, Weight: 100},
+		{PerformedAt: performedAt, Exercise: "Sentadilla", Muscles: []string{"quadriceps", "glutes"}, Reps: 5, Weight: 100},
+		{PerformedAt: performedAt, Exercise: "Curl", Reps: 10, Weight: 10},
+	}
+
+	tests := []struct {
+		groupBy string
+		groups  []GroupVolume
+	}{
+		{GroupByMuscle, []GroupVolume{
+			{Key: "glutes", Sets: 2, Reps: 10, Tonnage: 1000},
+			{Key: "quadriceps", Sets: 2, Reps: 10, Tonnage: 1000},
+			{Key: MuscleUnassigned, Sets: 1, Reps: 10, Tonnage: 100},
+		}},
+		{GroupByExercise, []GroupVolume{
+			{Key: "Sentadilla", Sets: 2, Reps: 10, Tonnage: 1000},
+			{Key: "Curl", Sets: 1, Reps: 10, Tonnage: 100},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.groupBy, func(t *testing.T) {
+			weeks := WeeklyVolume(entries, from, to, tt.groupBy)
+			if len(weeks) != 1 {
+				t.Fatalf("%d semanas, se esperaba 1", len(weeks))
+			}
+			week := weeks[0]
+			if week.Sets != 3 || week.Reps != 20 || week.Tonnage != 1100 {
+				t.Fatalf("totales inesperados: %+v", week)
+			}
+			if !reflect.DeepEqual(week.Groups, tt.groups) {
+				t.Fatalf("grupos %+v, se esperaban %+v", week.Groups, tt.groups)
+			}
+		})
+	}
+}
@@ -0,0 +1,36 @@
+// Package catalog contiene el catálogo de ejercicios incluido en el binario y su carga en la base de datos.
+package catalog
+
+import (
+	_ "embed"
+	"encoding/json"
+
+	"github.com/danilsgit/gym-stats-backend/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+//go:embed exercises.json
+var exercisesJSON []byte
+
+// Definitions devuelve las definiciones del catálogo incluido
+func Definitions() ([]models.ExerciseDefinition, error) {
+	var definitions []models.ExerciseDefinition
+	if err := json.Unmarshal(exercisesJSON, &definitions); err != nil {
+		return nil, err
+	}
+	return definitions, nil
+}
+
+// Seed inserta o actualiza (por slug) las definiciones del catálogo incluido
+func Seed(db *gorm.DB) error {
+	definitions, err := Definitions()
+	if err != nil {
+		return err
+	}
+
+	return db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "slug"}},
+		DoUpdates: clause.AssignmentColumns([]string{"updated_at", "name_en", "name_es", "primary_muscles", "secondary_muscles", "equipment", "movement_pattern", "aliases"}),
+	}).Create(&definitions).Error
+}
@@ -0,0 +1,114 @@
+// Package e1rm estima el máximo a una repetición (1RM) a partir de las repeticiones y el peso de un set.
+package e1rm
+
+import (
+	"errors"
+	"math"
+)
+
+// Fórmulas disponibles
+const (
+	Epley    = "epley"
+	Brzycki  = "brzycki"
+	Lombardi = "lombardi"
+	RPE      = "rpe"
+)
+
+// Formulas lista las fórmulas en el orden en que se devuelven
+var Formulas = []string{Epley, Brzycki, Lombardi, RPE}
+
+var (
+	ErrUnknownFormula = errors.New("fórmula de 1RM desconocida")
+	ErrInvalidReps    = errors.New("las repeticiones están fuera del rango de la fórmula")
+	ErrInvalidWeight  = errors.New("el peso debe ser mayor a cero")
+	ErrInvalidRPE     = errors.New("el RPE debe estar entre 6 y 10 en pasos de 0.5")
+)
+
+// Límites de la tabla de RPE
+const (
+	maxRPEReps = 12
+	minRPE     = 6.0
+	maxRPE     = 10.0
+)
+
+// rpePercentages es la tabla de RPE de Tuchscherer aplanada: cada repetición extra o cada
+// medio punto menos de RPE avanza una posición. 1 repetición a RPE 10 equivale al 100%.
+var rpePercentages = []float64{
+	100.0, 97.8, 95.5, 93.9, 92.2, 90.7, 89.2, 87.8, 86.3, 85.0, 83.7,
+	82.4, 81.1, 79.9, 78.6, 77.4, 76.2, 75.1, 73.9, 72.3, 70.7, 69.4,
+	68.0, 66.7, 65.3, 64.0, 62.6, 61.3, 59.9, 58.6, 57.4,
+}
+
+// Estimate calcula el 1RM con la fórmula indicada. El RPE solo se usa en la fórmula RPE.
+func Estimate(formula string, reps int, weight float64, rpe float64) (float64, error) {
+	if weight <= 0 {
+		return 0, ErrInvalidWeight
+	}
+	if reps <= 0 {
+		return 0, ErrInvalidReps
+	}
+
+	switch formula {
+	case Epley:
+		return round(epley(reps, weight)), nil
+	case Brzycki:
+		// La fórmula se indefine a partir de 37 repeticiones
+		if reps >= 37 {
+			return 0, ErrInvalidReps
+		}
+		return round(brzycki(reps, weight)), nil
+	case Lombardi:
+		return round(lombardi(reps, weight)), nil
+	case RPE:
+		percentage, err := Percentage(reps, rpe)
+		if err != nil {
+			return 0, err
+		}
+		return round(weight * 100 / percentage), nil
+	default:
+		return 0, ErrUnknownFormula
+	}
+}
+
+// Percentage devuelve el porcentaje del 1RM que corresponde a unas repeticiones con un RPE
+func Percentage(reps int, rpe float64) (float64, error) {
+	if reps <= 0 || reps > maxRPEReps {
+		return 0, ErrInvalidReps
+	}
+	// El RPE debe ser múltiplo de 0.5 dentro del rango de la tabla
+	if rpe < minRPE || rpe > maxRPE || math.Mod(rpe*2, 1) != 0 {
+		return 0, ErrInvalidRPE
+	}
+	index := (reps-1)*2 + int((maxRPE-rpe)*2)
+	return rpePercentages[index], nil
+}
+
+// Valid indica si la fórmula existe
+func Valid(formula string) bool {
+	for _, f := range Formulas {
+		if f == formula {
+			return true
+		}
+	}
+	return false
+}
+
+func epley(reps int, weight float64) float64 {
+	if reps == 1 {
+		return weight
+	}
+	return weight * (1 + float64(reps)/30)
+}
+
+func brzycki(reps int, weight float64) float64 {
+	return weight * 36 / float64(37-reps)
+}
+
+func lombardi(reps int, weight float64) float64 {
+	return weight * math.Pow(float64(reps), 0.1)
+}
+
+// round redondea a un decimal
+func round(value float64) float64 {
+	return math.Round(value*10) / 10
+}
@@ -0,0 +1,124 @@
+// Package mailer envía los correos de la aplicación (restablecer contraseña, verificar correo).
+// En producción se usa SMTP; en desarrollo los correos se escriben en un archivo o en el log.
+package mailer
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Message es un correo de texto plano
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer envía correos
+type Mailer interface {
+	Send(msg Message) error
+}
+
+// FromEnv crea el Mailer según MAIL_DRIVER: "smtp" usa SMTP_HOST, SMTP_PORT, SMTP_USERNAME,
+// SMTP_PASSWORD y MAIL_FROM; "log" escribe los correos en MAIL_LOG_FILE o en el log, y solo se
+// debe usar en desarrollo porque los correos llevan tokens de un solo uso. Sin un driver válido
+// devuelve un error, para no perder los correos (ni escribir sus tokens en el log) en producción.
+func FromEnv() (Mailer, error) {
+	switch driver := os.Getenv("MAIL_DRIVER"); driver {
+	case "smtp":
+		host := os.Getenv("SMTP_HOST")
+		if host == "" {
+			return nil, errors.New("MAIL_DRIVER es smtp pero falta SMTP_HOST")
+		}
+		from := os.Getenv("MAIL_FROM")
+		if _, err := mail.ParseAddress(from); err != nil {
+			return nil, fmt.Errorf("MAIL_FROM no es una dirección válida: %w", err)
+		}
+		port := os.Getenv("SMTP_PORT")
+		if port == "" {
+			port = "587"
+		}
+		return &SMTPMailer{
+			Host:     host,
+			Port:     port,
+			Username: os.Getenv("SMTP_USERNAME"),
+			Password: os.Getenv("SMTP_PASSWORD"),
+			From:     from,
+		}, nil
+	case "log":
+		return &LogMailer{Path: os.Getenv("MAIL_LOG_FILE")}, nil
+	case "":
+		return nil, errors.New("falta MAIL_DRIVER (smtp, o log en desarrollo)")
+	default:
+		return nil, fmt.Errorf("MAIL_DRIVER %q no es válido (smtp o log)", driver)
+	}
+}
+
+// SMTPMailer envía los correos por SMTP con autenticación PLAIN (STARTTLS si el servidor lo ofrece)
+type SMTPMailer struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	From     string // Dirección del remitente, con o sin nombre: "Gym Stats <no-reply@ejemplo.com>"
+}
+
+func (m *SMTPMailer) Send(msg Message) error {
+	// El sobre SMTP (MAIL FROM) lleva solo la dirección; el nombre va en la cabecera From
+	from, err := mail.ParseAddress(m.From)
+	if err != nil {
+		return fmt.Errorf("remitente no válido: %w", err)
+	}
+	var auth smtp.Auth
+	if m.Username != "" {
+		auth = smtp.PlainAuth("", m.Username, m.Password, m.Host)
+	}
+	return smtp.SendMail(net.JoinHostPort(m.Host, m.Port), auth, from.Address, []string{msg.To}, format(from.String(), msg))
+}
+
+// format arma el correo con sus cabeceras
+func format(from string, msg Message) []byte {
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", from)
+	fmt.Fprintf(&b, "To: %s\r\n", msg.To)
+	fmt.Fprintf(&b, "Subject: %s\r\n", msg.Subject)
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(strings.ReplaceAll(msg.Body, "\n", "\r\n"))
+	return []byte(b.String())
+}
+
+// LogMailer no envía los correos: los agrega a Path o, si está vacío, los escribe en el log
+type LogMailer struct {
+	Path string
+
+	mu sync.Mutex
+}
+
+func (m *LogMailer) Send(msg Message) error {
+	entry := fmt.Sprintf("--- %s\nPara: %s\nAsunto: %s\n\n%s\n\n", time.Now().Format(time.RFC3339), msg.To, msg.Subject, msg.Body)
+	if m.Path == "" {
+		log.Print("Correo no enviado (MAIL_DRIVER es log)\n" + entry)
+		return nil
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	file, err := os.OpenFile(m.Path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	_, err = file.WriteString(entry)
+	return err
+}
@@ -0,0 +1,155 @@
+package mailer
+
+import (
+	"bufio"
+	"net"
+	"strings"
+	"testing"
+)
+
+// smtpSession es lo que recibió el servidor SMTP de prueba
+type smtpSession struct {
+	mailFrom string
+	rcptTo   string
+	data     string
+}
+
+// newSMTPServer atiende una sesión SMTP sin TLS ni autenticación y la envía por el canal al terminar
+func newSMTPServer(t *testing.T) (string, string, <-chan smtpSession) {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	sessions := make(chan smtpSession, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		reader := bufio.NewReader(conn)
+		reply := func(line string) { conn.Write([]byte(line + "\r\n")) }
+
+		var session smtpSession
+		reply("220 localhost")
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				return
+			}
+			line = strings.TrimRight(line, "\r\n")
+			command := strings.ToUpper(line)
+			switch {
+			case strings.HasPrefix(command, "EHLO"), strings.HasPrefix(command, "HELO"):
+				reply("250 localhost")
+			case strings.HasPrefix(command, "MAIL FROM:"):
+				session.mailFrom = line[len("MAIL FROM:"):]
+				reply("250 OK")
+			case strings.HasPrefix(command, "RCPT TO:"):
+				session.rcptTo = line[len("RCPT TO:"):]
+				reply("250 OK")
+			case command == "DATA":
+				reply("354 Enviar datos")
+				var data strings.Builder
+				for {
+					dataLine, err := reader.ReadString('\n')
+					if err != nil {
+						return
+					}
+					if dataLine == ".\r\n" {
+						break
+					}
+					data.WriteString(dataLine)
+				}
+				session.data = data.String()
+				reply("250 OK")
+			case command == "QUIT":
+				reply("221 Adiós")
+				sessions <- session
+				return
+			default:
+				reply("502 No implementado")
+			}
+		}
+	}()
+
+	host, port, err := net.SplitHostPort(listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return host, port, sessions
+}
+
+// El sobre SMTP lleva solo la dirección del remitente; el nombre queda en la cabecera From
+func TestSMTPMailerSendsBareEnvelopeSender(t *testing.T) {
+	host, port, sessions := newSMTPServer(t)
+	m := &SMTPMailer{Host: host, Port: port, From: "Gym Stats <no-reply@ejemplo.com>"}
+
+	if err := m.Send(Message{To: "ana@example.com", Subject: "Hola", Body: "Cuerpo"}); err != nil {
+		t.Fatalf("enviar: %v", err)
+	}
+	session := <-sessions
+	if session.mailFrom != "<no-reply@ejemplo.com>" {
+		t.Fatalf("MAIL FROM %q, se esperaba <no-reply@ejemplo.com>", session.mailFrom)
+	}
+	if session.rcptTo != "<ana@example.com>" {
+		t.Fatalf("RCPT TO %q", session.rcptTo)
+	}
+	if !strings.Contains(session.data, "From: \"Gym Stats\" <no-reply@ejemplo.com>\r\n") {
+		t.Fatalf("cabecera From inesperada: %q", session.data)
+	}
+}
+
+func TestSMTPMailerRejectsInvalidSender(t *testing.T) {
+	m := &SMTPMailer{Host: "127.0.0.1", Port: "1", From: "Gym Stats"}
+	if err := m.Send(Message{To: "ana@example.com"}); err == nil {
+		t.Fatal("se esperaba un error con un remitente no válido")
+	}
+}
+
+// Sin un driver configurado no se usa el log en silencio: FromEnv devuelve un error
+func TestFromEnv(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		want    string // Tipo del Mailer; vacío si se espera un error
+		wantErr bool
+	}{
+		{"sin driver", map[string]string{}, "", true},
+		{"driver desconocido", map[string]string{"MAIL_DRIVER": "sendgrid"}, "", true},
+		{"log", map[string]string{"MAIL_DRIVER": "log"}, "log", false},
+		{"smtp", map[string]string{"MAIL_DRIVER": "smtp", "SMTP_HOST": "smtp.ejemplo.com", "MAIL_FROM": "Gym Stats <no-reply@ejemplo.com>"}, "smtp", false},
+		{"smtp sin host", map[string]string{"MAIL_DRIVER": "smtp", "MAIL_FROM": "no-reply@ejemplo.com"}, "", true},
+		{"smtp con remitente no válido", map[string]string{"MAIL_DRIVER": "smtp", "SMTP_HOST": "smtp.ejemplo.com", "MAIL_FROM": "Gym Stats"}, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, name := range []string{"MAIL_DRIVER", "MAIL_FROM", "MAIL_LOG_FILE", "SMTP_HOST", "SMTP_PORT", "SMTP_USERNAME", "SMTP_PASSWORD"} {
+				t.Setenv(name, tt.env[name])
+			}
+			m, err := FromEnv()
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("se esperaba un error, se obtuvo %T", m)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("error inesperado: %v", err)
+			}
+			switch m.(type) {
+			case *LogMailer:
+				if tt.want != "log" {
+					t.Fatalf("se obtuvo LogMailer, se esperaba %s", tt.want)
+				}
+			case *SMTPMailer:
+				if tt.want != "smtp" || m.(*SMTPMailer).Port != "587" {
+					t.Fatalf("SMTPMailer inesperado: %+v", m)
+				}
+			}
+		})
+	}
+}
@@ -5,55 +5,50 @@ import (
 	"net/http"
 	"os"
 
+	"github.com/danilsgit/gym-stats-backend/catalog"
 	"github.com/danilsgit/gym-stats-backend/db"
-	"github.com/danilsgit/gym-stats-backend/models"
+	"github.com/danilsgit/gym-stats-backend/migrations"
 	"github.com/danilsgit/gym-stats-backend/routes"
 	"github.com/gorilla/handlers"
-	"github.com/gorilla/mux"
 )
 
 func main() {
 
+	// Subcomando para administrar las migraciones: go run . migrate <up|down|status|create>
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		runMigrateCommand(os.Args[2:])
+		return
+	}
+
 	db.DBConnection()
 
-	db.DB.AutoMigrate(models.User{})
-	db.DB.AutoMigrate(models.Routine{})
-	db.DB.AutoMigrate(models.Exercise{})
-	db.DB.AutoMigrate(models.Set{})
+	// Aplicar las migraciones pendientes antes de atender solicitudes
+	applied, err := migrations.Up(db.DB)
+	if err != nil {
+		log.Fatal("Error aplicando las migraciones: ", err)
+	}
+	for _, migration := range applied {
+		log.Printf("Migración aplicada: %04d_%s", migration.Version, migration.Name)
+	}
 
-	r := mux.NewRouter()
+	// Cargar el catálogo global de ejercicios incluido en el binario
+	if err := catalog.Seed(db.DB); err != nil {
+		log.Println("Error cargando el catálogo de ejercicios:", err)
+	}
 
-	r.HandleFunc("/", routes.HomeHandler)
+	// Handlers con los stores sobre la conexión a Postgres
+	server, err := routes.NewServer(db.DB)
+	if err != nil {
+		log.Fatal("Error en la configuración: ", err)
+	}
 
-	// Usuario y autenticación
-	r.HandleFunc("/users", routes.PostUserHandler).Methods("POST")
-	r.HandleFunc("/login", routes.LoginHandler).Methods("POST")
-	r.HandleFunc("/login/social", routes.LoginSocialHandler).Methods("POST")
-	// Rutinas generales
-	r.HandleFunc("/routines", routes.GetRoutinesHandler).Methods("GET")
-	r.Handle("/routines/copy", routes.JwtAuthentication(http.HandlerFunc(routes.CopyRoutineHandler))).Methods("POST")
-	r.HandleFunc("/routines/{id}", routes.GetRoutineHandler).Methods("GET")
-	// Rutinas del usuario
-	// Sin autenticación
-	r.HandleFunc("/users/routines/{userId}", routes.GetRoutineByUserIdHandler).Methods("GET")
-	// Con autenticación
-	r.Handle("/users/routines", routes.JwtAuthentication(http.HandlerFunc(routes.GetUserRoutinesHandler))).Methods("GET")
-	r.Handle("/users/routines", routes.JwtAuthentication(http.HandlerFunc(routes.CreateUserRoutineHandler))).Methods("POST")
-	r.Handle("/users/routines/name", routes.JwtAuthentication(http.HandlerFunc(routes.UpdateNameUserRoutineHandler))).Methods("PUT")
-	r.Handle("/users/routines/description", routes.JwtAuthentication(http.HandlerFunc(routes.UpdateDescriptionUserRoutineHandler))).Methods("PUT")
-	r.Handle("/users/routines/{id}", routes.JwtAuthentication(http.HandlerFunc(routes.DeleteUserRoutineHandler))).Methods("DELETE")
-	// Ejercicios del usuario
-	r.Handle("/users/routines/exercises/name", routes.JwtAuthentication(http.HandlerFunc(routes.UpdateNameUserExerciseHandler))).Methods("PUT")
-	r.Handle("/users/routines/exercises", routes.JwtAuthentication(http.HandlerFunc(routes.CreateUserExerciseHandler))).Methods("POST")
-	r.Handle("/users/routines/exercises/{id}", routes.JwtAuthentication(http.HandlerFunc(routes.DeleteUserExerciseHandler))).Methods("DELETE")
-	r.Handle("/users/routines/exercises/sets", routes.JwtAuthentication(http.HandlerFunc(routes.UpdateUserExerciseHandler))).Methods("PUT")
-	// Configuración del usuario
-	r.Handle("/users/config/username", routes.JwtAuthentication(http.HandlerFunc(routes.PutUserInUsernameHandler))).Methods("PUT")
+	r := server.Router()
 
 	corsOpts := handlers.CORS(
 		handlers.AllowedOrigins([]string{"*"}),
 		handlers.AllowedMethods([]string{"GET", "POST", "PUT", "DELETE", "OPTIONS"}),
-		handlers.AllowedHeaders([]string{"X-Requested-With", "Content-Type", "Authorization"}),
+		handlers.AllowedHeaders([]string{"X-Requested-With", "Content-Type", "Authorization", routes.RequestIDHeader}),
+		handlers.ExposedHeaders([]string{routes.RequestIDHeader}),
 		handlers.AllowCredentials(),
 	)
 
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+
+	"github.com/danilsgit/gym-stats-backend/db"
+	"github.com/danilsgit/gym-stats-backend/migrations"
+)
+
+const migrateUsage = `Uso: go run . migrate <comando>
+
+Comandos:
+  up             Aplica todas las migraciones pendientes
+  down [pasos]   Revierte las últimas migraciones aplicadas (1 por defecto)
+  status         Muestra qué migraciones están aplicadas y cuáles pendientes
+  create <name>  Crea los archivos up/down de una nueva migración en ` + migrations.Dir
+
+// runMigrateCommand ejecuta el subcomando migrate
+func runMigrateCommand(args []string) {
+	if len(args) == 0 {
+		log.Fatal(migrateUsage)
+	}
+
+	switch args[0] {
+	case "up":
+		db.DBConnection()
+		applied, err := migrations.Up(db.DB)
+		for _, migration := range applied {
+			fmt.Printf("Aplicada   %04d_%s\n", migration.Version, migration.Name)
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+		if len(applied) == 0 {
+			fmt.Println("No hay migraciones pendientes")
+		}
+
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			var err error
+			steps, err = strconv.Atoi(args[1])
+			if err != nil || steps <= 0 {
+				log.Fatal("La cantidad de pasos debe ser un número mayor a cero")
+			}
+		}
+		db.DBConnection()
+		reverted, err := migrations.Down(db.DB, steps)
+		for _, migration := range reverted {
+			fmt.Printf("Revertida  %04d_%s\n", migration.Version, migration.Name)
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+
+	case "status":
+		db.DBConnection()
+		statuses, err := migrations.StatusOf(db.DB)
+		if err != nil {
+			log.Fatal(err)
+		}
+		for _, status := range statuses {
+			state := "pendiente"
+			if status.AppliedAt != nil {
+				state = "aplicada " + status.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%04d_%-30s %s\n", status.Version, status.Name, state)
+		}
+
+	case "create":
+		if len(args) < 2 {
+			log.Fatal("Falta el nombre de la migración")
+		}
+		upPath, downPath, err := migrations.Create(migrations.Dir, args[1])
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Println("Creada", upPath)
+		fmt.Println("Creada", downPath)
+
+	default:
+		log.Fatal(migrateUsage)
+	}
+}
@@ -0,0 +1,6 @@
+DROP TABLE IF EXISTS routine_work_exercise;
+DROP TABLE IF EXISTS user_make_routine;
+DROP TABLE IF EXISTS sets;
+DROP TABLE IF EXISTS exercises;
+DROP TABLE IF EXISTS routines;
+DROP TABLE IF EXISTS users;
@@ -0,0 +1,67 @@
+-- Esquema inicial: reproduce lo que creaba AutoMigrate para User, Routine, Exercise y Set.
+-- Usa IF NOT EXISTS para que las bases creadas con AutoMigrate adopten las migraciones sin cambios.
+
+CREATE TABLE IF NOT EXISTS users (
+    id varchar(36) PRIMARY KEY,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    username text NOT NULL,
+    email text NOT NULL,
+    password varchar(100),
+    role text DEFAULT 'user',
+    CONSTRAINT uni_users_username UNIQUE (username),
+    CONSTRAINT uni_users_email UNIQUE (email)
+);
+CREATE INDEX IF NOT EXISTS idx_users_deleted_at ON users (deleted_at);
+
+CREATE TABLE IF NOT EXISTS routines (
+    id bigserial PRIMARY KEY,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    name text,
+    description text,
+    public boolean DEFAULT true,
+    CONSTRAINT uni_routines_name UNIQUE (name)
+);
+CREATE INDEX IF NOT EXISTS idx_routines_deleted_at ON routines (deleted_at);
+
+CREATE TABLE IF NOT EXISTS exercises (
+    id bigserial PRIMARY KEY,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    name text NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_exercises_deleted_at ON exercises (deleted_at);
+
+CREATE TABLE IF NOT EXISTS sets (
+    id bigserial PRIMARY KEY,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    reps bigint NOT NULL,
+    weight numeric NOT NULL,
+    rest numeric NOT NULL,
+    note text,
+    exercise_id bigint,
+    CONSTRAINT fk_exercises_sets FOREIGN KEY (exercise_id) REFERENCES exercises (id)
+);
+CREATE INDEX IF NOT EXISTS idx_sets_deleted_at ON sets (deleted_at);
+
+CREATE TABLE IF NOT EXISTS user_make_routine (
+    user_id varchar(36),
+    routine_id bigint,
+    PRIMARY KEY (user_id, routine_id),
+    CONSTRAINT fk_user_make_routine_user FOREIGN KEY (user_id) REFERENCES users (id),
+    CONSTRAINT fk_user_make_routine_routine FOREIGN KEY (routine_id) REFERENCES routines (id)
+);
+
+CREATE TABLE IF NOT EXISTS routine_work_exercise (
+    routine_id bigint,
+    exercise_id bigint,
+    PRIMARY KEY (routine_id, exercise_id),
+    CONSTRAINT fk_routine_work_exercise_routine FOREIGN KEY (routine_id) REFERENCES routines (id),
+    CONSTRAINT fk_routine_work_exercise_exercise FOREIGN KEY (exercise_id) REFERENCES exercises (id)
+);
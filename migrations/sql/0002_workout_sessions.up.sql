@@ -0,0 +1,38 @@
+-- Sesiones de entrenamiento y los sets realizados en ellas
+
+CREATE TABLE IF NOT EXISTS workout_sessions (
+    id bigserial PRIMARY KEY,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    user_id varchar(36) NOT NULL,
+    routine_id bigint NOT NULL,
+    routine_name text,
+    started_at timestamptz NOT NULL,
+    finished_at timestamptz,
+    duration_seconds bigint,
+    notes text
+);
+CREATE INDEX IF NOT EXISTS idx_workout_sessions_deleted_at ON workout_sessions (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_workout_sessions_user_id ON workout_sessions (user_id);
+CREATE INDEX IF NOT EXISTS idx_workout_sessions_routine_id ON workout_sessions (routine_id);
+
+CREATE TABLE IF NOT EXISTS workout_sets (
+    id bigserial PRIMARY KEY,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    workout_session_id bigint NOT NULL,
+    exercise_id bigint NOT NULL,
+    exercise_name text,
+    set_id bigint,
+    reps bigint NOT NULL,
+    weight numeric NOT NULL,
+    rest numeric NOT NULL,
+    note varchar(255),
+    performed_at timestamptz NOT NULL,
+    CONSTRAINT fk_workout_sessions_sets FOREIGN KEY (workout_session_id) REFERENCES workout_sessions (id)
+);
+CREATE INDEX IF NOT EXISTS idx_workout_sets_deleted_at ON workout_sets (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_workout_sets_workout_session_id ON workout_sets (workout_session_id);
+CREATE INDEX IF NOT EXISTS idx_workout_sets_exercise_id ON workout_sets (exercise_id);
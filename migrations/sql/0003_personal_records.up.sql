@@ -0,0 +1,22 @@
+-- Historial de récords personales por ejercicio
+
+CREATE TABLE IF NOT EXISTS personal_records (
+    id bigserial PRIMARY KEY,
+    created_at timestamptz,
+    user_id varchar(36) NOT NULL,
+    exercise_id bigint NOT NULL,
+    exercise_name text,
+    type varchar(20) NOT NULL,
+    value numeric NOT NULL,
+    previous numeric,
+    reps bigint NOT NULL,
+    weight numeric NOT NULL,
+    workout_session_id bigint NOT NULL,
+    workout_set_id bigint NOT NULL,
+    achieved_at timestamptz NOT NULL,
+    CONSTRAINT fk_workout_sets_records FOREIGN KEY (workout_set_id) REFERENCES workout_sets (id)
+);
+CREATE INDEX IF NOT EXISTS idx_personal_records_user_id ON personal_records (user_id);
+CREATE INDEX IF NOT EXISTS idx_personal_records_exercise_id ON personal_records (exercise_id);
+CREATE INDEX IF NOT EXISTS idx_personal_records_workout_session_id ON personal_records (workout_session_id);
+CREATE INDEX IF NOT EXISTS idx_personal_records_workout_set_id ON personal_records (workout_set_id);
@@ -0,0 +1,2 @@
+ALTER TABLE exercises DROP COLUMN IF EXISTS definition_id;
+DROP TABLE IF EXISTS exercise_definitions;
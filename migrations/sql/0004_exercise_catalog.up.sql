@@ -0,0 +1,19 @@
+-- Catálogo global de ejercicios y su referencia desde los ejercicios de las rutinas
+
+CREATE TABLE IF NOT EXISTS exercise_definitions (
+    id bigserial PRIMARY KEY,
+    created_at timestamptz,
+    updated_at timestamptz,
+    slug text NOT NULL,
+    name_en text NOT NULL,
+    name_es text NOT NULL,
+    primary_muscles jsonb,
+    secondary_muscles jsonb,
+    equipment jsonb,
+    movement_pattern text,
+    aliases jsonb
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_exercise_definitions_slug ON exercise_definitions (slug);
+
+ALTER TABLE exercises ADD COLUMN IF NOT EXISTS definition_id bigint REFERENCES exercise_definitions (id);
+CREATE INDEX IF NOT EXISTS idx_exercises_definition_id ON exercises (definition_id);
@@ -0,0 +1 @@
+ALTER TABLE routines DROP COLUMN IF EXISTS forked_from_id;
@@ -0,0 +1,4 @@
+-- Linaje de las copias de rutinas
+
+ALTER TABLE routines ADD COLUMN IF NOT EXISTS forked_from_id bigint;
+CREATE INDEX IF NOT EXISTS idx_routines_forked_from_id ON routines (forked_from_id);
@@ -0,0 +1,25 @@
+-- Restaura la unicidad global del nombre de las rutinas. Tras la migración puede haber nombres
+-- repetidos entre usuarios (o con rutinas eliminadas): se les vuelven a agregar 4 dígitos al final,
+-- como se hacía antes, dejando el nombre original a la rutina más antigua.
+DROP INDEX IF EXISTS idx_routines_owner_name;
+
+DO $$
+DECLARE
+    candidate RECORD;
+    next_name text;
+BEGIN
+    FOR candidate IN
+        SELECT id, name FROM routines r
+        WHERE EXISTS (SELECT 1 FROM routines o WHERE o.name = r.name AND o.id < r.id)
+        ORDER BY id
+    LOOP
+        next_name := candidate.name || lpad((candidate.id % 10000)::text, 4, '0');
+        WHILE EXISTS (SELECT 1 FROM routines WHERE name = next_name) LOOP
+            next_name := next_name || lpad(floor(random() * 10000)::int::text, 4, '0');
+        END LOOP;
+        UPDATE routines SET name = next_name WHERE id = candidate.id;
+    END LOOP;
+END $$;
+
+ALTER TABLE routines ADD CONSTRAINT uni_routines_name UNIQUE (name);
+ALTER TABLE routines DROP COLUMN IF EXISTS owner_id;
@@ -0,0 +1,43 @@
+-- Cambia la unicidad global del nombre de las rutinas por una unicidad por dueño que ignora
+-- las rutinas eliminadas, y limpia los sufijos que se agregaban para evitar colisiones.
+
+ALTER TABLE routines ADD COLUMN IF NOT EXISTS owner_id varchar(36);
+CREATE INDEX IF NOT EXISTS idx_routines_owner_id ON routines (owner_id);
+
+-- GORM crea la restricción como uni_routines_name (versiones anteriores como índice idx_routines_name)
+ALTER TABLE routines DROP CONSTRAINT IF EXISTS uni_routines_name;
+DROP INDEX IF EXISTS idx_routines_name;
+
+UPDATE routines SET owner_id = umr.user_id
+FROM user_make_routine umr
+WHERE umr.routine_id = routines.id AND (routines.owner_id IS NULL OR routines.owner_id = '');
+
+-- Las rutinas eliminadas llevaban un UUID al final del nombre
+UPDATE routines
+SET name = regexp_replace(name, '[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$', '')
+WHERE deleted_at IS NOT NULL AND name ~ '.[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$';
+
+-- Las rutinas vigentes llevaban 4 dígitos aleatorios (una o varias veces). Solo se quitan si el
+-- nombre original lo usa otra rutina (por eso se agregaron) y el dueño no tiene ya uno igual.
+DO $$
+DECLARE
+    candidate RECORD;
+    base text;
+BEGIN
+    FOR candidate IN
+        SELECT id, name, owner_id FROM routines
+        WHERE deleted_at IS NULL AND name ~ '\D(\d{4})+$'
+        ORDER BY id
+    LOOP
+        base := regexp_replace(candidate.name, '(\d{4})+$', '');
+        IF EXISTS (SELECT 1 FROM routines WHERE name = base AND id <> candidate.id)
+            AND NOT EXISTS (
+                SELECT 1 FROM routines
+                WHERE owner_id = candidate.owner_id AND name = base AND deleted_at IS NULL
+            ) THEN
+            UPDATE routines SET name = base WHERE id = candidate.id;
+        END IF;
+    END LOOP;
+END $$;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_routines_owner_name ON routines (owner_id, name) WHERE deleted_at IS NULL;
@@ -0,0 +1,6 @@
+-- Verificación del correo de los usuarios
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS email_verified boolean NOT NULL DEFAULT false;
+
+-- Los usuarios registrados antes de la verificación quedan como verificados
+UPDATE users SET email_verified = true;
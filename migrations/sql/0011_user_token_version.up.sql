@@ -0,0 +1,3 @@
+-- Versión de los tokens de cada usuario: al cambiar la contraseña se invalidan los tokens emitidos antes
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS token_version bigint NOT NULL DEFAULT 0;
@@ -0,0 +1,3 @@
+-- Cuentas deshabilitadas por un administrador
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS disabled boolean NOT NULL DEFAULT false;
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS moderation_actions;
+DROP TABLE IF EXISTS routine_reports;
+ALTER TABLE routines DROP COLUMN IF EXISTS hidden;
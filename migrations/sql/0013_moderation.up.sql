@@ -0,0 +1,33 @@
+-- Moderación de rutinas públicas: rutinas ocultas, reportes y registro de auditoría
+
+ALTER TABLE routines ADD COLUMN IF NOT EXISTS hidden boolean NOT NULL DEFAULT false;
+
+CREATE TABLE IF NOT EXISTS routine_reports (
+    id bigserial PRIMARY KEY,
+    created_at timestamptz,
+    routine_id bigint NOT NULL REFERENCES routines (id),
+    reporter_id varchar(36) NOT NULL,
+    reason varchar(30) NOT NULL,
+    comment text,
+    status varchar(20) NOT NULL DEFAULT 'pending',
+    action varchar(30),
+    resolved_by varchar(36),
+    resolved_at timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_routine_reports_routine_id ON routine_reports (routine_id);
+CREATE INDEX IF NOT EXISTS idx_routine_reports_reporter_id ON routine_reports (reporter_id);
+CREATE INDEX IF NOT EXISTS idx_routine_reports_status ON routine_reports (status);
+
+CREATE TABLE IF NOT EXISTS moderation_actions (
+    id bigserial PRIMARY KEY,
+    created_at timestamptz,
+    moderator_id varchar(36) NOT NULL,
+    action varchar(30) NOT NULL,
+    routine_id bigint,
+    target_user_id varchar(36),
+    report_id bigint,
+    note text
+);
+CREATE INDEX IF NOT EXISTS idx_moderation_actions_moderator_id ON moderation_actions (moderator_id);
+CREATE INDEX IF NOT EXISTS idx_moderation_actions_routine_id ON moderation_actions (routine_id);
+CREATE INDEX IF NOT EXISTS idx_moderation_actions_target_user_id ON moderation_actions (target_user_id);
@@ -0,0 +1,11 @@
+DROP TRIGGER IF EXISTS users_search ON users;
+DROP TRIGGER IF EXISTS exercises_search ON exercises;
+DROP TRIGGER IF EXISTS user_make_routine_search ON user_make_routine;
+DROP TRIGGER IF EXISTS routine_work_exercise_search ON routine_work_exercise;
+DROP TRIGGER IF EXISTS routines_search_vector ON routines;
+DROP FUNCTION IF EXISTS refresh_routine_search();
+DROP FUNCTION IF EXISTS routines_search_vector();
+DROP INDEX IF EXISTS idx_routines_search_vector;
+ALTER TABLE routines DROP COLUMN IF EXISTS search_vector;
+DROP FUNCTION IF EXISTS search_text(text, "char");
+DROP FUNCTION IF EXISTS immutable_unaccent(text);
@@ -0,0 +1,103 @@
+-- Búsqueda de texto completo de rutinas públicas: un tsvector por rutina con su nombre (A), los
+-- nombres de sus ejercicios junto con los nombres y alias del catálogo (B, así "press banca"
+-- también encuentra "bench press"), su descripción (C) y el username del autor (D), en español
+-- e inglés y sin acentos. Lo mantienen actualizado los triggers de abajo.
+
+CREATE EXTENSION IF NOT EXISTS unaccent;
+
+-- unaccent no es IMMUTABLE; este envoltorio fija el diccionario para poder usarlo en índices y triggers
+CREATE OR REPLACE FUNCTION immutable_unaccent(value text) RETURNS text
+    LANGUAGE sql IMMUTABLE PARALLEL SAFE STRICT
+    AS $$ SELECT public.unaccent('public.unaccent'::regdictionary, value) $$;
+
+-- search_text normaliza un texto en español e inglés
+CREATE OR REPLACE FUNCTION search_text(value text, weight "char") RETURNS tsvector
+    LANGUAGE sql IMMUTABLE PARALLEL SAFE
+    AS $$
+        SELECT setweight(to_tsvector('spanish', immutable_unaccent(coalesce(value, ''))), weight) ||
+               setweight(to_tsvector('english', immutable_unaccent(coalesce(value, ''))), weight)
+    $$;
+
+ALTER TABLE routines ADD COLUMN IF NOT EXISTS search_vector tsvector;
+
+CREATE OR REPLACE FUNCTION routines_search_vector() RETURNS trigger
+    LANGUAGE plpgsql
+    AS $$
+    BEGIN
+        NEW.search_vector :=
+            search_text(NEW.name, 'A') ||
+            search_text((
+                SELECT string_agg(concat_ws(' ', e.name, d.name_es, d.name_en, (
+                    SELECT string_agg(alias, ' ') FROM jsonb_array_elements_text(CASE WHEN jsonb_typeof(d.aliases) = 'array' THEN d.aliases ELSE '[]' END) AS alias
+                )), ' ')
+                FROM routine_work_exercise rwe
+                JOIN exercises e ON e.id = rwe.exercise_id AND e.deleted_at IS NULL
+                LEFT JOIN exercise_definitions d ON d.id = e.definition_id
+                WHERE rwe.routine_id = NEW.id
+            ), 'B') ||
+            search_text(NEW.description, 'C') ||
+            setweight(to_tsvector('simple', immutable_unaccent(coalesce((
+                SELECT string_agg(u.username, ' ')
+                FROM user_make_routine umr
+                JOIN users u ON u.id = umr.user_id
+                WHERE umr.routine_id = NEW.id
+            ), ''))), 'D');
+        RETURN NEW;
+    END
+    $$;
+
+DROP TRIGGER IF EXISTS routines_search_vector ON routines;
+CREATE TRIGGER routines_search_vector
+    BEFORE INSERT OR UPDATE OF name, description, search_vector ON routines
+    FOR EACH ROW EXECUTE FUNCTION routines_search_vector();
+
+-- Las tablas relacionadas recalculan el vector poniéndolo en NULL; el trigger anterior lo rellena
+CREATE OR REPLACE FUNCTION refresh_routine_search() RETURNS trigger
+    LANGUAGE plpgsql
+    AS $$
+    DECLARE
+        row_data record;
+    BEGIN
+        IF TG_OP = 'DELETE' THEN
+            row_data := OLD;
+        ELSE
+            row_data := NEW;
+        END IF;
+
+        IF TG_TABLE_NAME IN ('routine_work_exercise', 'user_make_routine') THEN
+            UPDATE routines SET search_vector = NULL WHERE id = row_data.routine_id;
+        ELSIF TG_TABLE_NAME = 'exercises' THEN
+            UPDATE routines SET search_vector = NULL
+            WHERE id IN (SELECT routine_id FROM routine_work_exercise WHERE exercise_id = row_data.id);
+        ELSIF TG_TABLE_NAME = 'users' THEN
+            UPDATE routines SET search_vector = NULL
+            WHERE id IN (SELECT routine_id FROM user_make_routine WHERE user_id = row_data.id);
+        END IF;
+        RETURN NULL;
+    END
+    $$;
+
+DROP TRIGGER IF EXISTS routine_work_exercise_search ON routine_work_exercise;
+CREATE TRIGGER routine_work_exercise_search
+    AFTER INSERT OR DELETE ON routine_work_exercise
+    FOR EACH ROW EXECUTE FUNCTION refresh_routine_search();
+
+DROP TRIGGER IF EXISTS user_make_routine_search ON user_make_routine;
+CREATE TRIGGER user_make_routine_search
+    AFTER INSERT OR DELETE ON user_make_routine
+    FOR EACH ROW EXECUTE FUNCTION refresh_routine_search();
+
+DROP TRIGGER IF EXISTS exercises_search ON exercises;
+CREATE TRIGGER exercises_search
+    AFTER UPDATE OF name, definition_id, deleted_at ON exercises
+    FOR EACH ROW EXECUTE FUNCTION refresh_routine_search();
+
+DROP TRIGGER IF EXISTS users_search ON users;
+CREATE TRIGGER users_search
+    AFTER UPDATE OF username ON users
+    FOR EACH ROW EXECUTE FUNCTION refresh_routine_search();
+
+-- Calcular el vector de las rutinas existentes
+UPDATE routines SET search_vector = NULL;
+
+CREATE INDEX IF NOT EXISTS idx_routines_search_vector ON routines USING GIN (search_vector);
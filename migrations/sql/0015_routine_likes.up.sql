@@ -0,0 +1,9 @@
+-- Me gusta de los usuarios a las rutinas
+
+CREATE TABLE IF NOT EXISTS routine_likes (
+    user_id varchar(36) NOT NULL REFERENCES users (id),
+    routine_id bigint NOT NULL REFERENCES routines (id),
+    created_at timestamptz,
+    PRIMARY KEY (user_id, routine_id)
+);
+CREATE INDEX IF NOT EXISTS idx_routine_likes_routine_id ON routine_likes (routine_id);
@@ -0,0 +1,9 @@
+-- Correos pedidos por dirección, para limitarlos entre todas las instancias del servidor
+
+CREATE TABLE IF NOT EXISTS email_requests (
+    id bigserial PRIMARY KEY,
+    created_at timestamptz,
+    email text NOT NULL,
+    purpose varchar(30) NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_email_requests_email_purpose ON email_requests (email, purpose, created_at);
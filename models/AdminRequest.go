@@ -0,0 +1,19 @@
+package models
+
+// Deshabilitar o habilitar una cuenta
+type DisableUserRequest struct {
+	Disabled bool   `json:"disabled"`
+	Note     string `json:"note"`
+}
+
+// Ocultar o volver a mostrar una rutina
+type HideRoutineRequest struct {
+	Hidden bool   `json:"hidden"`
+	Note   string `json:"note"`
+}
+
+// Resolver un reporte: dismiss, hide, unpublish o suspend
+type ResolveReportRequest struct {
+	Action string `json:"action"`
+	Note   string `json:"note"`
+}
@@ -0,0 +1,50 @@
+package models
+
+// RegisterRequest es el cuerpo de POST /users
+type RegisterRequest struct {
+	Username string `json:"username"`
+	Email    string `json:"email"`
+	Password string `json:"password"`
+}
+
+// LoginRequest es el cuerpo de /login
+type LoginRequest struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+}
+
+// RefreshTokenRequest es el cuerpo de /auth/refresh y /auth/logout
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refreshToken"`
+}
+
+// SocialLoginRequest es el cuerpo de /login/social: el ID token que emitió el proveedor
+type SocialLoginRequest struct {
+	Provider string `json:"provider"`
+	IDToken  string `json:"idToken"`
+	// Username es opcional y solo se usa al crear la cuenta
+	Username string `json:"username"`
+}
+
+// ForgotPasswordRequest es el cuerpo de /auth/password/forgot
+type ForgotPasswordRequest struct {
+	Email string `json:"email"`
+}
+
+// ResetPasswordRequest es el cuerpo de /auth/password/reset
+type ResetPasswordRequest struct {
+	Token    string `json:"token"`
+	Password string `json:"password"`
+}
+
+// VerifyEmailRequest es el cuerpo de /auth/email/verify
+type VerifyEmailRequest struct {
+	Token string `json:"token"`
+}
+
+// UpdatePasswordRequest es el cuerpo de /users/config/password. CurrentPassword solo es
+// obligatoria si el usuario ya tiene contraseña.
+type UpdatePasswordRequest struct {
+	CurrentPassword string `json:"currentPassword"`
+	NewPassword     string `json:"newPassword"`
+}
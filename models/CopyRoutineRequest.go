@@ -1,7 +1,11 @@
 package models
 
+// Estructura de la solicitud anterior para copiar una rutina. Solo se usan el ID o, si no se
+// envía, el autor y el nombre para encontrar la rutina guardada; los ejercicios y los sets se
+// ignoran porque la copia se construye desde la rutina guardada.
 type CopyRoutineRequest struct {
-	UserId          string            `json:"userId"`
+	ID              uint              `json:"id"`     // ID de la rutina a copiar
+	UserId          string            `json:"userId"` // Autor de la rutina
 	Name            string            `json:"name"`
 	Description     string            `json:"description"`
 	Public          bool              `json:"public"`
@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+// EmailRequest registra un correo pedido para una dirección (restablecer la contraseña,
+// reenviar la verificación). Sirve para limitar cuántos se pueden pedir.
+type EmailRequest struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	CreatedAt time.Time `json:"createdAt"`
+	Email     string    `gorm:"not null" json:"email"`
+	Purpose   string    `gorm:"size:30;not null" json:"purpose"`
+}
@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+// ExerciseDefinition representa un ejercicio del catálogo global.
+// Los ejercicios de las rutinas la referencian para que "Bench Press" y "Press banca" sean el mismo ejercicio.
+type ExerciseDefinition struct {
+	ID               uint      `gorm:"primaryKey" json:"id"`
+	CreatedAt        time.Time `json:"createdAt"`
+	UpdatedAt        time.Time `json:"updatedAt"`
+	Slug             string    `gorm:"uniqueIndex;not null" json:"slug"`
+	NameEn           string    `gorm:"not null" json:"nameEn"`
+	NameEs           string    `gorm:"not null" json:"nameEs"`
+	PrimaryMuscles   []string  `gorm:"serializer:json;type:jsonb" json:"primaryMuscles"`
+	SecondaryMuscles []string  `gorm:"serializer:json;type:jsonb" json:"secondaryMuscles"`
+	Equipment        []string  `gorm:"serializer:json;type:jsonb" json:"equipment"`
+	MovementPattern  string    `json:"movementPattern"`
+	Aliases          []string  `gorm:"serializer:json;type:jsonb" json:"aliases"` // Otros nombres en inglés y español
+}
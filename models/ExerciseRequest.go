@@ -7,6 +7,8 @@ type ExerciseRequest struct {
 	IDRoutine uint `json:"idRoutine"`
 	// ID del ejercicio para actualizar (No es necesario para crear)
 	IDExercise uint `json:"idExercise"`
+	// ID del ejercicio del catálogo (opcional, si no se envía se busca por nombre)
+	DefinitionID uint `json:"definitionId"`
 	// Demás datos del ejercicio
 	Name string `json:"name"`
 	Sets []struct {
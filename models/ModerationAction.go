@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+// Acciones de moderación
+const (
+	ModerationDismiss   = "dismiss"
+	ModerationHide      = "hide"
+	ModerationUnhide    = "unhide"
+	ModerationUnpublish = "unpublish"
+	ModerationSuspend   = "suspend"
+	ModerationReinstate = "reinstate"
+)
+
+// ModerationAction es una entrada del registro de auditoría de moderación
+type ModerationAction struct {
+	ID           uint      `gorm:"primaryKey" json:"id"`
+	CreatedAt    time.Time `json:"createdAt"`
+	ModeratorID  string    `gorm:"size:36;not null;index" json:"moderatorId"`
+	Action       string    `gorm:"size:30;not null" json:"action"`
+	RoutineID    *uint     `gorm:"index" json:"routineId"`
+	TargetUserID *string   `gorm:"size:36;index" json:"targetUserId"`
+	ReportID     *uint     `json:"reportId"`
+	Note         string    `json:"note"`
+}
@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+// PersonalRecord representa un récord personal de un usuario en un ejercicio.
+// Se guarda el historial completo: el récord vigente es el mejor valor de cada tipo.
+type PersonalRecord struct {
+	ID               uint      `gorm:"primaryKey" json:"id"`
+	CreatedAt        time.Time `json:"createdAt"`
+	UserID           string    `gorm:"size:36;not null;index" json:"userId"`
+	ExerciseID       uint      `gorm:"not null;index" json:"exerciseId"`
+	ExerciseName     string    `json:"exerciseName"`
+	Type             string    `gorm:"size:20;not null" json:"type"` // weight, reps, e1rm o volume
+	Value            float64   `gorm:"not null" json:"value"`
+	Previous         float64   `json:"previous"` // Valor del récord superado (0 si es el primero)
+	Reps             int       `gorm:"not null" json:"reps"`
+	Weight           float64   `gorm:"not null" json:"weight"`
+	WorkoutSessionID uint      `gorm:"not null;index" json:"workoutSessionId"`
+	WorkoutSetID     uint      `gorm:"not null;index" json:"workoutSetId"` // Set de la sesión que marcó el récord
+	AchievedAt       time.Time `gorm:"not null" json:"achievedAt"`
+}
@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// RefreshToken es un token de renovación emitido al iniciar sesión. Solo se guarda su hash.
+// Cada uso lo rota por uno nuevo de la misma familia (sesión); si un token ya usado se vuelve
+// a presentar se revoca la familia completa.
+type RefreshToken struct {
+	ID        uint       `gorm:"primaryKey" json:"id"`
+	CreatedAt time.Time  `json:"createdAt"`
+	UserID    string     `gorm:"size:36;not null;index" json:"userId"`
+	FamilyID  string     `gorm:"size:36;not null;index" json:"familyId"`
+	TokenHash string     `gorm:"size:64;not null;uniqueIndex" json:"-"`
+	ExpiresAt time.Time  `gorm:"not null" json:"expiresAt"`
+	UsedAt    *time.Time `json:"usedAt"`    // Cuándo se rotó por uno nuevo
+	RevokedAt *time.Time `json:"revokedAt"` // Cuándo se cerró la sesión
+}
@@ -8,13 +8,19 @@ import (
 
 // Routine representa una rutina en la base de datos
 type Routine struct {
-	ID          uint           `gorm:"primaryKey" json:"id"`
-	CreatedAt   time.Time      `json:"createdAt"`
-	UpdatedAt   time.Time      `json:"updatedAt"`
-	DeletedAt   gorm.DeletedAt `gorm:"index" json:"deletedAt,omitempty"`
-	Name        string         `gorm:"unique;not_null" json:"name"`
-	Description string         `json:"description"`
-	Public      bool           `gorm:"default:true" json:"public"`
-	Users       []User         `gorm:"many2many:user_make_routine;" json:"users"`
-	Exercises   []Exercise     `gorm:"many2many:routine_work_exercise;" json:"exercises"`
+	ID           uint           `gorm:"primaryKey" json:"id"`
+	CreatedAt    time.Time      `json:"createdAt"`
+	UpdatedAt    time.Time      `json:"updatedAt"`
+	DeletedAt    gorm.DeletedAt `gorm:"index" json:"deletedAt,omitempty"`
+	Name         string         `gorm:"not_null" json:"name"` // Único por usuario entre las rutinas no eliminadas
+	OwnerID      string         `gorm:"size:36;index" json:"ownerId"`
+	Description  string         `json:"description"`
+	Public       bool           `gorm:"default:true" json:"public"`
+	Hidden       bool           `gorm:"default:false" json:"hidden"`     // Ocultada por moderación
+	ForkedFromID *uint          `gorm:"index" json:"forkedFromId"`       // Rutina original si es una copia
+	ForkCount    int64          `gorm:"->;-:migration" json:"forkCount"` // Veces que se ha copiado (solo lectura, se calcula en la consulta)
+	LikeCount    int64          `gorm:"->;-:migration" json:"likeCount"` // Me gusta que tiene (solo lectura, se calcula en la consulta)
+	SearchRank   float64        `gorm:"->;-:migration" json:"-"`         // Relevancia para la búsqueda (solo lectura, se calcula en la consulta)
+	Users        []User         `gorm:"many2many:user_make_routine;" json:"users"`
+	Exercises    []Exercise     `gorm:"many2many:routine_work_exercise;" json:"exercises"`
 }
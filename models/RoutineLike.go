@@ -0,0 +1,10 @@
+package models
+
+import "time"
+
+// RoutineLike es el me gusta de un usuario a una rutina
+type RoutineLike struct {
+	UserID    string    `gorm:"primaryKey;size:36" json:"userId"`
+	RoutineID uint      `gorm:"primaryKey;index" json:"routineId"`
+	CreatedAt time.Time `json:"createdAt"`
+}
@@ -0,0 +1,37 @@
+package models
+
+import "time"
+
+// Motivos por los que se puede reportar una rutina
+const (
+	ReportSpam          = "spam"
+	ReportInappropriate = "inappropriate"
+	ReportDangerous     = "dangerous"
+	ReportCopyright     = "copyright"
+	ReportOther         = "other"
+)
+
+// Estados de un reporte
+const (
+	ReportPending   = "pending"
+	ReportDismissed = "dismissed" // Revisado sin tomar acción
+	ReportActioned  = "actioned"  // Revisado y se tomó una acción de moderación
+)
+
+// ReportReasons son los motivos aceptados
+var ReportReasons = []string{ReportSpam, ReportInappropriate, ReportDangerous, ReportCopyright, ReportOther}
+
+// RoutineReport es el reporte de un usuario sobre una rutina pública
+type RoutineReport struct {
+	ID         uint       `gorm:"primaryKey" json:"id"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	RoutineID  uint       `gorm:"not null;index" json:"routineId"`
+	ReporterID string     `gorm:"size:36;not null;index" json:"reporterId"`
+	Reason     string     `gorm:"size:30;not null" json:"reason"`
+	Comment    string     `json:"comment"`
+	Status     string     `gorm:"size:20;not null;default:pending;index" json:"status"`
+	Action     string     `gorm:"size:30" json:"action"` // Acción con la que se resolvió
+	ResolvedBy *string    `gorm:"size:36" json:"resolvedBy"`
+	ResolvedAt *time.Time `json:"resolvedAt"`
+	Routine    *Routine   `json:"routine,omitempty"`
+}
@@ -19,3 +19,15 @@ type UpdateDescriptionRoutineRequest struct {
 	ID          uint   `json:"id"`
 	Description string `json:"description"`
 }
+
+// Estructura para publicar u ocultar la rutina
+type UpdatePublicRoutineRequest struct {
+	ID     uint `json:"id"`
+	Public bool `json:"public"`
+}
+
+// Estructura para reportar una rutina pública
+type ReportRoutineRequest struct {
+	Reason  string `json:"reason"`
+	Comment string `json:"comment"`
+}
@@ -8,7 +8,7 @@ import (
 
 // Set representa un set de un ejercicio en la base de datos
 type Set struct {
-	gorm.Model
+	gorm.Model `json:"-"`     // Sus campos se declaran abajo con nombres JSON
 	ID         uint           `gorm:"primaryKey" json:"id"`
 	CreatedAt  time.Time      `json:"createdAt"`
 	UpdatedAt  time.Time      `json:"updatedAt"`
@@ -18,4 +18,5 @@ type Set struct {
 	Rest       float64        `gorm:"not null" json:"rest"`
 	Note       string         `gorm:"size 255" json:"note"`
 	ExerciseID uint           // Llave foránea que referencia a Exercise
+	E1RM       *float64       `gorm:"-" json:"e1rm,omitempty"` // 1RM estimado, solo si se solicita
 }
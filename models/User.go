@@ -8,18 +8,28 @@ import (
 	"gorm.io/gorm"
 )
 
+// Roles de los usuarios
+const (
+	RoleUser  = "user"
+	RoleCoach = "coach"
+	RoleAdmin = "admin"
+)
+
 // User representa un usuario en la base de datos
 type User struct {
-	gorm.Model
-	CreatedAt time.Time      `json:"createdAt"`
-	UpdatedAt time.Time      `json:"updatedAt"`
-	DeletedAt gorm.DeletedAt `gorm:"index" json:"deletedAt,omitempty"`
-	ID        string         `gorm:"primaryKey;size:36" json:"id"`
-	Username  string         `gorm:"unique;not null" json:"username"`
-	Email     string         `gorm:"unique;not null" json:"email"`
-	Password  string         `gorm:"size:100" json:"password"`
-	Role      string         `gorm:"default:'user'" json:"role"`
-	Routines  []Routine      `gorm:"many2many:user_make_routine;" json:"routines"`
+	gorm.Model    `json:"-"`     // Sus campos se declaran abajo con nombres JSON
+	CreatedAt     time.Time      `json:"createdAt"`
+	UpdatedAt     time.Time      `json:"updatedAt"`
+	DeletedAt     gorm.DeletedAt `gorm:"index" json:"deletedAt,omitempty"`
+	ID            string         `gorm:"primaryKey;size:36" json:"id"`
+	Username      string         `gorm:"unique;not null" json:"username"`
+	Email         string         `gorm:"unique;not null" json:"email"`
+	Password      string         `gorm:"size:100" json:"password"`
+	Role          string         `gorm:"default:'user'" json:"role"`
+	EmailVerified bool           `gorm:"not null;default:false" json:"emailVerified"` // Confirmó su correo con el enlace enviado al registrarse
+	Disabled      bool           `gorm:"not null;default:false" json:"disabled"`      // Deshabilitado por un administrador: no puede iniciar sesión
+	TokenVersion  int            `gorm:"not null;default:0" json:"-"`                 // Aumenta al cambiar la contraseña para invalidar los tokens anteriores
+	Routines      []Routine      `gorm:"many2many:user_make_routine;" json:"routines"`
 }
 
 // Las tablas intermedias user_make_routine y routine_work_exercise son manejadas automáticamente por GORM gracias a las anotaciones many2many.
@@ -32,11 +42,20 @@ func (user *User) BeforeCreate(tx *gorm.DB) (err error) {
 	}
 	// Hashear la contraseña si no está vacía
 	if user.Password != "" {
-		hashedPassword, err := bcrypt.GenerateFromPassword([]byte(user.Password), bcrypt.DefaultCost)
+		hashedPassword, err := HashPassword(user.Password)
 		if err != nil {
 			return err
 		}
-		user.Password = string(hashedPassword)
+		user.Password = hashedPassword
 	}
 	return nil
 }
+
+// HashPassword hashea una contraseña con bcrypt
+func HashPassword(password string) (string, error) {
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hashedPassword), nil
+}
@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// UserIdentity vincula un usuario con su cuenta en un proveedor de inicio de sesión social.
+// El par (provider, subject) identifica la cuenta; el correo solo se guarda como referencia.
+type UserIdentity struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	CreatedAt time.Time `json:"createdAt"`
+	UserID    string    `gorm:"size:36;not null;index" json:"userId"`
+	Provider  string    `gorm:"size:50;not null;uniqueIndex:idx_user_identities_provider_subject" json:"provider"`
+	Subject   string    `gorm:"size:255;not null;uniqueIndex:idx_user_identities_provider_subject" json:"subject"`
+	Email     string    `json:"email"`
+}
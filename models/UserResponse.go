@@ -0,0 +1,49 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// PublicUser es el perfil de un usuario que puede ver cualquiera (autor de una rutina, perfil público)
+type PublicUser struct {
+	ID        string    `json:"id"`
+	Username  string    `json:"username"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// PrivateUser son los datos de la cuenta; solo se devuelven al propio usuario o a un administrador
+type PrivateUser struct {
+	ID            string    `json:"id"`
+	Username      string    `json:"username"`
+	Email         string    `json:"email"`
+	Role          string    `json:"role"`
+	EmailVerified bool      `json:"emailVerified"`
+	Disabled      bool      `json:"disabled"`
+	CreatedAt     time.Time `json:"createdAt"`
+}
+
+// Public devuelve el perfil público del usuario
+func (user User) Public() PublicUser {
+	return PublicUser{ID: user.ID, Username: user.Username, CreatedAt: user.CreatedAt}
+}
+
+// Private devuelve los datos de la cuenta del usuario
+func (user User) Private() PrivateUser {
+	return PrivateUser{
+		ID:            user.ID,
+		Username:      user.Username,
+		Email:         user.Email,
+		Role:          user.Role,
+		EmailVerified: user.EmailVerified,
+		Disabled:      user.Disabled,
+		CreatedAt:     user.CreatedAt,
+	}
+}
+
+// MarshalJSON serializa un User como su perfil público. Así un User que llega a una respuesta
+// (por ejemplo en Routine.Users) nunca expone el hash de la contraseña, el correo ni los campos
+// internos; los datos de la cuenta se responden explícitamente con Private().
+func (user User) MarshalJSON() ([]byte, error) {
+	return json.Marshal(user.Public())
+}
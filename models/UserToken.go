@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// Propósitos de los tokens de un solo uso que se envían por correo
+const (
+	TokenPasswordReset     = "password_reset"
+	TokenEmailVerification = "email_verification"
+)
+
+// UserToken es un token de un solo uso enviado por correo. Solo se guarda su hash.
+type UserToken struct {
+	ID        uint       `gorm:"primaryKey" json:"id"`
+	CreatedAt time.Time  `json:"createdAt"`
+	UserID    string     `gorm:"size:36;not null;index" json:"userId"`
+	Purpose   string     `gorm:"size:30;not null" json:"purpose"`
+	TokenHash string     `gorm:"size:64;not null;uniqueIndex" json:"-"`
+	ExpiresAt time.Time  `gorm:"not null" json:"expiresAt"`
+	UsedAt    *time.Time `json:"usedAt"`
+}
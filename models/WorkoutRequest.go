@@ -0,0 +1,24 @@
+package models
+
+// Estructura para iniciar una sesión de entrenamiento a partir de una rutina
+type StartWorkoutRequest struct {
+	RoutineID uint   `json:"routineId"`
+	Notes     string `json:"notes"`
+}
+
+// Estructura para registrar un set realizado durante la sesión
+type LogWorkoutSetRequest struct {
+	ExerciseID uint    `json:"exerciseId"`
+	SetID      uint    `json:"setId"` // Set planificado al que corresponde (opcional)
+	Reps       int     `json:"reps"`
+	Weight     float64 `json:"weight"`
+	Rest       float64 `json:"rest"`
+	Note       string  `json:"note"`
+}
+
+// Estructura para finalizar la sesión de entrenamiento
+type FinishWorkoutRequest struct {
+	// Duración en segundos (si no se envía se calcula desde el inicio de la sesión)
+	DurationSeconds int    `json:"durationSeconds"`
+	Notes           string `json:"notes"`
+}
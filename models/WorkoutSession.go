@@ -0,0 +1,42 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// WorkoutSession representa un entrenamiento realizado por un usuario a partir de una rutina.
+// La rutina es solo la plantilla; lo que realmente se levantó se guarda en WorkoutSet.
+type WorkoutSession struct {
+	ID              uint           `gorm:"primaryKey" json:"id"`
+	CreatedAt       time.Time      `json:"createdAt"`
+	UpdatedAt       time.Time      `json:"updatedAt"`
+	DeletedAt       gorm.DeletedAt `gorm:"index" json:"deletedAt,omitempty"`
+	UserID          string         `gorm:"size:36;not null;index" json:"userId"`
+	RoutineID       uint           `gorm:"not null;index" json:"routineId"`
+	RoutineName     string         `json:"routineName"` // Nombre de la rutina al momento de entrenar
+	StartedAt       time.Time      `gorm:"not null" json:"startedAt"`
+	FinishedAt      *time.Time     `json:"finishedAt"`
+	DurationSeconds int            `json:"durationSeconds"`
+	Notes           string         `json:"notes"`
+	Sets            []WorkoutSet   `gorm:"foreignKey:WorkoutSessionID" json:"sets"`
+}
+
+// WorkoutSet representa un set realizado durante una sesión de entrenamiento
+type WorkoutSet struct {
+	ID               uint             `gorm:"primaryKey" json:"id"`
+	CreatedAt        time.Time        `json:"createdAt"`
+	UpdatedAt        time.Time        `json:"updatedAt"`
+	DeletedAt        gorm.DeletedAt   `gorm:"index" json:"deletedAt,omitempty"`
+	WorkoutSessionID uint             `gorm:"not null;index" json:"workoutSessionId"` // Llave foránea que referencia a WorkoutSession
+	ExerciseID       uint             `gorm:"not null;index" json:"exerciseId"`       // Ejercicio de la rutina que se realizó
+	ExerciseName     string           `json:"exerciseName"`
+	SetID            *uint            `json:"setId"` // Set planificado en la rutina (opcional)
+	Reps             int              `gorm:"not null" json:"reps"`
+	Weight           float64          `gorm:"not null" json:"weight"`
+	Rest             float64          `gorm:"not null" json:"rest"`
+	Note             string           `gorm:"size:255" json:"note"`
+	PerformedAt      time.Time        `gorm:"not null" json:"performedAt"`
+	Records          []PersonalRecord `gorm:"foreignKey:WorkoutSetID" json:"records"` // Récords marcados por este set
+}
@@ -0,0 +1,273 @@
+// Package oidc verifica los ID tokens (JWT firmados con RS256) que emiten los proveedores de
+// inicio de sesión social contra sus claves públicas (JWKS), su emisor y los client IDs de la app.
+package oidc
+
+import (
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+var (
+	ErrUnknownProvider = errors.New("proveedor de inicio de sesión no configurado")
+	ErrInvalidToken    = errors.New("token de identidad inválido")
+)
+
+const (
+	// jwksTTL es cada cuánto se vuelven a descargar las claves de un proveedor
+	jwksTTL = time.Hour
+	// jwksMinRefresh evita descargar las claves en cada token con un kid desconocido
+	jwksMinRefresh = time.Minute
+)
+
+// Provider es un proveedor OIDC configurado
+type Provider struct {
+	Name      string
+	Issuers   []string // Valores aceptados para el claim iss
+	JWKSURL   string
+	ClientIDs []string // Valores aceptados para el claim aud
+}
+
+// Identity son los datos del usuario que certifica un ID token válido
+type Identity struct {
+	Provider      string
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+}
+
+// knownProviders son los emisores y JWKS de los proveedores conocidos; solo se habilitan
+// si se configuran sus client IDs
+var knownProviders = map[string]Provider{
+	"google": {
+		Name:    "google",
+		Issuers: []string{"https://accounts.google.com", "accounts.google.com"},
+		JWKSURL: "https://www.googleapis.com/oauth2/v3/certs",
+	},
+	"apple": {
+		Name:    "apple",
+		Issuers: []string{"https://appleid.apple.com"},
+		JWKSURL: "https://appleid.apple.com/auth/keys",
+	},
+}
+
+// ProvidersFromEnv lee los proveedores de OIDC_PROVIDERS (p. ej. "google,apple") y, para cada uno,
+// OIDC_<NOMBRE>_CLIENT_IDS (separados por coma), OIDC_<NOMBRE>_ISSUER y OIDC_<NOMBRE>_JWKS_URL.
+// El emisor y la URL de las claves de Google y Apple tienen valores por defecto.
+func ProvidersFromEnv() []Provider {
+	var providers []Provider
+	for _, name := range splitList(os.Getenv("OIDC_PROVIDERS")) {
+		name = strings.ToLower(name)
+		prefix := "OIDC_" + strings.ToUpper(name) + "_"
+
+		provider := knownProviders[name]
+		provider.Name = name
+		if issuer := os.Getenv(prefix + "ISSUER"); issuer != "" {
+			provider.Issuers = []string{issuer}
+		}
+		if jwksURL := os.Getenv(prefix + "JWKS_URL"); jwksURL != "" {
+			provider.JWKSURL = jwksURL
+		}
+		provider.ClientIDs = splitList(os.Getenv(prefix + "CLIENT_IDS"))
+
+		if len(provider.Issuers) == 0 || provider.JWKSURL == "" || len(provider.ClientIDs) == 0 {
+			continue // Configuración incompleta: el proveedor queda deshabilitado
+		}
+		providers = append(providers, provider)
+	}
+	return providers
+}
+
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// Verifier verifica ID tokens de los proveedores configurados y guarda sus claves en caché
+type Verifier struct {
+	Client *http.Client
+
+	providers map[string]Provider
+
+	mu   sync.Mutex
+	keys map[string]cachedKeys // Por proveedor
+}
+
+type cachedKeys struct {
+	keys      map[string]*rsa.PublicKey // Por kid
+	fetchedAt time.Time
+}
+
+// NewVerifier crea un Verifier para los proveedores dados
+func NewVerifier(providers []Provider) *Verifier {
+	byName := map[string]Provider{}
+	for _, provider := range providers {
+		byName[provider.Name] = provider
+	}
+	return &Verifier{
+		Client:    &http.Client{Timeout: 10 * time.Second},
+		providers: byName,
+		keys:      map[string]cachedKeys{},
+	}
+}
+
+// Verify comprueba la firma, el emisor, la audiencia y la vigencia del ID token y devuelve la identidad
+func (v *Verifier) Verify(providerName string, idToken string) (Identity, error) {
+	provider, ok := v.providers[strings.ToLower(providerName)]
+	if !ok {
+		return Identity{}, ErrUnknownProvider
+	}
+
+	token, err := jwt.Parse(idToken, func(token *jwt.Token) (interface{}, error) {
+		if token.Method != jwt.SigningMethodRS256 {
+			return nil, fmt.Errorf("algoritmo de firma no permitido: %v", token.Header["alg"])
+		}
+		kid, _ := token.Header["kid"].(string)
+		return v.key(provider, kid)
+	})
+	if err != nil || !token.Valid {
+		return Identity{}, ErrInvalidToken
+	}
+
+	// jwt.Parse ya comprobó exp, iat y nbf
+	claims := token.Claims.(jwt.MapClaims)
+	issuer, _ := claims["iss"].(string)
+	if !contains(provider.Issuers, issuer) || !audienceMatches(claims["aud"], provider.ClientIDs) {
+		return Identity{}, ErrInvalidToken
+	}
+	subject, _ := claims["sub"].(string)
+	if subject == "" {
+		return Identity{}, ErrInvalidToken
+	}
+	if _, ok := claims["exp"]; !ok {
+		return Identity{}, ErrInvalidToken
+	}
+
+	identity := Identity{Provider: provider.Name, Subject: subject}
+	identity.Email, _ = claims["email"].(string)
+	identity.Name, _ = claims["name"].(string)
+	// Apple envía email_verified como texto
+	switch verified := claims["email_verified"].(type) {
+	case bool:
+		identity.EmailVerified = verified
+	case string:
+		identity.EmailVerified = verified == "true"
+	}
+	return identity, nil
+}
+
+// key devuelve la clave pública con ese kid; si no está en caché (o la caché venció) vuelve a
+// descargar el JWKS, ya que los proveedores rotan sus claves
+func (v *Verifier) key(provider Provider, kid string) (*rsa.PublicKey, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	cached, ok := v.keys[provider.Name]
+	if ok && time.Since(cached.fetchedAt) < jwksTTL {
+		if key, ok := cached.keys[kid]; ok {
+			return key, nil
+		}
+		if time.Since(cached.fetchedAt) < jwksMinRefresh {
+			return nil, fmt.Errorf("clave %q no encontrada", kid)
+		}
+	}
+
+	keys, err := v.fetchKeys(provider.JWKSURL)
+	if err != nil {
+		return nil, err
+	}
+	v.keys[provider.Name] = cachedKeys{keys: keys, fetchedAt: time.Now()}
+
+	key, ok := keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("clave %q no encontrada", kid)
+	}
+	return key, nil
+}
+
+// jwks es el formato de https://datatracker.ietf.org/doc/html/rfc7517
+type jwks struct {
+	Keys []struct {
+		Kty string `json:"kty"`
+		Kid string `json:"kid"`
+		Use string `json:"use"`
+		N   string `json:"n"`
+		E   string `json:"e"`
+	} `json:"keys"`
+}
+
+func (v *Verifier) fetchKeys(url string) (map[string]*rsa.PublicKey, error) {
+	resp, err := v.Client.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("error al descargar las claves: %s", resp.Status)
+	}
+
+	var set jwks
+	if err := json.NewDecoder(resp.Body).Decode(&set); err != nil {
+		return nil, err
+	}
+
+	keys := map[string]*rsa.PublicKey{}
+	for _, jwk := range set.Keys {
+		if jwk.Kty != "RSA" || (jwk.Use != "" && jwk.Use != "sig") {
+			continue
+		}
+		n, err := base64.RawURLEncoding.DecodeString(jwk.N)
+		if err != nil {
+			continue
+		}
+		e, err := base64.RawURLEncoding.DecodeString(jwk.E)
+		if err != nil {
+			continue
+		}
+		keys[jwk.Kid] = &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}
+	}
+	return keys, nil
+}
+
+// audienceMatches acepta aud como texto o como lista
+func audienceMatches(aud interface{}, clientIDs []string) bool {
+	switch aud := aud.(type) {
+	case string:
+		return contains(clientIDs, aud)
+	case []interface{}:
+		for _, value := range aud {
+			if value, ok := value.(string); ok && contains(clientIDs, value) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func contains(values []string, value string) bool {
+	for _, candidate := range values {
+		if candidate == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,251 @@
+package oidc
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+const (
+	testIssuer   = "https://issuer.example.com"
+	testClientID = "app-client-id"
+)
+
+// testJWKS es un proveedor local que publica las claves públicas de keys y cuenta las descargas
+type testJWKS struct {
+	server  *httptest.Server
+	fetches int32
+
+	mu   sync.Mutex
+	keys map[string]*rsa.PrivateKey // Por kid
+}
+
+func newTestJWKS(t *testing.T) *testJWKS {
+	t.Helper()
+	provider := &testJWKS{keys: map[string]*rsa.PrivateKey{}}
+	provider.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&provider.fetches, 1)
+		provider.mu.Lock()
+		defer provider.mu.Unlock()
+		var set jwks
+		for kid, key := range provider.keys {
+			set.Keys = append(set.Keys, struct {
+				Kty string `json:"kty"`
+				Kid string `json:"kid"`
+				Use string `json:"use"`
+				N   string `json:"n"`
+				E   string `json:"e"`
+			}{
+				Kty: "RSA",
+				Kid: kid,
+				Use: "sig",
+				N:   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			})
+		}
+		json.NewEncoder(w).Encode(set)
+	}))
+	t.Cleanup(provider.server.Close)
+	return provider
+}
+
+// addKey genera una clave RSA nueva y la publica con ese kid
+func (p *testJWKS) addKey(t *testing.T, kid string) *rsa.PrivateKey {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.mu.Lock()
+	p.keys[kid] = key
+	p.mu.Unlock()
+	return key
+}
+
+func (p *testJWKS) verifier() *Verifier {
+	return NewVerifier([]Provider{{
+		Name:      "test",
+		Issuers:   []string{testIssuer},
+		JWKSURL:   p.server.URL,
+		ClientIDs: []string{testClientID},
+	}})
+}
+
+// validClaims son los claims de un ID token vigente para la app
+func validClaims() jwt.MapClaims {
+	return jwt.MapClaims{
+		"iss":            testIssuer,
+		"aud":            testClientID,
+		"sub":            "user-123",
+		"email":          "ana@example.com",
+		"email_verified": true,
+		"name":           "Ana",
+		"iat":            time.Now().Unix(),
+		"exp":            time.Now().Add(time.Hour).Unix(),
+	}
+}
+
+// sign firma los claims con RS256 y el kid dado
+func sign(t *testing.T, key *rsa.PrivateKey, kid string, claims jwt.MapClaims) string {
+	t.Helper()
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = kid
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed
+}
+
+func TestVerify(t *testing.T) {
+	provider := newTestJWKS(t)
+	key := provider.addKey(t, "k1")
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	withClaim := func(name string, value interface{}) jwt.MapClaims {
+		claims := validClaims()
+		claims[name] = value
+		return claims
+	}
+	hs256 := jwt.NewWithClaims(jwt.SigningMethodHS256, validClaims())
+	hs256.Header["kid"] = "k1"
+	hs256Token, err := hs256.SignedString(key.PublicKey.N.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	rs512 := jwt.NewWithClaims(jwt.SigningMethodRS512, validClaims())
+	rs512.Header["kid"] = "k1"
+	rs512Token, err := rs512.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	none := jwt.NewWithClaims(jwt.SigningMethodNone, validClaims())
+	none.Header["kid"] = "k1"
+	noneToken, err := none.SignedString(jwt.UnsafeAllowNoneSignatureType)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		token   string
+		wantErr bool
+	}{
+		{"token válido", sign(t, key, "k1", validClaims()), false},
+		{"firma de otra clave", sign(t, otherKey, "k1", validClaims()), true},
+		{"algoritmo HS256", hs256Token, true},
+		{"algoritmo RS512", rs512Token, true},
+		{"algoritmo none", noneToken, true},
+		{"otro emisor", sign(t, key, "k1", withClaim("iss", "https://evil.example.com")), true},
+		{"otra audiencia", sign(t, key, "k1", withClaim("aud", "otra-app")), true},
+		{"audiencia en lista", sign(t, key, "k1", withClaim("aud", []string{"otra-app", testClientID})), false},
+		{"vencido", sign(t, key, "k1", withClaim("exp", time.Now().Add(-time.Minute).Unix())), true},
+		{"sin exp", sign(t, key, "k1", func() jwt.MapClaims { c := validClaims(); delete(c, "exp"); return c }()), true},
+		{"sin sub", sign(t, key, "k1", withClaim("sub", "")), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			identity, err := provider.verifier().Verify("test", tt.token)
+			if tt.wantErr {
+				if err != ErrInvalidToken {
+					t.Fatalf("error %v, se esperaba ErrInvalidToken", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("error inesperado: %v", err)
+			}
+			if identity.Provider != "test" || identity.Subject != "user-123" || identity.Email != "ana@example.com" || !identity.EmailVerified || identity.Name != "Ana" {
+				t.Fatalf("identidad inesperada: %+v", identity)
+			}
+		})
+	}
+}
+
+func TestVerifyEmailVerifiedAsString(t *testing.T) {
+	provider := newTestJWKS(t)
+	key := provider.addKey(t, "k1")
+
+	for value, want := range map[string]bool{"true": true, "false": false} {
+		claims := validClaims()
+		claims["email_verified"] = value
+		identity, err := provider.verifier().Verify("test", sign(t, key, "k1", claims))
+		if err != nil {
+			t.Fatalf("email_verified %q: %v", value, err)
+		}
+		if identity.EmailVerified != want {
+			t.Fatalf("email_verified %q: EmailVerified = %v", value, identity.EmailVerified)
+		}
+	}
+}
+
+func TestVerifyUnknownProvider(t *testing.T) {
+	provider := newTestJWKS(t)
+	key := provider.addKey(t, "k1")
+	if _, err := provider.verifier().Verify("otro", sign(t, key, "k1", validClaims())); err != ErrUnknownProvider {
+		t.Fatalf("error %v, se esperaba ErrUnknownProvider", err)
+	}
+}
+
+// Un kid desconocido vuelve a descargar las claves (el proveedor las rotó), pero no más de una
+// vez por jwksMinRefresh
+func TestVerifyUnknownKidRefreshesKeys(t *testing.T) {
+	provider := newTestJWKS(t)
+	key := provider.addKey(t, "k1")
+	verifier := provider.verifier()
+
+	if _, err := verifier.Verify("test", sign(t, key, "k1", validClaims())); err != nil {
+		t.Fatal(err)
+	}
+	if fetches := atomic.LoadInt32(&provider.fetches); fetches != 1 {
+		t.Fatalf("%d descargas, se esperaba 1", fetches)
+	}
+
+	// Con las claves en caché no se vuelve a descargar
+	if _, err := verifier.Verify("test", sign(t, key, "k1", validClaims())); err != nil {
+		t.Fatal(err)
+	}
+	if fetches := atomic.LoadInt32(&provider.fetches); fetches != 1 {
+		t.Fatalf("%d descargas, se esperaba 1", fetches)
+	}
+
+	// El proveedor rota la clave: recién descargadas, un kid desconocido no se vuelve a pedir
+	rotated := provider.addKey(t, "k2")
+	if _, err := verifier.Verify("test", sign(t, rotated, "k2", validClaims())); err != ErrInvalidToken {
+		t.Fatalf("error %v, se esperaba ErrInvalidToken", err)
+	}
+	if fetches := atomic.LoadInt32(&provider.fetches); fetches != 1 {
+		t.Fatalf("%d descargas, se esperaba 1", fetches)
+	}
+
+	// Pasado jwksMinRefresh, el kid desconocido hace descargar las claves de nuevo
+	verifier.mu.Lock()
+	cached := verifier.keys["test"]
+	cached.fetchedAt = time.Now().Add(-2 * jwksMinRefresh)
+	verifier.keys["test"] = cached
+	verifier.mu.Unlock()
+
+	identity, err := verifier.Verify("test", sign(t, rotated, "k2", validClaims()))
+	if err != nil {
+		t.Fatalf("error con la clave rotada: %v", err)
+	}
+	if identity.Subject != "user-123" {
+		t.Fatalf("identidad inesperada: %+v", identity)
+	}
+	if fetches := atomic.LoadInt32(&provider.fetches); fetches != 2 {
+		t.Fatalf("%d descargas, se esperaban 2", fetches)
+	}
+}
@@ -0,0 +1,125 @@
+package records
+
+import (
+	"math"
+	"sort"
+
+	"github.com/danilsgit/gym-stats-backend/e1rm"
+	"github.com/danilsgit/gym-stats-backend/models"
+)
+
+// Tipos de récord personal
+const (
+	TypeWeight = "weight" // Mayor peso levantado
+	TypeReps   = "reps"   // Más repeticiones con un mismo peso
+	TypeE1RM   = "e1rm"   // Mejor 1RM estimado (Epley)
+	TypeVolume = "volume" // Mayor volumen (reps x peso) en un set
+)
+
+// key identifica un récord: el de repeticiones se guarda por cada peso
+type key struct {
+	exerciseID uint
+	kind       string
+	weight     float64
+}
+
+func keyOf(record models.PersonalRecord) key {
+	k := key{exerciseID: record.ExerciseID, kind: record.Type}
+	if record.Type == TypeReps {
+		k.weight = record.Weight
+	}
+	return k
+}
+
+// Candidates devuelve los valores que el set podría establecer como récord
+func Candidates(set models.WorkoutSet) []models.PersonalRecord {
+	if set.Reps <= 0 {
+		return nil
+	}
+
+	base := models.PersonalRecord{
+		ExerciseID:       set.ExerciseID,
+		ExerciseName:     set.ExerciseName,
+		Reps:             set.Reps,
+		Weight:           set.Weight,
+		WorkoutSessionID: set.WorkoutSessionID,
+		WorkoutSetID:     set.ID,
+		AchievedAt:       set.PerformedAt,
+	}
+
+	// Las repeticiones cuentan también con peso corporal (peso 0)
+	reps := base
+	reps.Type = TypeReps
+	reps.Value = float64(set.Reps)
+	candidates := []models.PersonalRecord{reps}
+
+	if set.Weight <= 0 {
+		return candidates
+	}
+
+	weight := base
+	weight.Type = TypeWeight
+	weight.Value = set.Weight
+
+	oneRepMax := base
+	oneRepMax.Type = TypeE1RM
+	oneRepMax.Value, _ = e1rm.Estimate(e1rm.Epley, set.Reps, set.Weight, 0)
+
+	volume := base
+	volume.Type = TypeVolume
+	volume.Value = round(float64(set.Reps) * set.Weight)
+
+	return append(candidates, weight, oneRepMax, volume)
+}
+
+// Detect compara el set con los récords vigentes y devuelve los que supera
+func Detect(current []models.PersonalRecord, set models.WorkoutSet) []models.PersonalRecord {
+	best := make(map[key]models.PersonalRecord)
+	for _, record := range current {
+		best[keyOf(record)] = record
+	}
+
+	var beaten []models.PersonalRecord
+	for _, candidate := range Candidates(set) {
+		previous, ok := best[keyOf(candidate)]
+		if ok && candidate.Value <= previous.Value {
+			continue
+		}
+		if ok {
+			candidate.Previous = previous.Value
+		}
+		beaten = append(beaten, candidate)
+	}
+	return beaten
+}
+
+// Current reduce el historial de récords a los vigentes (el mejor de cada tipo)
+func Current(history []models.PersonalRecord) []models.PersonalRecord {
+	best := make(map[key]models.PersonalRecord)
+	for _, record := range history {
+		k := keyOf(record)
+		if previous, ok := best[k]; !ok || record.Value > previous.Value {
+			best[k] = record
+		}
+	}
+
+	current := make([]models.PersonalRecord, 0, len(best))
+	for _, record := range best {
+		current = append(current, record)
+	}
+	sort.Slice(current, func(i, j int) bool {
+		if current[i].ExerciseID != current[j].ExerciseID {
+			return current[i].ExerciseID < current[j].ExerciseID
+		}
+		if current[i].Type != current[j].Type {
+			return current[i].Type < current[j].Type
+		}
+		return current[i].Weight < current[j].Weight
+	})
+	return current
+}
+
+// round redondea a un decimal
+func round(value float64) float64 {
+	return math.Round(value*10) / 10
+}
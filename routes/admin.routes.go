@@ -0,0 +1,315 @@
+package routes
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/danilsgit/gym-stats-backend/models"
+	"github.com/danilsgit/gym-stats-backend/store"
+	"github.com/gorilla/mux"
+)
+
+// Administración
+
+// Listar los usuarios, buscando por username o correo (?search=)
+func (s *Server) AdminListUsersHandler(w http.ResponseWriter, r *http.Request) {
+	search := r.URL.Query().Get("search")
+	offset, err := strconv.Atoi(r.URL.Query().Get("offset"))
+	if err != nil || offset < 0 {
+		offset = 0 // Valor predeterminado si hay un error o no se proporciona
+	}
+	limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+	if err != nil || limit <= 0 || limit > 100 {
+		limit = 20 // Valor predeterminado si hay un error o no se proporciona
+	}
+
+	users, total, err := s.Users.List(search, limit, offset)
+	if err != nil {
+		writeError(w, r, http.StatusInternalServerError, CodeInternal, "Error al obtener los usuarios")
+		return
+	}
+	// Los administradores ven los datos de la cuenta, nunca el hash de la contraseña
+	accounts := make([]models.PrivateUser, 0, len(users))
+	for _, user := range users {
+		accounts = append(accounts, user.Private())
+	}
+
+	var result = map[string]interface{}{}
+	result["users"] = accounts
+	result["total"] = total
+	result["pages"] = int64(math.Ceil(float64(total) / float64(limit)))
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(result)
+}
+
+// Deshabilitar (o volver a habilitar) una cuenta. Al deshabilitarla se cierran sus sesiones.
+func (s *Server) AdminDisableUserHandler(w http.ResponseWriter, r *http.Request) {
+	adminID, ok := currentUserID(r)
+	if !ok {
+		writeError(w, r, http.StatusInternalServerError, CodeInternal, "No se encontró el ID del usuario en la solicitud")
+		return
+	}
+
+	var req models.DisableUserRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, r, http.StatusBadRequest, CodeInvalidBody, "Error al decodificar el cuerpo de la solicitud")
+		return
+	}
+
+	userID := mux.Vars(r)["id"]
+	if userID == adminID {
+		writeError(w, r, http.StatusBadRequest, CodeValidationFailed, "No puedes deshabilitar tu propia cuenta")
+		return
+	}
+
+	action := models.ModerationReinstate
+	if req.Disabled {
+		action = models.ModerationSuspend
+	}
+	// Actualizar el usuario, cerrar sus sesiones y registrar la acción en una transacción
+	if err := s.Moderation.Apply(&store.ModerationChange{
+		Entry:        models.ModerationAction{ModeratorID: adminID, Action: action, TargetUserID: &userID, Note: req.Note},
+		UserDisabled: &req.Disabled,
+	}); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			writeError(w, r, http.StatusNotFound, CodeUserNotFound, "Usuario no encontrado")
+			return
+		}
+		writeError(w, r, http.StatusInternalServerError, CodeInternal, "Error al actualizar el usuario")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode("Usuario actualizado con éxito")
+}
+
+// Despublicar una rutina de cualquier usuario
+func (s *Server) AdminUnpublishRoutineHandler(w http.ResponseWriter, r *http.Request) {
+	moderatorID, ok := currentUserID(r)
+	if !ok {
+		writeError(w, r, http.StatusInternalServerError, CodeInternal, "No se encontró el ID del usuario en la solicitud")
+		return
+	}
+
+	routine, err := s.Routines.FindByID(pathID(r, "id"))
+	if err != nil {
+		writeError(w, r, http.StatusNotFound, CodeRoutineNotFound, "Rutina no encontrada")
+		return
+	}
+
+	public := false
+	if err := s.Moderation.Apply(&store.ModerationChange{
+		Entry:         models.ModerationAction{ModeratorID: moderatorID, Action: models.ModerationUnpublish, RoutineID: &routine.ID, TargetUserID: routineAuthorID(routine)},
+		RoutinePublic: &public,
+	}); err != nil {
+		writeError(w, r, http.StatusInternalServerError, CodeInternal, "Error al despublicar la rutina")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode("Rutina despublicada con éxito")
+}
+
+// Ocultar una rutina (o volver a mostrarla). Una rutina oculta no aparece en las búsquedas,
+// en el perfil de su autor ni en GET /routines/{id}, aunque siga siendo pública.
+func (s *Server) AdminHideRoutineHandler(w http.ResponseWriter, r *http.Request) {
+	moderatorID, ok := currentUserID(r)
+	if !ok {
+		writeError(w, r, http.StatusInternalServerError, CodeInternal, "No se encontró el ID del usuario en la solicitud")
+		return
+	}
+
+	var req models.HideRoutineRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, r, http.StatusBadRequest, CodeInvalidBody, "Error al decodificar el cuerpo de la solicitud")
+		return
+	}
+
+	routine, err := s.Routines.FindByID(pathID(r, "id"))
+	if err != nil {
+		writeError(w, r, http.StatusNotFound, CodeRoutineNotFound, "Rutina no encontrada")
+		return
+	}
+
+	action := models.ModerationUnhide
+	if req.Hidden {
+		action = models.ModerationHide
+	}
+	if err := s.Moderation.Apply(&store.ModerationChange{
+		Entry:         models.ModerationAction{ModeratorID: moderatorID, Action: action, RoutineID: &routine.ID, TargetUserID: routineAuthorID(routine), Note: req.Note},
+		RoutineHidden: &req.Hidden,
+	}); err != nil {
+		writeError(w, r, http.StatusInternalServerError, CodeInternal, "Error al actualizar la rutina")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode("Rutina actualizada con éxito")
+}
+
+// Cola de reportes (?status=pending por defecto; ?status=all para todos), los más antiguos primero
+func (s *Server) AdminListReportsHandler(w http.ResponseWriter, r *http.Request) {
+	status := r.URL.Query().Get("status")
+	switch status {
+	case "":
+		status = models.ReportPending
+	case "all":
+		status = ""
+	case models.ReportPending, models.ReportDismissed, models.ReportActioned:
+	default:
+		writeError(w, r, http.StatusBadRequest, CodeInvalidParameter, "Estado de reporte inválido")
+		return
+	}
+	offset, err := strconv.Atoi(r.URL.Query().Get("offset"))
+	if err != nil || offset < 0 {
+		offset = 0 // Valor predeterminado si hay un error o no se proporciona
+	}
+	limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+	if err != nil || limit <= 0 || limit > 100 {
+		limit = 20 // Valor predeterminado si hay un error o no se proporciona
+	}
+
+	reports, total, err := s.Moderation.ListReports(status, limit, offset)
+	if err != nil {
+		writeError(w, r, http.StatusInternalServerError, CodeInternal, "Error al obtener los reportes")
+		return
+	}
+
+	var result = map[string]interface{}{}
+	result["reports"] = reports
+	result["total"] = total
+	result["pages"] = int64(math.Ceil(float64(total) / float64(limit)))
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(result)
+}
+
+// Resolver un reporte. La acción (dismiss, hide, unpublish o suspend) se aplica a la rutina
+// reportada o a su autor y cierra todos los reportes pendientes de esa rutina.
+func (s *Server) AdminResolveReportHandler(w http.ResponseWriter, r *http.Request) {
+	moderatorID, ok := currentUserID(r)
+	if !ok {
+		writeError(w, r, http.StatusInternalServerError, CodeInternal, "No se encontró el ID del usuario en la solicitud")
+		return
+	}
+
+	var req models.ResolveReportRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, r, http.StatusBadRequest, CodeInvalidBody, "Error al decodificar el cuerpo de la solicitud")
+		return
+	}
+	switch req.Action {
+	case models.ModerationDismiss, models.ModerationHide, models.ModerationUnpublish:
+	case models.ModerationSuspend:
+		// Suspender cuentas requiere un permiso adicional
+		if !hasPermission(currentUserRole(r), PermDisableUsers) {
+			writeError(w, r, http.StatusForbidden, CodeForbidden, "No tienes permiso para realizar esta acción")
+			return
+		}
+	default:
+		writeError(w, r, http.StatusBadRequest, CodeValidationFailed, "Acción de moderación inválida")
+		return
+	}
+
+	report, err := s.Moderation.FindReport(pathID(r, "id"))
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			writeError(w, r, http.StatusNotFound, CodeReportNotFound, "Reporte no encontrado")
+			return
+		}
+		writeError(w, r, http.StatusInternalServerError, CodeInternal, "Error al obtener el reporte")
+		return
+	}
+	if report.Status != models.ReportPending {
+		writeError(w, r, http.StatusConflict, CodeReportResolved, "El reporte ya fue resuelto")
+		return
+	}
+
+	change := store.ModerationChange{
+		Entry:        models.ModerationAction{ModeratorID: moderatorID, Action: req.Action, RoutineID: &report.RoutineID, ReportID: &report.ID, Note: req.Note},
+		ReportStatus: models.ReportActioned,
+	}
+
+	if req.Action == models.ModerationDismiss {
+		change.ReportStatus = models.ReportDismissed
+	} else {
+		routine, err := s.Routines.FindByID(report.RoutineID)
+		if err != nil {
+			writeError(w, r, http.StatusNotFound, CodeRoutineNotFound, "Rutina no encontrada")
+			return
+		}
+		change.Entry.TargetUserID = routineAuthorID(routine)
+
+		switch req.Action {
+		case models.ModerationHide:
+			hidden := true
+			change.RoutineHidden = &hidden
+		case models.ModerationUnpublish:
+			public := false
+			change.RoutinePublic = &public
+		case models.ModerationSuspend:
+			if change.Entry.TargetUserID == nil || *change.Entry.TargetUserID == moderatorID {
+				writeError(w, r, http.StatusBadRequest, CodeValidationFailed, "No es posible suspender al autor de esta rutina")
+				return
+			}
+			disabled := true
+			change.UserDisabled = &disabled
+		}
+	}
+
+	// Aplicar la acción, cerrar los reportes de la rutina y registrarla en una transacción
+	if err := s.Moderation.Apply(&change); err != nil {
+		writeError(w, r, http.StatusInternalServerError, CodeInternal, "Error al aplicar la acción de moderación")
+		return
+	}
+
+	report, err = s.Moderation.FindReport(report.ID)
+	if err != nil {
+		writeError(w, r, http.StatusInternalServerError, CodeInternal, "Error al obtener el reporte")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(report)
+}
+
+// Registro de auditoría de las decisiones de moderación, lo más reciente primero
+func (s *Server) AdminModerationLogHandler(w http.ResponseWriter, r *http.Request) {
+	offset, err := strconv.Atoi(r.URL.Query().Get("offset"))
+	if err != nil || offset < 0 {
+		offset = 0 // Valor predeterminado si hay un error o no se proporciona
+	}
+	limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+	if err != nil || limit <= 0 || limit > 100 {
+		limit = 50 // Valor predeterminado si hay un error o no se proporciona
+	}
+
+	actions, total, err := s.Moderation.ListActions(limit, offset)
+	if err != nil {
+		writeError(w, r, http.StatusInternalServerError, CodeInternal, "Error al obtener el registro de moderación")
+		return
+	}
+
+	var result = map[string]interface{}{}
+	result["actions"] = actions
+	result["total"] = total
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(result)
+}
+
+// routineAuthorID devuelve el dueño de la rutina, o nil si no se conoce; las rutinas anteriores
+// a owner_id solo tienen la relación user_make_routine
+func routineAuthorID(routine models.Routine) *string {
+	if routine.OwnerID != "" {
+		return &routine.OwnerID
+	}
+	if len(routine.Users) > 0 {
+		return &routine.Users[0].ID
+	}
+	return nil
+}
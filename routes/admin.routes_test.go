@@ -0,0 +1,148 @@
+package routes
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/danilsgit/gym-stats-backend/models"
+	"github.com/danilsgit/gym-stats-backend/store"
+)
+
+// failingModeration es un ModerationStore cuyo Apply siempre falla, como si fallara la transacción
+type failingModeration struct {
+	store.ModerationStore
+}
+
+func (failingModeration) Apply(change *store.ModerationChange) error {
+	return errors.New("fallo inyectado")
+}
+
+// reportedRoutine crea una rutina pública de author con un reporte pendiente
+func (api *testAPI) reportedRoutine(author models.User, reporter models.User) (models.Routine, models.RoutineReport) {
+	api.t.Helper()
+	routine := api.createRoutine(author.ID, "Reportada", true)
+	report := models.RoutineReport{RoutineID: routine.ID, ReporterID: reporter.ID, Reason: models.ReportSpam}
+	if err := api.memory.Moderation.CreateReport(&report); err != nil {
+		api.t.Fatal(err)
+	}
+	return routine, report
+}
+
+func TestResolveReportAppliesActionResolvesAndLogs(t *testing.T) {
+	api := newTestAPI(t)
+	author, _ := api.createUser("ana")
+	reporter, _ := api.createUser("beto")
+	_, token := api.createUserWithRole("mod", models.RoleAdmin)
+	routine, report := api.reportedRoutine(author, reporter)
+
+	rec := api.do("PUT", fmt.Sprintf("/admin/reports/%d", report.ID), token, models.ResolveReportRequest{Action: models.ModerationHide})
+	expectStatus(t, rec, http.StatusOK)
+
+	stored, err := api.memory.Routines.FindByID(routine.ID)
+	if err != nil || !stored.Hidden {
+		t.Fatalf("la rutina no quedó oculta: %+v, %v", stored, err)
+	}
+	resolved, _ := api.memory.Moderation.FindReport(report.ID)
+	if resolved.Status != models.ReportActioned || resolved.Action != models.ModerationHide {
+		t.Fatalf("reporte inesperado: %+v", resolved)
+	}
+	actions, _, _ := api.memory.Moderation.ListActions(10, 0)
+	if len(actions) != 1 || actions[0].TargetUserID == nil || *actions[0].TargetUserID != author.ID {
+		t.Fatalf("registro de auditoría inesperado: %+v", actions)
+	}
+}
+
+// Si la transacción falla no queda ningún efecto aplicado sin su entrada en el registro
+func TestModerationFailureAppliesNothing(t *testing.T) {
+	api := newTestAPI(t)
+	author, authorToken := api.createUser("ana")
+	reporter, _ := api.createUser("beto")
+	_, token := api.createUserWithRole("mod", models.RoleAdmin)
+	routine, report := api.reportedRoutine(author, reporter)
+	api.server.Moderation = failingModeration{api.memory.Moderation}
+
+	requests := []struct {
+		name   string
+		method string
+		path   string
+		body   interface{}
+	}{
+		{"resolver con hide", "PUT", fmt.Sprintf("/admin/reports/%d", report.ID), models.ResolveReportRequest{Action: models.ModerationHide}},
+		{"resolver con unpublish", "PUT", fmt.Sprintf("/admin/reports/%d", report.ID), models.ResolveReportRequest{Action: models.ModerationUnpublish}},
+		{"resolver con suspend", "PUT", fmt.Sprintf("/admin/reports/%d", report.ID), models.ResolveReportRequest{Action: models.ModerationSuspend}},
+		{"despublicar", "PUT", fmt.Sprintf("/admin/routines/%d/unpublish", routine.ID), nil},
+		{"ocultar", "PUT", fmt.Sprintf("/admin/routines/%d/hidden", routine.ID), models.HideRoutineRequest{Hidden: true}},
+		{"deshabilitar usuario", "PUT", "/admin/users/" + author.ID + "/disabled", models.DisableUserRequest{Disabled: true}},
+	}
+	for _, req := range requests {
+		t.Run(req.name, func(t *testing.T) {
+			expectStatus(t, api.do(req.method, req.path, token, req.body), http.StatusInternalServerError)
+		})
+	}
+
+	stored, _ := api.memory.Routines.FindByID(routine.ID)
+	if !stored.Public || stored.Hidden {
+		t.Fatalf("la rutina cambió: public=%v hidden=%v", stored.Public, stored.Hidden)
+	}
+	if pending, _ := api.memory.Moderation.FindReport(report.ID); pending.Status != models.ReportPending {
+		t.Fatalf("el reporte quedó %q", pending.Status)
+	}
+	if user, _ := api.memory.Users.FindByID(author.ID); user.Disabled {
+		t.Fatal("el autor quedó deshabilitado")
+	}
+	expectStatus(t, api.do("GET", "/users/routines", authorToken, nil), http.StatusOK)
+}
+
+// Sin autor conocido la entrada del registro no tiene usuario afectado (y no uno vacío)
+func TestRoutineAuthorID(t *testing.T) {
+	if id := routineAuthorID(models.Routine{}); id != nil {
+		t.Fatalf("sin autor: %q, se esperaba nil", *id)
+	}
+	if id := routineAuthorID(models.Routine{Users: []models.User{{ID: "legado"}}}); id == nil || *id != "legado" {
+		t.Fatalf("rutina anterior a owner_id: %v", id)
+	}
+	if id := routineAuthorID(models.Routine{OwnerID: "dueño", Users: []models.User{{ID: "legado"}}}); id == nil || *id != "dueño" {
+		t.Fatalf("rutina con owner_id: %v", id)
+	}
+}
+
+func TestDisableUserRevokesSessions(t *testing.T) {
+	api := newTestAPI(t)
+	user, userToken := api.createUser("ana")
+	_, token := api.createUserWithRole("mod", models.RoleAdmin)
+
+	expectStatus(t, api.do("PUT", "/admin/users/no-existe/disabled", token, models.DisableUserRequest{Disabled: true}), http.StatusNotFound)
+
+	expectStatus(t, api.do("PUT", "/admin/users/"+user.ID+"/disabled", token, models.DisableUserRequest{Disabled: true}), http.StatusOK)
+	expectStatus(t, api.do("GET", "/users/routines", userToken, nil), http.StatusForbidden)
+
+	actions, _, _ := api.memory.Moderation.ListActions(10, 0)
+	if len(actions) != 1 || actions[0].Action != models.ModerationSuspend || *actions[0].TargetUserID != user.ID {
+		t.Fatalf("registro de auditoría inesperado: %+v", actions)
+	}
+}
+
+// Solo los administradores ven y suspenden usuarios; los coaches moderan rutinas pero no cuentas
+func TestAdminUserRoutesRequireAdmin(t *testing.T) {
+	api := newTestAPI(t)
+	target, targetToken := api.createUser("ana")
+	_, adminToken := api.createUserWithRole("admin", models.RoleAdmin)
+
+	for _, role := range []string{models.RoleUser, models.RoleCoach} {
+		t.Run(role, func(t *testing.T) {
+			_, token := api.createUserWithRole("rol-"+role, role)
+			expectError(t, api.do("GET", "/admin/users", token, nil), http.StatusForbidden, CodeForbidden)
+			expectError(t, api.do("PUT", "/admin/users/"+target.ID+"/disabled", token, models.DisableUserRequest{Disabled: true}), http.StatusForbidden, CodeForbidden)
+		})
+	}
+
+	// Nada cambió: la cuenta sigue activa y sin acciones registradas
+	expectStatus(t, api.do("GET", "/users/routines", targetToken, nil), http.StatusOK)
+	actions, _, _ := api.memory.Moderation.ListActions(10, 0)
+	if len(actions) != 0 {
+		t.Fatalf("se registraron acciones: %+v", actions)
+	}
+	expectStatus(t, api.do("GET", "/admin/users", adminToken, nil), http.StatusOK)
+}
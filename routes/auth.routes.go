@@ -2,16 +2,23 @@ package routes
 
 import (
 	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"os"
 	"strings"
 	"time"
 
-	"github.com/danilsgit/gym-stats-backend/db"
 	"github.com/danilsgit/gym-stats-backend/models"
+	"github.com/danilsgit/gym-stats-backend/oidc"
+	"github.com/danilsgit/gym-stats-backend/store"
 	"github.com/dgrijalva/jwt-go"
+	"github.com/google/uuid"
 	"golang.org/x/crypto/bcrypt"
 )
 
@@ -22,13 +29,23 @@ func init() {
 	jwtKey = []byte(os.Getenv("JWT_KEY"))
 }
 
-func GenerateJWT(userID string) (string, error) {
+// Los tokens de acceso duran poco; la sesión se mantiene renovándolos con el refresh token
+const (
+	accessTokenTTL  = 15 * time.Minute
+	refreshTokenTTL = 30 * 24 * time.Hour
+)
+
+// GenerateJWT genera el token de acceso del usuario para la sesión (familia de refresh tokens) dada
+func GenerateJWT(user models.User, sessionID string) (string, error) {
 	token := jwt.New(jwt.SigningMethodHS256)
 	claims := token.Claims.(jwt.MapClaims)
 
 	claims["authorized"] = true
-	claims["user_id"] = userID
-	claims["exp"] = time.Now().Add(time.Hour * 24).Unix() // El token expira en 24 horas
+	claims["user_id"] = user.ID
+	claims["sid"] = sessionID
+	claims["role"] = user.Role
+	claims["tv"] = user.TokenVersion                      // Versión de los tokens del usuario al emitirlo
+	claims["exp"] = time.Now().Add(accessTokenTTL).Unix() // El token expira en 15 minutos
 
 	tokenString, err := token.SignedString(jwtKey)
 	if err != nil {
@@ -38,15 +55,58 @@ func GenerateJWT(userID string) (string, error) {
 	return tokenString, nil
 }
 
-func JwtAuthentication(next http.Handler) http.Handler {
+// issueTokens genera un token de acceso y un refresh token nuevo para la sesión.
+// Si sessionID está vacío se inicia una sesión nueva.
+func (s *Server) issueTokens(user models.User, sessionID string) (string, string, error) {
+	if sessionID == "" {
+		sessionID = uuid.New().String()
+	}
+
+	// En la base de datos solo se guarda el hash del refresh token
+	refreshToken, err := newRandomToken()
+	if err != nil {
+		return "", "", err
+	}
+	if err := s.Tokens.Create(&models.RefreshToken{
+		UserID:    user.ID,
+		FamilyID:  sessionID,
+		TokenHash: hashToken(refreshToken),
+		ExpiresAt: time.Now().Add(refreshTokenTTL),
+	}); err != nil {
+		return "", "", err
+	}
+
+	accessToken, err := GenerateJWT(user, sessionID)
+	if err != nil {
+		return "", "", err
+	}
+	return accessToken, refreshToken, nil
+}
+
+// newRandomToken genera un token opaco de 32 bytes aleatorios
+func newRandomToken() (string, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(raw), nil
+}
+
+// hashToken devuelve el SHA-256 en hexadecimal de un token
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func (s *Server) JwtAuthentication(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		authorizationHeader := r.Header.Get("Authorization")
 		if authorizationHeader == "" {
-			http.Error(w, "Acceso denegado. No se encontró el token de autorización", http.StatusForbidden)
+			writeError(w, r, http.StatusForbidden, CodeMissingToken, "Acceso denegado. No se encontró el token de autorización")
 			return
 		}
 
-		tokenString := strings.Split(authorizationHeader, " ")[1]
+		tokenString := strings.TrimPrefix(authorizationHeader, "Bearer ")
 		token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
 			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
 				return nil, fmt.Errorf("Error inesperado al validar el token")
@@ -55,42 +115,169 @@ func JwtAuthentication(next http.Handler) http.Handler {
 		})
 
 		if err != nil {
-			http.Error(w, "Token de autorización inválido", http.StatusForbidden)
+			writeError(w, r, http.StatusForbidden, CodeInvalidToken, "Token de autorización inválido")
+			return
+		}
+
+		claims, ok := token.Claims.(jwt.MapClaims)
+		if !ok || !token.Valid {
+			writeError(w, r, http.StatusForbidden, CodeInvalidToken, "Token de autorización inválido")
+			return
+		}
+
+		// Comprobar que la sesión del token no se haya cerrado
+		sessionID, _ := claims["sid"].(string)
+		if sessionID == "" {
+			writeError(w, r, http.StatusForbidden, CodeInvalidToken, "Token de autorización inválido")
+			return
+		}
+		active, err := s.Tokens.FamilyActive(sessionID)
+		if err != nil {
+			writeError(w, r, http.StatusInternalServerError, CodeInternal, "Error al comprobar la sesión")
+			return
+		}
+		if !active {
+			writeError(w, r, http.StatusForbidden, CodeSessionRevoked, "La sesión fue cerrada. Inicia sesión nuevamente")
+			return
+		}
+
+		// Los tokens emitidos antes de cambiar la contraseña dejan de servir
+		userID, _ := claims["user_id"].(string)
+		user, err := s.Users.FindByID(userID)
+		if errors.Is(err, store.ErrNotFound) {
+			writeError(w, r, http.StatusForbidden, CodeInvalidToken, "Token de autorización inválido")
+			return
+		}
+		if err != nil {
+			writeError(w, r, http.StatusInternalServerError, CodeInternal, "Error al comprobar el usuario")
+			return
+		}
+		tokenVersion, _ := claims["tv"].(float64)
+		if int(tokenVersion) != user.TokenVersion {
+			writeError(w, r, http.StatusForbidden, CodeSessionRevoked, "La sesión fue cerrada. Inicia sesión nuevamente")
+			return
+		}
+		if user.Disabled {
+			writeError(w, r, http.StatusForbidden, CodeAccountDisabled, errAccountDisabled.Error())
 			return
 		}
 
-		if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
-			ctx := context.WithValue(r.Context(), "userID", claims["user_id"])
-			next.ServeHTTP(w, r.WithContext(ctx))
-		} else {
-			http.Error(w, "Token de autorización inválido", http.StatusForbidden)
+		// El rol se toma de la base de datos para que los cambios apliquen de inmediato
+		ctx := context.WithValue(r.Context(), "userID", user.ID)
+		ctx = context.WithValue(ctx, "role", user.Role)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// Renovar el token de acceso con un refresh token. El refresh token se rota: el enviado deja de
+// servir y se devuelve uno nuevo. Si se presenta un refresh token ya usado se asume que fue
+// robado y se cierra la sesión completa.
+func (s *Server) RefreshTokenHandler(w http.ResponseWriter, r *http.Request) {
+	var req models.RefreshTokenRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.RefreshToken == "" {
+		writeError(w, r, http.StatusBadRequest, CodeValidationFailed, "El campo refreshToken es obligatorio")
+		return
+	}
+
+	stored, err := s.Tokens.FindByHash(hashToken(req.RefreshToken))
+	if err != nil {
+		writeError(w, r, http.StatusUnauthorized, CodeRefreshTokenInvalid, "Refresh token inválido")
+		return
+	}
+	if stored.RevokedAt != nil {
+		writeError(w, r, http.StatusUnauthorized, CodeSessionRevoked, "La sesión fue cerrada. Inicia sesión nuevamente")
+		return
+	}
+	if time.Now().After(stored.ExpiresAt) {
+		writeError(w, r, http.StatusUnauthorized, CodeRefreshTokenExpired, "El refresh token expiró. Inicia sesión nuevamente")
+		return
+	}
+
+	// Rotar el token; si ya estaba usado es una reutilización y se revoca la sesión
+	rotated := false
+	if stored.UsedAt == nil {
+		rotated, err = s.Tokens.MarkUsed(stored.ID)
+		if err != nil {
+			writeError(w, r, http.StatusInternalServerError, CodeInternal, "Error al renovar la sesión")
 			return
 		}
+	}
+	if !rotated {
+		if err := s.Tokens.RevokeFamily(stored.FamilyID); err != nil {
+			writeError(w, r, http.StatusInternalServerError, CodeInternal, "Error al cerrar la sesión")
+			return
+		}
+		writeError(w, r, http.StatusUnauthorized, CodeRefreshTokenReused, "Refresh token reutilizado. Se cerró la sesión por seguridad")
+		return
+	}
+
+	user, err := s.Users.FindByID(stored.UserID)
+	if err != nil {
+		writeError(w, r, http.StatusUnauthorized, CodeUserNotFound, "Usuario no encontrado")
+		return
+	}
+	if user.Disabled {
+		writeError(w, r, http.StatusForbidden, CodeAccountDisabled, errAccountDisabled.Error())
+		return
+	}
+
+	accessToken, refreshToken, err := s.issueTokens(user, stored.FamilyID)
+	if err != nil {
+		writeError(w, r, http.StatusInternalServerError, CodeInternal, "Error al generar el token JWT")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"token":        accessToken,
+		"refreshToken": refreshToken,
 	})
 }
 
-func LoginHandler(w http.ResponseWriter, r *http.Request) {
-	var credentials models.User
+// Cerrar la sesión del refresh token: deja de servir él, los tokens de acceso de la sesión
+// y cualquier otro refresh token de la misma familia
+func (s *Server) LogoutHandler(w http.ResponseWriter, r *http.Request) {
+	var req models.RefreshTokenRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.RefreshToken == "" {
+		writeError(w, r, http.StatusBadRequest, CodeValidationFailed, "El campo refreshToken es obligatorio")
+		return
+	}
+
+	stored, err := s.Tokens.FindByHash(hashToken(req.RefreshToken))
+	if err != nil {
+		writeError(w, r, http.StatusUnauthorized, CodeRefreshTokenInvalid, "Refresh token inválido")
+		return
+	}
+	if err := s.Tokens.RevokeFamily(stored.FamilyID); err != nil {
+		writeError(w, r, http.StatusInternalServerError, CodeInternal, "Error al cerrar la sesión")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode("Sesión cerrada con éxito")
+}
+
+func (s *Server) LoginHandler(w http.ResponseWriter, r *http.Request) {
+	var credentials models.LoginRequest
 	err := json.NewDecoder(r.Body).Decode(&credentials)
 	if err != nil {
-		http.Error(w, "Error al decodificar las credenciales", http.StatusBadRequest)
+		writeError(w, r, http.StatusBadRequest, CodeInvalidBody, "Error al decodificar las credenciales")
 		return
 	}
 
 	// Validar datos vacíos
 	if credentials.Email == "" || credentials.Password == "" {
-		http.Error(w, "Todos los campos son obligatorios", http.StatusBadRequest)
+		writeError(w, r, http.StatusBadRequest, CodeValidationFailed, "Todos los campos son obligatorios")
 		return
 	}
 
 	// Buscar usuario en la base de datos
-	var userExist models.User
-	result := db.DB.Where("email = ?", credentials.Email).First(&userExist)
-	if result.Error != nil {
-		http.Error(w, "Usuario sin cuenta", http.StatusNotFound)
+	userExist, err := s.Users.FindByEmail(credentials.Email)
+	if err != nil {
+		writeError(w, r, http.StatusNotFound, CodeAccountNotFound, "Usuario sin cuenta")
 		return
 	} else if userExist.Password == "" {
-		http.Error(w, "Utiliza el inicio de sesión social", http.StatusBadRequest)
+		writeError(w, r, http.StatusBadRequest, CodeSocialLoginRequired, "Utiliza el inicio de sesión social")
 		return
 	}
 
@@ -98,80 +285,182 @@ func LoginHandler(w http.ResponseWriter, r *http.Request) {
 	errPassword := bcrypt.CompareHashAndPassword([]byte(userExist.Password), []byte(credentials.Password))
 	if errPassword != nil {
 		// Si hay un error, la comparación falló, lo que significa que las contraseñas no coinciden
-		http.Error(w, "Contraseña incorrecta", http.StatusUnauthorized)
+		writeError(w, r, http.StatusUnauthorized, CodeInvalidCredentials, "Contraseña incorrecta")
 		return
 	}
 
-	tokenString, err := GenerateJWT(userExist.ID)
-	if err != nil {
-		http.Error(w, "Error al generar el token JWT", http.StatusInternalServerError)
+	// Se comprueba después de la contraseña para no revelar a cualquiera que la cuenta está suspendida
+	if userExist.Disabled {
+		writeError(w, r, http.StatusForbidden, CodeAccountDisabled, errAccountDisabled.Error())
 		return
 	}
 
-	// Información del usuario
-	userInfo := map[string]interface{}{
-		"id":       userExist.ID,
-		"username": userExist.Username,
-		"email":    userExist.Email,
-		"role":     userExist.Role,
+	tokenString, refreshToken, err := s.issueTokens(userExist, "")
+	if err != nil {
+		writeError(w, r, http.StatusInternalServerError, CodeInternal, "Error al generar el token JWT")
+		return
 	}
 
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(map[string]interface{}{
-		"token": tokenString,
-		"user":  userInfo,
+		"token":        tokenString,
+		"refreshToken": refreshToken,
+		"user":         userExist.Private(),
 	})
 }
 
-func LoginSocialHandler(w http.ResponseWriter, r *http.Request) {
-	var credentials models.User
-	err := json.NewDecoder(r.Body).Decode(&credentials)
+// Iniciar sesión con un proveedor social. El cliente envía el ID token que emitió el proveedor;
+// se verifica su firma contra el JWKS del proveedor y la cuenta se identifica por (provider, sub),
+// nunca por un correo enviado en el cuerpo.
+func (s *Server) LoginSocialHandler(w http.ResponseWriter, r *http.Request) {
+	var req models.SocialLoginRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
 	if err != nil {
-		http.Error(w, "Error al decodificar el cuerpo de la solicitud", http.StatusBadRequest)
+		writeError(w, r, http.StatusBadRequest, CodeInvalidBody, "Error al decodificar el cuerpo de la solicitud")
+		return
+	}
+	if req.Provider == "" || req.IDToken == "" {
+		writeError(w, r, http.StatusBadRequest, CodeValidationFailed, "Los campos provider e idToken son obligatorios")
 		return
 	}
 
-	// Buscar usuario en la base de datos
+	// Verificar el ID token con el proveedor
+	identity, err := s.OIDC.Verify(req.Provider, req.IDToken)
+	if errors.Is(err, oidc.ErrUnknownProvider) {
+		writeError(w, r, http.StatusBadRequest, CodeProviderUnsupported, "Proveedor de inicio de sesión no soportado")
+		return
+	}
+	if err != nil {
+		writeError(w, r, http.StatusUnauthorized, CodeIdentityTokenInvalid, "Token de identidad inválido")
+		return
+	}
+
+	// Buscar el usuario vinculado a la cuenta del proveedor
 	var userExist models.User
-	result := db.DB.Where("email = ?", credentials.Email).First(&userExist)
-	if result.Error != nil {
-		// Crear usuario si no existe, primero valida si el username ya existe, si existe se le concatenará la id
-		resultUsername := db.DB.Where("username = ?", credentials.Username).First(&userExist)
-		if resultUsername.Error == nil {
-			// Agarrar los últimos 4 caracteres del ID para concatenarlos al username
-			credentials.Username = credentials.Username + "-" + credentials.ID[len(credentials.ID)-4:]
-		}
-		createdUser := db.DB.Create(&credentials)
-		if createdUser.Error != nil {
-			http.Error(w, "Error al crear el usuario", http.StatusInternalServerError)
+	linked, err := s.Users.FindIdentity(identity.Provider, identity.Subject)
+	switch {
+	case err == nil:
+		userExist, err = s.Users.FindByID(linked.UserID)
+		if err != nil {
+			writeError(w, r, http.StatusNotFound, CodeUserNotFound, "Usuario no encontrado")
+			return
+		}
+	case errors.Is(err, store.ErrNotFound):
+		// Primera vez con esta cuenta: solo se vincula o se crea con un correo verificado por el proveedor
+		userExist, err = s.linkSocialIdentity(identity, req.Username)
+		if err != nil {
+			writeSocialLoginError(w, r, err)
 			return
 		}
-		userExist = credentials
+	default:
+		writeError(w, r, http.StatusInternalServerError, CodeInternal, "Error al buscar la cuenta vinculada")
+		return
 	}
 
-	// Si tiene contraseña, quiere decir que no se ha registrado con un proveedor social
-	if userExist.Password != "" {
-		http.Error(w, "Utiliza el inicio de sesión tradicional", http.StatusBadRequest)
+	if userExist.Disabled {
+		writeError(w, r, http.StatusForbidden, CodeAccountDisabled, errAccountDisabled.Error())
 		return
 	}
 
-	tokenString, err := GenerateJWT(userExist.ID)
+	tokenString, refreshToken, err := s.issueTokens(userExist, "")
 	if err != nil {
-		http.Error(w, "Error al generar el token JWT", http.StatusInternalServerError)
+		writeError(w, r, http.StatusInternalServerError, CodeInternal, "Error al generar el token JWT")
 		return
 	}
 
-	// Información del usuario
-	userInfo := map[string]interface{}{
-		"id":       userExist.ID,
-		"username": userExist.Username,
-		"email":    userExist.Email,
-		"role":     userExist.Role,
-	}
-
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(map[string]interface{}{
-		"token": tokenString,
-		"user":  userInfo,
+		"token":        tokenString,
+		"refreshToken": refreshToken,
+		"user":         userExist.Private(),
 	})
 }
+
+var errAccountDisabled = errors.New("Tu cuenta fue deshabilitada")
+
+var (
+	errSocialEmailNotVerified = errors.New("El proveedor no entregó un correo verificado")
+	errSocialPasswordAccount  = errors.New("Utiliza el inicio de sesión tradicional")
+)
+
+// linkSocialIdentity vincula la cuenta del proveedor con el usuario que tiene su correo (si se
+// registró con un proveedor social) o crea un usuario nuevo
+func (s *Server) linkSocialIdentity(identity oidc.Identity, username string) (models.User, error) {
+	if identity.Email == "" || !identity.EmailVerified {
+		return models.User{}, errSocialEmailNotVerified
+	}
+
+	user, err := s.Users.FindByEmail(identity.Email)
+	switch {
+	case err == nil:
+		// Si tiene contraseña, quiere decir que no se ha registrado con un proveedor social
+		if user.Password != "" {
+			return models.User{}, errSocialPasswordAccount
+		}
+		// El proveedor confirmó el correo
+		if !user.EmailVerified {
+			if err := s.Users.MarkEmailVerified(user.ID); err != nil {
+				return models.User{}, err
+			}
+			user.EmailVerified = true
+		}
+	case errors.Is(err, store.ErrNotFound):
+		username, err := s.availableUsername(socialUsername(identity, username))
+		if err != nil {
+			return models.User{}, err
+		}
+		user = models.User{Username: username, Email: identity.Email, EmailVerified: true}
+		if err := s.Users.Create(&user); err != nil {
+			return models.User{}, err
+		}
+	default:
+		return models.User{}, err
+	}
+
+	if err := s.Users.CreateIdentity(&models.UserIdentity{
+		UserID:   user.ID,
+		Provider: identity.Provider,
+		Subject:  identity.Subject,
+		Email:    identity.Email,
+	}); err != nil {
+		return models.User{}, err
+	}
+	return user, nil
+}
+
+// socialUsername elige el username de un usuario nuevo: el enviado, el nombre del proveedor o
+// la parte local del correo
+func socialUsername(identity oidc.Identity, username string) string {
+	if username = strings.TrimSpace(username); username != "" {
+		return username
+	}
+	if name := strings.TrimSpace(identity.Name); name != "" {
+		return name
+	}
+	return strings.SplitN(identity.Email, "@", 2)[0]
+}
+
+// availableUsername devuelve el username o, si ya existe, el username con un sufijo aleatorio
+func (s *Server) availableUsername(username string) (string, error) {
+	candidate := username
+	for attempt := 0; attempt < 10; attempt++ {
+		_, err := s.Users.FindByUsername(candidate)
+		if errors.Is(err, store.ErrNotFound) {
+			return candidate, nil
+		}
+		if err != nil {
+			return "", err
+		}
+		candidate = username + "-" + uuid.New().String()[:4]
+	}
+	return "", errors.New("no hay un nombre de usuario disponible")
+}
+
+// writeSocialLoginError responde según el motivo por el que no se pudo vincular la cuenta
+func writeSocialLoginError(w http.ResponseWriter, r *http.Request, err error) {
+	if errors.Is(err, errSocialEmailNotVerified) || errors.Is(err, errSocialPasswordAccount) {
+		writeError(w, r, http.StatusBadRequest, CodeSocialLoginFailed, err.Error())
+		return
+	}
+	writeError(w, r, http.StatusInternalServerError, CodeInternal, "Error al crear el usuario")
+}
@@ -0,0 +1,190 @@
+package routes
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/danilsgit/gym-stats-backend/models"
+	"github.com/danilsgit/gym-stats-backend/store"
+	"github.com/dgrijalva/jwt-go"
+)
+
+// tokenResponse es la respuesta de POST /auth/refresh
+type tokenResponse struct {
+	Token        string `json:"token"`
+	RefreshToken string `json:"refreshToken"`
+}
+
+// startSession abre una sesión nueva del usuario y devuelve su token de acceso y su refresh token
+func (api *testAPI) startSession(user models.User) (string, string) {
+	api.t.Helper()
+	access, refresh, err := api.server.issueTokens(user, "")
+	if err != nil {
+		api.t.Fatalf("abrir sesión para %s: %v", user.Username, err)
+	}
+	return access, refresh
+}
+
+// refresh renueva la sesión con el refresh token y exige una respuesta correcta
+func (api *testAPI) refresh(refreshToken string) tokenResponse {
+	api.t.Helper()
+	rec := api.do("POST", "/auth/refresh", "", models.RefreshTokenRequest{RefreshToken: refreshToken})
+	expectStatus(api.t, rec, http.StatusOK)
+	var tokens tokenResponse
+	decode(api.t, rec, &tokens)
+	if tokens.Token == "" || tokens.RefreshToken == "" || tokens.RefreshToken == refreshToken {
+		api.t.Fatalf("tokens inesperados: %+v", tokens)
+	}
+	return tokens
+}
+
+// El refresh token se rota: el nuevo sirve y el anterior deja de servir
+func TestRefreshTokenRotation(t *testing.T) {
+	api := newTestAPI(t)
+	user, _ := api.createUser("ana")
+	_, refresh := api.startSession(user)
+
+	tokens := api.refresh(refresh)
+	expectStatus(t, api.do("GET", "/users/routines", tokens.Token, nil), http.StatusOK)
+	tokens = api.refresh(tokens.RefreshToken)
+	expectStatus(t, api.do("GET", "/users/routines", tokens.Token, nil), http.StatusOK)
+
+	expectError(t, api.do("POST", "/auth/refresh", "", models.RefreshTokenRequest{RefreshToken: "no-existe"}), http.StatusUnauthorized, CodeRefreshTokenInvalid)
+	expectError(t, api.do("POST", "/auth/refresh", "", nil), http.StatusBadRequest, CodeValidationFailed)
+}
+
+// Reutilizar un refresh token ya rotado cierra la sesión completa, pero no las demás del usuario
+func TestRefreshTokenReuseRevokesFamily(t *testing.T) {
+	api := newTestAPI(t)
+	user, otherSession := api.createUser("ana")
+	access, refresh := api.startSession(user)
+	tokens := api.refresh(refresh)
+
+	expectError(t, api.do("POST", "/auth/refresh", "", models.RefreshTokenRequest{RefreshToken: refresh}), http.StatusUnauthorized, CodeRefreshTokenReused)
+
+	// Se revocan el refresh token vigente de la sesión y sus tokens de acceso
+	expectError(t, api.do("POST", "/auth/refresh", "", models.RefreshTokenRequest{RefreshToken: tokens.RefreshToken}), http.StatusUnauthorized, CodeSessionRevoked)
+	expectError(t, api.do("GET", "/users/routines", tokens.Token, nil), http.StatusForbidden, CodeSessionRevoked)
+	expectError(t, api.do("GET", "/users/routines", access, nil), http.StatusForbidden, CodeSessionRevoked)
+
+	expectStatus(t, api.do("GET", "/users/routines", otherSession, nil), http.StatusOK)
+}
+
+func TestRefreshTokenExpired(t *testing.T) {
+	api := newTestAPI(t)
+	user, _ := api.createUser("ana")
+	if err := api.memory.Tokens.Create(&models.RefreshToken{
+		UserID:    user.ID,
+		FamilyID:  "sesion-vencida",
+		TokenHash: hashToken("refresh-vencido"),
+		ExpiresAt: time.Now().Add(-time.Minute),
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	expectError(t, api.do("POST", "/auth/refresh", "", models.RefreshTokenRequest{RefreshToken: "refresh-vencido"}), http.StatusUnauthorized, CodeRefreshTokenExpired)
+}
+
+func TestRefreshTokenDisabledUser(t *testing.T) {
+	api := newTestAPI(t)
+	user, _ := api.createUser("ana")
+	_, refresh := api.startSession(user)
+	if err := api.memory.Users.SetDisabled(user.ID, true); err != nil {
+		t.Fatal(err)
+	}
+
+	expectError(t, api.do("POST", "/auth/refresh", "", models.RefreshTokenRequest{RefreshToken: refresh}), http.StatusForbidden, CodeAccountDisabled)
+}
+
+// Cerrar sesión revoca el token de acceso y el refresh token de esa sesión; las demás siguen activas
+func TestLogout(t *testing.T) {
+	api := newTestAPI(t)
+	user, otherSession := api.createUser("ana")
+	access, refresh := api.startSession(user)
+
+	expectStatus(t, api.do("POST", "/auth/logout", "", models.RefreshTokenRequest{RefreshToken: refresh}), http.StatusOK)
+
+	expectError(t, api.do("GET", "/users/routines", access, nil), http.StatusForbidden, CodeSessionRevoked)
+	expectError(t, api.do("POST", "/auth/refresh", "", models.RefreshTokenRequest{RefreshToken: refresh}), http.StatusUnauthorized, CodeSessionRevoked)
+	expectStatus(t, api.do("GET", "/users/routines", otherSession, nil), http.StatusOK)
+
+	expectError(t, api.do("POST", "/auth/logout", "", models.RefreshTokenRequest{RefreshToken: "no-existe"}), http.StatusUnauthorized, CodeRefreshTokenInvalid)
+}
+
+// signToken firma un token de acceso con los claims y la clave dados
+func signToken(t *testing.T, claims jwt.MapClaims, key []byte) string {
+	t.Helper()
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+func TestJwtAuthenticationRejectsInvalidTokens(t *testing.T) {
+	api := newTestAPI(t)
+	user, _ := api.createUser("ana")
+	_, refresh := api.startSession(user)
+	stored, err := api.memory.Tokens.FindByHash(hashToken(refresh))
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims := func(changes jwt.MapClaims) jwt.MapClaims {
+		claims := jwt.MapClaims{"user_id": user.ID, "sid": stored.FamilyID, "tv": user.TokenVersion, "exp": time.Now().Add(time.Minute).Unix()}
+		for name, value := range changes {
+			claims[name] = value
+		}
+		return claims
+	}
+
+	// Los claims completos firmados con la clave correcta sirven
+	expectStatus(t, api.do("GET", "/users/routines", signToken(t, claims(nil), jwtKey), nil), http.StatusOK)
+
+	tests := []struct {
+		name  string
+		token string
+		code  string
+	}{
+		{"sin sid", signToken(t, claims(jwt.MapClaims{"sid": ""}), jwtKey), CodeInvalidToken},
+		{"sesión desconocida", signToken(t, claims(jwt.MapClaims{"sid": "no-existe"}), jwtKey), CodeSessionRevoked},
+		{"otra clave", signToken(t, claims(nil), []byte("otra-clave")), CodeInvalidToken},
+		{"vencido", signToken(t, claims(jwt.MapClaims{"exp": time.Now().Add(-time.Minute).Unix()}), jwtKey), CodeInvalidToken},
+		{"versión anterior", signToken(t, claims(jwt.MapClaims{"tv": user.TokenVersion - 1}), jwtKey), CodeSessionRevoked},
+		{"usuario inexistente", signToken(t, claims(jwt.MapClaims{"user_id": "no-existe"}), jwtKey), CodeInvalidToken},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expectError(t, api.do("GET", "/users/routines", tt.token, nil), http.StatusForbidden, tt.code)
+		})
+	}
+}
+
+// failingUsers es un UserStore que falla al buscar usuarios por ID
+type failingUsers struct{ store.UserStore }
+
+func (failingUsers) FindByID(id string) (models.User, error) {
+	return models.User{}, errors.New("fallo inyectado")
+}
+
+// Un fallo de la base de datos al buscar al usuario no es un token inválido
+func TestJwtAuthenticationUserStoreError(t *testing.T) {
+	api := newTestAPI(t)
+	_, token := api.createUser("ana")
+	api.server.Users = failingUsers{api.memory.Users}
+
+	expectError(t, api.do("GET", "/users/routines", token, nil), http.StatusInternalServerError, CodeInternal)
+}
+
+// Una cuenta suspendida solo se informa a quien conoce la contraseña
+func TestLoginDisabledAccount(t *testing.T) {
+	api := newTestAPI(t)
+	user, _ := api.createUser("ana")
+	if err := api.memory.Users.SetDisabled(user.ID, true); err != nil {
+		t.Fatal(err)
+	}
+
+	expectError(t, api.do("POST", "/login", "", models.LoginRequest{Email: user.Email, Password: "otra-clave"}), http.StatusUnauthorized, CodeInvalidCredentials)
+	expectError(t, api.do("POST", "/login", "", models.LoginRequest{Email: user.Email, Password: "secreto123"}), http.StatusForbidden, CodeAccountDisabled)
+}
@@ -0,0 +1,83 @@
+package routes
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/danilsgit/gym-stats-backend/store"
+)
+
+var errDefinitionNotFound = errors.New("Ejercicio del catálogo no encontrado")
+
+// Catálogo global de ejercicios
+
+// Buscar en el catálogo por nombre o alias (?search=) y filtrar por músculo, equipamiento o patrón de movimiento
+func (s *Server) GetCatalogHandler(w http.ResponseWriter, r *http.Request) {
+	search := strings.TrimSpace(r.URL.Query().Get("search"))
+	muscle := r.URL.Query().Get("muscle")
+	equipment := r.URL.Query().Get("equipment")
+	pattern := r.URL.Query().Get("pattern")
+
+	offset, err := strconv.Atoi(r.URL.Query().Get("offset"))
+	if err != nil || offset < 0 {
+		offset = 0 // Valor predeterminado si hay un error o no se proporciona
+	}
+	limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+	if err != nil || limit <= 0 || limit > 100 {
+		limit = 50 // Valor predeterminado si hay un error o no se proporciona
+	}
+
+	definitions, total, err := s.Exercises.ListDefinitions(store.CatalogQuery{
+		Search:    search,
+		Muscle:    muscle,
+		Equipment: equipment,
+		Pattern:   pattern,
+		Limit:     limit,
+		Offset:    offset,
+	})
+	if err != nil {
+		writeError(w, r, http.StatusInternalServerError, CodeInternal, "Error al obtener el catálogo de ejercicios")
+		return
+	}
+
+	var result = map[string]interface{}{}
+	result["exercises"] = definitions
+	result["total"] = total
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(result)
+}
+
+// Obtener un ejercicio del catálogo
+func (s *Server) GetCatalogDefinitionHandler(w http.ResponseWriter, r *http.Request) {
+	definition, err := s.Exercises.FindDefinition(pathID(r, "id"))
+	if err != nil {
+		writeError(w, r, http.StatusNotFound, CodeDefinitionNotFound, errDefinitionNotFound.Error())
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(definition)
+}
+
+// resolveDefinition devuelve el ID del catálogo para un ejercicio: el enviado por el cliente
+// (que debe existir) o, si no se envía, el que coincida con el nombre o alguno de sus alias.
+func (s *Server) resolveDefinition(definitionID uint, name string) (*uint, error) {
+	id, err := s.Exercises.ResolveDefinition(definitionID, name)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, errDefinitionNotFound
+	}
+	return id, err
+}
+
+// writeDefinitionError responde con 400 si el ejercicio del catálogo no existe
+func writeDefinitionError(w http.ResponseWriter, r *http.Request, err error) {
+	if errors.Is(err, errDefinitionNotFound) {
+		writeError(w, r, http.StatusBadRequest, CodeDefinitionNotFound, err.Error())
+		return
+	}
+	writeError(w, r, http.StatusInternalServerError, CodeInternal, "Error al buscar el ejercicio en el catálogo")
+}
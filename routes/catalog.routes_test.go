@@ -0,0 +1,60 @@
+package routes
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/danilsgit/gym-stats-backend/models"
+)
+
+func TestGetCatalog(t *testing.T) {
+	api := newTestAPI(t)
+	api.memory.Exercises.AddDefinitions(
+		models.ExerciseDefinition{ID: 1, Slug: "bench-press", NameEn: "Bench Press", NameEs: "Press banca", PrimaryMuscles: []string{"chest"}, SecondaryMuscles: []string{"triceps"}, Equipment: []string{"barbell"}, MovementPattern: "push"},
+		models.ExerciseDefinition{ID: 2, Slug: "squat", NameEn: "Squat", NameEs: "Sentadilla", PrimaryMuscles: []string{"quads"}, Equipment: []string{"barbell"}, MovementPattern: "squat", Aliases: []string{"Back squat"}},
+		models.ExerciseDefinition{ID: 3, Slug: "push-up", NameEn: "Push-up", NameEs: "Flexión", PrimaryMuscles: []string{"chest"}, MovementPattern: "push"},
+	)
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"", []string{"Bench Press", "Push-up", "Squat"}},
+		{"?search=banca", []string{"Bench Press"}},
+		{"?search=back", []string{"Squat"}},
+		{"?muscle=triceps", []string{"Bench Press"}},
+		{"?muscle=chest&equipment=barbell", []string{"Bench Press"}},
+		{"?pattern=push&limit=1&offset=1", []string{"Push-up"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			rec := api.do("GET", "/exercises/catalog"+tt.query, "", nil)
+			expectStatus(t, rec, http.StatusOK)
+			var result struct {
+				Exercises []models.ExerciseDefinition `json:"exercises"`
+			}
+			decode(t, rec, &result)
+			var got []string
+			for _, definition := range result.Exercises {
+				got = append(got, definition.NameEn)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("got %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+
+	rec := api.do("GET", "/exercises/catalog/2", "", nil)
+	expectStatus(t, rec, http.StatusOK)
+	var definition models.ExerciseDefinition
+	decode(t, rec, &definition)
+	if definition.Slug != "squat" {
+		t.Fatalf("ejercicio inesperado: %+v", definition)
+	}
+	expectStatus(t, api.do("GET", "/exercises/catalog/99", "", nil), http.StatusNotFound)
+}
@@ -0,0 +1,113 @@
+package routes
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/danilsgit/gym-stats-backend/e1rm"
+	"github.com/danilsgit/gym-stats-backend/models"
+)
+
+// Calculadora de 1RM estimado
+
+// Calcular el 1RM estimado de un set con una fórmula (?formula=) o con todas si no se indica
+func E1RMHandler(w http.ResponseWriter, r *http.Request) {
+	reps, err := strconv.Atoi(r.URL.Query().Get("reps"))
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, CodeInvalidParameter, "El parámetro reps es obligatorio y debe ser un número entero")
+		return
+	}
+	weight, err := strconv.ParseFloat(r.URL.Query().Get("weight"), 64)
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, CodeInvalidParameter, "El parámetro weight es obligatorio y debe ser un número")
+		return
+	}
+	rpe, err := parseRPE(r)
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, CodeInvalidParameter, "El parámetro rpe debe ser un número")
+		return
+	}
+
+	var result = map[string]interface{}{}
+	result["reps"] = reps
+	result["weight"] = weight
+
+	// Una sola fórmula
+	if formula := r.URL.Query().Get("formula"); formula != "" {
+		value, err := e1rm.Estimate(formula, reps, weight, rpe)
+		if err != nil {
+			writeError(w, r, http.StatusBadRequest, CodeInvalidParameter, err.Error())
+			return
+		}
+		result["formula"] = formula
+		result["e1rm"] = value
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(result)
+		return
+	}
+
+	// Todas las fórmulas que apliquen (la de RPE solo si se envía el RPE)
+	estimates := map[string]float64{}
+	for _, formula := range e1rm.Formulas {
+		if formula == e1rm.RPE && rpe == 0 {
+			continue
+		}
+		value, err := e1rm.Estimate(formula, reps, weight, rpe)
+		if err == e1rm.ErrInvalidWeight || err == e1rm.ErrInvalidRPE {
+			writeError(w, r, http.StatusBadRequest, CodeInvalidParameter, err.Error())
+			return
+		}
+		if err != nil {
+			continue // Repeticiones fuera del rango de esta fórmula
+		}
+		estimates[formula] = value
+	}
+	if len(estimates) == 0 {
+		writeError(w, r, http.StatusBadRequest, CodeInvalidParameter, e1rm.ErrInvalidReps.Error())
+		return
+	}
+	result["estimates"] = estimates
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(result)
+}
+
+// applyE1RM agrega el 1RM estimado a cada set si la solicitud incluye ?e1rm=<fórmula>.
+// Los sets sin peso o fuera del rango de la fórmula se dejan sin estimación.
+func applyE1RM(r *http.Request, exercises []models.Exercise) error {
+	formula := r.URL.Query().Get("e1rm")
+	if formula == "" {
+		return nil
+	}
+	if !e1rm.Valid(formula) {
+		return e1rm.ErrUnknownFormula
+	}
+	rpe, err := parseRPE(r)
+	if err != nil {
+		return e1rm.ErrInvalidRPE
+	}
+
+	for i := range exercises {
+		for j := range exercises[i].Sets {
+			set := &exercises[i].Sets[j]
+			value, err := e1rm.Estimate(formula, set.Reps, set.Weight, rpe)
+			if err == e1rm.ErrInvalidRPE {
+				return err
+			}
+			if err == nil {
+				set.E1RM = &value
+			}
+		}
+	}
+	return nil
+}
+
+// parseRPE lee el parámetro opcional rpe (0 si no se envía)
+func parseRPE(r *http.Request) (float64, error) {
+	rpeStr := r.URL.Query().Get("rpe")
+	if rpeStr == "" {
+		return 0, nil
+	}
+	return strconv.ParseFloat(rpeStr, 64)
+}
@@ -0,0 +1,135 @@
+package routes
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"github.com/google/uuid"
+)
+
+// Códigos de error. Los clientes deben decidir según el código y no según el mensaje,
+// que es texto para mostrar y puede cambiar.
+const (
+	// Generales
+	CodeInvalidBody      = "invalid_body"      // El cuerpo no es JSON válido o no tiene el formato esperado
+	CodeValidationFailed = "validation_failed" // Faltan campos o tienen valores inválidos
+	CodeInvalidParameter = "invalid_parameter" // Un parámetro de la URL no es válido
+	CodeNotFound         = "not_found"         // La ruta no existe
+	CodeMethodNotAllowed = "method_not_allowed"
+	CodeRateLimited      = "rate_limited"
+	CodeInternal         = "internal_error"
+
+	// Autenticación y sesiones
+	CodeMissingToken         = "missing_token"
+	CodeInvalidToken         = "invalid_token"
+	CodeSessionRevoked       = "session_revoked"
+	CodeRefreshTokenInvalid  = "refresh_token_invalid"
+	CodeRefreshTokenExpired  = "refresh_token_expired"
+	CodeRefreshTokenReused   = "refresh_token_reused"
+	CodeAccountNotFound      = "account_not_found"
+	CodeAccountDisabled      = "account_disabled"
+	CodeInvalidCredentials   = "invalid_credentials"
+	CodeSocialLoginRequired  = "social_login_required"
+	CodeSocialLoginFailed    = "social_login_failed"
+	CodeProviderUnsupported  = "provider_unsupported"
+	CodeIdentityTokenInvalid = "identity_token_invalid"
+	CodeInvalidLink          = "invalid_link" // Enlace de verificación o de restablecimiento inválido o vencido
+	CodeEmailAlreadyVerified = "email_already_verified"
+	CodeEmailNotVerified     = "email_not_verified"
+
+	// Permisos
+	CodeForbidden = "forbidden"
+
+	// Recursos
+	CodeUserNotFound       = "user_not_found"
+	CodeRoutineNotFound    = "routine_not_found"
+	CodeExerciseNotFound   = "exercise_not_found"
+	CodeWorkoutNotFound    = "workout_not_found"
+	CodeDefinitionNotFound = "definition_not_found"
+	CodeReportNotFound     = "report_not_found"
+	CodeEmailTaken         = "email_taken"
+	CodeUsernameTaken      = "username_taken"
+	CodeRoutineNameTaken   = "routine_name_taken"
+	CodeWorkoutFinished    = "workout_finished"
+	CodeReportDuplicate    = "report_duplicate"
+	CodeReportResolved     = "report_resolved"
+)
+
+// ErrorBody es el contenido de una respuesta de error
+type ErrorBody struct {
+	Code      string      `json:"code"`
+	Message   string      `json:"message"`
+	Details   interface{} `json:"details,omitempty"`
+	RequestID string      `json:"requestId,omitempty"`
+}
+
+// ErrorResponse es el sobre de todas las respuestas de error: {"error": {...}}
+type ErrorResponse struct {
+	Error ErrorBody `json:"error"`
+}
+
+// writeError responde con el estado HTTP y el error en el sobre estándar
+func writeError(w http.ResponseWriter, r *http.Request, status int, code string, message string) {
+	writeErrorDetails(w, r, status, code, message, nil)
+}
+
+// writeErrorDetails es writeError con información adicional (por ejemplo, el campo inválido)
+func writeErrorDetails(w http.ResponseWriter, r *http.Request, status int, code string, message string, details interface{}) {
+	// Los errores del servidor se registran con el ID de la solicitud para poder rastrearlos
+	if status >= http.StatusInternalServerError {
+		log.Printf("[%s] %s %s: %d %s: %s", requestID(r), r.Method, r.URL.Path, status, code, message)
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(ErrorResponse{Error: ErrorBody{
+		Code:      code,
+		Message:   message,
+		Details:   details,
+		RequestID: requestID(r),
+	}})
+}
+
+// writeDecodeError responde 400 cuando el cuerpo de la solicitud no se puede decodificar
+func writeDecodeError(w http.ResponseWriter, r *http.Request, err error) {
+	writeErrorDetails(w, r, http.StatusBadRequest, CodeInvalidBody, "Error al decodificar el cuerpo de la solicitud", map[string]string{"reason": err.Error()})
+}
+
+// Identificador de la solicitud
+
+type requestIDKey struct{}
+
+// RequestIDHeader es el encabezado con el que se recibe y se devuelve el ID de la solicitud
+const RequestIDHeader = "X-Request-ID"
+
+// RequestID asigna un ID a cada solicitud (el que envía el cliente o uno nuevo), lo devuelve en
+// X-Request-ID y lo guarda en el contexto para incluirlo en los errores y en los logs.
+func RequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(RequestIDHeader)
+		if id == "" || len(id) > 64 {
+			id = uuid.New().String()
+		}
+		w.Header().Set(RequestIDHeader, id)
+		ctx := context.WithValue(r.Context(), requestIDKey{}, id)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// requestID devuelve el ID de la solicitud asignado por RequestID
+func requestID(r *http.Request) string {
+	id, _ := r.Context().Value(requestIDKey{}).(string)
+	return id
+}
+
+// NotFoundHandler responde a las rutas que no existen con el sobre de error
+func NotFoundHandler(w http.ResponseWriter, r *http.Request) {
+	writeError(w, r, http.StatusNotFound, CodeNotFound, "Ruta no encontrada")
+}
+
+// MethodNotAllowedHandler responde a los métodos no soportados con el sobre de error
+func MethodNotAllowedHandler(w http.ResponseWriter, r *http.Request) {
+	writeError(w, r, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "Método no permitido")
+}
@@ -5,46 +5,50 @@ import (
 	"log"
 	"net/http"
 
-	"github.com/danilsgit/gym-stats-backend/db"
 	"github.com/danilsgit/gym-stats-backend/models"
-	"github.com/gorilla/mux"
+	"github.com/danilsgit/gym-stats-backend/store"
 )
 
 // Ejercicios del usuario
 
 // Crear un ejercicio
-func CreateUserExerciseHandler(w http.ResponseWriter, r *http.Request) {
+func (s *Server) CreateUserExerciseHandler(w http.ResponseWriter, r *http.Request) {
 	// Obtener el ID del usuario de la solicitud
-	userID := r.Context().Value("userID")
-	if userID == nil {
-		http.Error(w, "No se encontró el ID del usuario en la solicitud", http.StatusInternalServerError)
+	userID, ok := currentUserID(r)
+	if !ok {
+		writeError(w, r, http.StatusInternalServerError, CodeInternal, "No se encontró el ID del usuario en la solicitud")
 		return
 	}
 
 	// Buscar el usuario por ID
-	var user models.User
-	if err := db.DB.Preload("Routines.Exercises.Sets").First(&user, "id = ?", userID).Error; err != nil {
-		http.Error(w, "Usuario no encontrado", http.StatusNotFound)
+	if _, err := s.Users.FindByID(userID); err != nil {
+		writeError(w, r, http.StatusNotFound, CodeUserNotFound, "Usuario no encontrado")
 		return
 	}
 
 	// Decodificar la solicitud en una estructura UpdateNameExerciseRequest
 	var req models.ExerciseRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Error al decodificar la solicitud", http.StatusBadRequest)
+		writeError(w, r, http.StatusBadRequest, CodeInvalidBody, "Error al decodificar la solicitud")
 		return
 	}
 
-	// Buscar la rutina por ID
-	idRoutine := req.IDRoutine
-	var routine models.Routine
-	if err := db.DB.First(&routine, "id = ?", idRoutine).Error; err != nil {
-		http.Error(w, "Rutina no encontrada", http.StatusNotFound)
+	// Buscar la rutina por ID y comprobar que pertenezca al usuario
+	routine, err := s.findOwnedRoutine(userID, req.IDRoutine)
+	if err != nil {
+		writeOwnershipError(w, r, err)
+		return
+	}
+
+	// Vincular el ejercicio con el catálogo
+	definitionID, err := s.resolveDefinition(req.DefinitionID, req.Name)
+	if err != nil {
+		writeDefinitionError(w, r, err)
 		return
 	}
 
 	// Crear un nuevo ejercicio
-	exercise := models.Exercise{Name: req.Name}
+	exercise := models.Exercise{Name: req.Name, DefinitionID: definitionID}
 	for _, setReq := range req.Sets {
 		set := models.Set{
 			Reps:   setReq.Reps,
@@ -55,15 +59,9 @@ func CreateUserExerciseHandler(w http.ResponseWriter, r *http.Request) {
 		exercise.Sets = append(exercise.Sets, set)
 	}
 
-	// Guardar el ejercicio en la base de datos
-	if err := db.DB.Create(&exercise).Error; err != nil {
-		http.Error(w, "Error al guardar el ejercicio", http.StatusInternalServerError)
-		return
-	}
-
-	// Asociar el ejercicio a la rutina
-	if err := db.DB.Model(&routine).Association("Exercises").Append(&exercise); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	// Guardar el ejercicio con sus sets y asociarlo a la rutina en una sola transacción
+	if err := s.Exercises.Create(routine.ID, &exercise); err != nil {
+		writeError(w, r, http.StatusInternalServerError, CodeInternal, "Error al guardar el ejercicio")
 		return
 	}
 
@@ -73,18 +71,17 @@ func CreateUserExerciseHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 // Actualizar un ejercicio
-func UpdateUserExerciseHandler(w http.ResponseWriter, r *http.Request) {
+func (s *Server) UpdateUserExerciseHandler(w http.ResponseWriter, r *http.Request) {
 	// Obtener el ID del usuario de la solicitud
-	userID := r.Context().Value("userID")
-	if userID == nil {
-		http.Error(w, "No se encontró el ID del usuario en la solicitud", http.StatusInternalServerError)
+	userID, ok := currentUserID(r)
+	if !ok {
+		writeError(w, r, http.StatusInternalServerError, CodeInternal, "No se encontró el ID del usuario en la solicitud")
 		return
 	}
 
 	// Buscar el usuario por ID
-	var user models.User
-	if err := db.DB.Preload("Routines.Exercises.Sets").First(&user, "id = ?", userID).Error; err != nil {
-		http.Error(w, "Usuario no encontrado", http.StatusNotFound)
+	if _, err := s.Users.FindByID(userID); err != nil {
+		writeError(w, r, http.StatusNotFound, CodeUserNotFound, "Usuario no encontrado")
 		return
 	}
 
@@ -92,61 +89,26 @@ func UpdateUserExerciseHandler(w http.ResponseWriter, r *http.Request) {
 	var req models.ExerciseRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		log.Println(err)
-		http.Error(w, "Error al decodificar la solicitud", http.StatusBadRequest)
+		writeError(w, r, http.StatusBadRequest, CodeInvalidBody, "Error al decodificar la solicitud")
 		return
 	}
 
-	// Buscar el ejercicio por ID
-	var exercise models.Exercise
-	if err := db.DB.First(&exercise, "id = ?", req.IDExercise).Error; err != nil {
-		http.Error(w, "Ejercicio no encontrado", http.StatusNotFound)
+	// Buscar el ejercicio por ID y comprobar que pertenezca al usuario
+	exercise, err := s.findOwnedExercise(userID, req.IDExercise)
+	if err != nil {
+		writeOwnershipError(w, r, err)
 		return
 	}
 
-	// Obtener todos los sets actuales del ejercicio
-	var currentSets []models.Set
-	if err := db.DB.Where("exercise_id = ?", exercise.ID).Find(&currentSets).Error; err != nil {
-		http.Error(w, "Error al obtener sets actuales", http.StatusInternalServerError)
-		return
-	}
-
-	// Crear un slice para almacenar los sets finales
-	var finalSets []models.Set
-
-	// Crear un mapa de sets actuales por ID
-	currentSetsMap := make(map[uint]models.Set)
-	for _, set := range currentSets {
-		currentSetsMap[set.ID] = set
-	}
-
-	// Procesar sets de la solicitud
+	// Actualizar, crear y eliminar los sets en una sola transacción: si algo falla no se guarda nada
+	sets := make([]store.SetInput, 0, len(req.Sets))
 	for _, setReq := range req.Sets {
-		if setReq.ID != 0 { // Si el set tiene un ID, actualizar
-			if set, ok := currentSetsMap[setReq.ID]; ok {
-				set.Reps = setReq.Reps
-				set.Weight = setReq.Weight
-				set.Rest = setReq.Rest
-				set.Note = setReq.Note
-				db.DB.Save(&set)
-				finalSets = append(finalSets, set) // Añadir al slice de sets finales
-				delete(currentSetsMap, set.ID)     // Eliminar de mapa para no considerarlo para eliminación
-			}
-		} else { // Si el set es nuevo, crear
-			newSet := models.Set{
-				ExerciseID: exercise.ID,
-				Reps:       setReq.Reps,
-				Weight:     setReq.Weight,
-				Rest:       setReq.Rest,
-				Note:       setReq.Note,
-			}
-			db.DB.Create(&newSet)
-			finalSets = append(finalSets, newSet) // Añadir al slice de sets finales
-		}
+		sets = append(sets, store.SetInput{ID: setReq.ID, Reps: setReq.Reps, Weight: setReq.Weight, Rest: setReq.Rest, Note: setReq.Note})
 	}
-
-	// Eliminar sets que no están en la solicitud
-	for id := range currentSetsMap {
-		db.DB.Delete(&models.Set{}, id)
+	finalSets, err := s.Exercises.ReplaceSets(exercise.ID, sets)
+	if err != nil {
+		writeError(w, r, http.StatusInternalServerError, CodeInternal, "Error al actualizar los sets")
+		return
 	}
 
 	// Devolver el arreglo de sets actualizado
@@ -155,39 +117,47 @@ func UpdateUserExerciseHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 // Cambiar el nombre del ejercicio
-func UpdateNameUserExerciseHandler(w http.ResponseWriter, r *http.Request) {
+func (s *Server) UpdateNameUserExerciseHandler(w http.ResponseWriter, r *http.Request) {
 	// Obtener el ID del usuario de la solicitud
-	userID := r.Context().Value("userID")
-	if userID == nil {
-		http.Error(w, "No se encontró el ID del usuario en la solicitud", http.StatusInternalServerError)
+	userID, ok := currentUserID(r)
+	if !ok {
+		writeError(w, r, http.StatusInternalServerError, CodeInternal, "No se encontró el ID del usuario en la solicitud")
 		return
 	}
 
 	// Buscar el usuario por ID
-	var user models.User
-	if err := db.DB.Preload("Routines.Exercises.Sets").First(&user, "id = ?", userID).Error; err != nil {
-		http.Error(w, "Usuario no encontrado", http.StatusNotFound)
+	if _, err := s.Users.FindByID(userID); err != nil {
+		writeError(w, r, http.StatusNotFound, CodeUserNotFound, "Usuario no encontrado")
 		return
 	}
 
 	// Decodificar la solicitud en una estructura UpdateNameExerciseRequest
 	var req models.UpdateNameExerciseRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Error al decodificar la solicitud", http.StatusBadRequest)
+		writeError(w, r, http.StatusBadRequest, CodeInvalidBody, "Error al decodificar la solicitud")
 		return
 	}
 
-	// Buscar el ejercicio por ID
-	var exercise models.Exercise
-	if err := db.DB.First(&exercise, "id = ?", req.ID).Error; err != nil {
-		http.Error(w, "Ejercicio no encontrado", http.StatusNotFound)
+	// Buscar el ejercicio por ID y comprobar que pertenezca al usuario
+	exercise, err := s.findOwnedExercise(userID, req.ID)
+	if err != nil {
+		writeOwnershipError(w, r, err)
 		return
 	}
 
 	// Cambiar el nombre del ejercicio
 	exercise.Name = req.Name
-	if err := db.DB.Save(&exercise).Error; err != nil {
-		http.Error(w, "Error al guardar el ejercicio", http.StatusInternalServerError)
+	// Si aún no está vinculado con el catálogo, intentar vincularlo con el nuevo nombre
+	if exercise.DefinitionID == nil {
+		definitionID, err := s.resolveDefinition(0, req.Name)
+		if err != nil {
+			writeDefinitionError(w, r, err)
+			return
+		}
+		exercise.DefinitionID = definitionID
+	}
+	if err := s.Exercises.Update(&exercise); err != nil {
+		writeError(w, r, http.StatusInternalServerError, CodeInternal, "Error al guardar el ejercicio")
 		return
 	}
 
@@ -197,43 +167,30 @@ func UpdateNameUserExerciseHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 // Eliminar un ejercicio
-func DeleteUserExerciseHandler(w http.ResponseWriter, r *http.Request) {
+func (s *Server) DeleteUserExerciseHandler(w http.ResponseWriter, r *http.Request) {
 	// Obtener el ID del usuario de la solicitud
-	userID := r.Context().Value("userID")
-	if userID == nil {
-		http.Error(w, "No se encontró el ID del usuario en la solicitud", http.StatusInternalServerError)
+	userID, ok := currentUserID(r)
+	if !ok {
+		writeError(w, r, http.StatusInternalServerError, CodeInternal, "No se encontró el ID del usuario en la solicitud")
 		return
 	}
 
 	// Buscar el usuario por ID
-	var user models.User
-	if err := db.DB.Preload("Routines.Exercises.Sets").First(&user, "id = ?", userID).Error; err != nil {
-		http.Error(w, "Usuario no encontrado", http.StatusNotFound)
+	if _, err := s.Users.FindByID(userID); err != nil {
+		writeError(w, r, http.StatusNotFound, CodeUserNotFound, "Usuario no encontrado")
 		return
 	}
 
-	// Obtener el id del ejercicio de los parámetros
-	params := mux.Vars(r)
-	exerciseId := params["id"]
-
-	// Buscar el ejercicio por ID
-	var exercise models.Exercise
-	if err := db.DB.First(&exercise, "id = ?", exerciseId).Error; err != nil {
-		http.Error(w, "Ejercicio no encontrado", http.StatusNotFound)
+	// Buscar el ejercicio por ID (de los parámetros) y comprobar que pertenezca al usuario
+	exercise, err := s.findOwnedExercise(userID, pathID(r, "id"))
+	if err != nil {
+		writeOwnershipError(w, r, err)
 		return
 	}
 
-	// Eliminar los sets del ejercicio
-	for _, set := range exercise.Sets {
-		if err := db.DB.Delete(&set).Error; err != nil {
-			http.Error(w, "Error al eliminar los sets", http.StatusInternalServerError)
-			return
-		}
-	}
-
-	// Eliminar el ejercicio
-	if err := db.DB.Delete(&exercise).Error; err != nil {
-		http.Error(w, "Error al eliminar el ejercicio", http.StatusInternalServerError)
+	// Eliminar los sets y el ejercicio en una sola transacción
+	if err := s.Exercises.Delete(exercise.ID); err != nil {
+		writeError(w, r, http.StatusInternalServerError, CodeInternal, "Error al eliminar el ejercicio")
 		return
 	}
 }
@@ -0,0 +1,101 @@
+package routes
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/danilsgit/gym-stats-backend/models"
+	"github.com/danilsgit/gym-stats-backend/store"
+)
+
+// ownershipError describe por qué un usuario no puede modificar un recurso
+type ownershipError struct {
+	Status  int
+	Code    string
+	Message string
+}
+
+func (e *ownershipError) Error() string {
+	return e.Message
+}
+
+var (
+	errRoutineNotFound   = &ownershipError{http.StatusNotFound, CodeRoutineNotFound, "Rutina no encontrada"}
+	errRoutineForbidden  = &ownershipError{http.StatusForbidden, CodeForbidden, "No tienes permiso para modificar esta rutina"}
+	errExerciseNotFound  = &ownershipError{http.StatusNotFound, CodeExerciseNotFound, "Ejercicio no encontrado"}
+	errExerciseForbidden = &ownershipError{http.StatusForbidden, CodeForbidden, "No tienes permiso para modificar este ejercicio"}
+	errWorkoutNotFound   = &ownershipError{http.StatusNotFound, CodeWorkoutNotFound, "Sesión de entrenamiento no encontrada"}
+	errWorkoutForbidden  = &ownershipError{http.StatusForbidden, CodeForbidden, "No tienes permiso para acceder a esta sesión de entrenamiento"}
+)
+
+// writeOwnershipError responde con el estado asociado al error de propiedad
+func writeOwnershipError(w http.ResponseWriter, r *http.Request, err error) {
+	var ownErr *ownershipError
+	if errors.As(err, &ownErr) {
+		writeError(w, r, ownErr.Status, ownErr.Code, ownErr.Message)
+		return
+	}
+	writeError(w, r, http.StatusInternalServerError, CodeInternal, "Error al comprobar la propiedad del recurso")
+}
+
+// findOwnedRoutine busca la rutina (con sus ejercicios y sets) y comprueba que pertenezca al usuario.
+// Devuelve 404 si la rutina no existe y 403 si existe pero es de otro usuario.
+func (s *Server) findOwnedRoutine(userID string, routineID uint) (models.Routine, error) {
+	routine, err := s.Routines.FindByID(routineID)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return routine, errRoutineNotFound
+		}
+		return routine, err
+	}
+
+	owner, err := s.Routines.IsOwner(userID, routine.ID)
+	if err != nil {
+		return routine, err
+	}
+	if !owner {
+		return routine, errRoutineForbidden
+	}
+
+	return routine, nil
+}
+
+// findOwnedExercise busca el ejercicio (con sus sets) y comprueba que pertenezca a alguna
+// rutina del usuario.
+func (s *Server) findOwnedExercise(userID string, exerciseID uint) (models.Exercise, error) {
+	exercise, err := s.Exercises.FindByID(exerciseID)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return exercise, errExerciseNotFound
+		}
+		return exercise, err
+	}
+
+	owner, err := s.Exercises.IsOwner(userID, exercise.ID)
+	if err != nil {
+		return exercise, err
+	}
+	if !owner {
+		return exercise, errExerciseForbidden
+	}
+
+	return exercise, nil
+}
+
+// findOwnedWorkout busca la sesión de entrenamiento (con sus sets y récords) y comprueba que sea
+// del usuario
+func (s *Server) findOwnedWorkout(userID string, workoutID uint) (models.WorkoutSession, error) {
+	workout, err := s.Workouts.FindByID(workoutID)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return workout, errWorkoutNotFound
+		}
+		return workout, err
+	}
+
+	if workout.UserID != userID {
+		return workout, errWorkoutForbidden
+	}
+
+	return workout, nil
+}
@@ -0,0 +1,178 @@
+package routes
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/danilsgit/gym-stats-backend/models"
+)
+
+// missingID es un ID que nunca existe en los stores de las pruebas
+const missingID = 999
+
+// ownershipIDs son los recursos sobre los que se prueba cada ruta
+type ownershipIDs struct {
+	Routine  uint
+	Exercise uint
+	Set      uint
+	Workout  uint
+}
+
+// exerciseRequest arma el cuerpo de POST /users/routines/exercises y PUT /users/routines/exercises/sets
+func exerciseRequest(routineID uint, exerciseID uint, setID uint) models.ExerciseRequest {
+	req := models.ExerciseRequest{IDRoutine: routineID, IDExercise: exerciseID, Name: "Peso muerto"}
+	req.Sets = append(req.Sets, struct {
+		ID     uint    `json:"id_set"`
+		Reps   int     `json:"reps"`
+		Weight float64 `json:"weight"`
+		Rest   float64 `json:"rest"`
+		Note   string  `json:"note"`
+	}{ID: setID, Reps: 1, Weight: 1})
+	return req
+}
+
+// ownershipRoutes son todas las rutas que leen o modifican recursos del usuario
+var ownershipRoutes = []struct {
+	name   string
+	method string
+	path   func(ids ownershipIDs) string
+	body   func(ids ownershipIDs) interface{}
+}{
+	{"cambiar nombre de rutina", "PUT",
+		func(ids ownershipIDs) string { return "/users/routines/name" },
+		func(ids ownershipIDs) interface{} {
+			return models.UpdateNameRoutineRequest{ID: ids.Routine, Name: "Robada"}
+		}},
+	{"cambiar descripción de rutina", "PUT",
+		func(ids ownershipIDs) string { return "/users/routines/description" },
+		func(ids ownershipIDs) interface{} {
+			return models.UpdateDescriptionRoutineRequest{ID: ids.Routine, Description: "Robada"}
+		}},
+	{"publicar rutina", "PUT",
+		func(ids ownershipIDs) string { return "/users/routines/public" },
+		func(ids ownershipIDs) interface{} {
+			return models.UpdatePublicRoutineRequest{ID: ids.Routine, Public: true}
+		}},
+	{"eliminar rutina", "DELETE",
+		func(ids ownershipIDs) string { return fmt.Sprintf("/users/routines/%d", ids.Routine) },
+		nil},
+	{"agregar ejercicio", "POST",
+		func(ids ownershipIDs) string { return "/users/routines/exercises" },
+		func(ids ownershipIDs) interface{} { return exerciseRequest(ids.Routine, 0, 0) }},
+	{"cambiar nombre de ejercicio", "PUT",
+		func(ids ownershipIDs) string { return "/users/routines/exercises/name" },
+		func(ids ownershipIDs) interface{} {
+			return models.UpdateNameExerciseRequest{ID: ids.Exercise, Name: "Robado"}
+		}},
+	{"reemplazar sets", "PUT",
+		func(ids ownershipIDs) string { return "/users/routines/exercises/sets" },
+		func(ids ownershipIDs) interface{} { return exerciseRequest(0, ids.Exercise, ids.Set) }},
+	{"eliminar ejercicio", "DELETE",
+		func(ids ownershipIDs) string { return fmt.Sprintf("/users/routines/exercises/%d", ids.Exercise) },
+		nil},
+	{"ver sesión", "GET",
+		func(ids ownershipIDs) string { return fmt.Sprintf("/users/workouts/%d", ids.Workout) },
+		nil},
+	{"registrar set", "POST",
+		func(ids ownershipIDs) string { return fmt.Sprintf("/users/workouts/%d/sets", ids.Workout) },
+		func(ids ownershipIDs) interface{} {
+			return models.LogWorkoutSetRequest{ExerciseID: ids.Exercise, Reps: 1, Weight: 1}
+		}},
+	{"finalizar sesión", "PUT",
+		func(ids ownershipIDs) string { return fmt.Sprintf("/users/workouts/%d/finish", ids.Workout) },
+		func(ids ownershipIDs) interface{} { return models.FinishWorkoutRequest{} }},
+	{"eliminar sesión", "DELETE",
+		func(ids ownershipIDs) string { return fmt.Sprintf("/users/workouts/%d", ids.Workout) },
+		nil},
+}
+
+func TestOwnershipForeignAndMissingResources(t *testing.T) {
+	api := newTestAPI(t)
+	owner, _ := api.createUser("ana")
+	_, intruderToken := api.createUser("beto")
+
+	routine := api.createRoutine(owner.ID, "Piernas", false)
+	workout := models.WorkoutSession{UserID: owner.ID, RoutineID: routine.ID, RoutineName: routine.Name}
+	if err := api.memory.Workouts.Create(&workout); err != nil {
+		t.Fatal(err)
+	}
+	foreign := ownershipIDs{Routine: routine.ID, Exercise: routine.Exercises[0].ID, Set: routine.Exercises[0].Sets[0].ID, Workout: workout.ID}
+	missing := ownershipIDs{Routine: missingID, Exercise: missingID, Set: missingID, Workout: missingID}
+
+	for _, route := range ownershipRoutes {
+		t.Run(route.name, func(t *testing.T) {
+			var body interface{}
+			if route.body != nil {
+				body = route.body(foreign)
+			}
+			expectStatus(t, api.do(route.method, route.path(foreign), intruderToken, body), http.StatusForbidden)
+
+			if route.body != nil {
+				body = route.body(missing)
+			}
+			expectStatus(t, api.do(route.method, route.path(missing), intruderToken, body), http.StatusNotFound)
+		})
+	}
+
+	// Nada de lo de ana cambió
+	stored, err := api.memory.Routines.FindByID(routine.ID)
+	if err != nil {
+		t.Fatalf("la rutina de ana ya no existe: %v", err)
+	}
+	if stored.Name != "Piernas" || stored.Description != "Rutina de prueba" || stored.Public {
+		t.Fatalf("la rutina de ana cambió: %+v", stored)
+	}
+	if len(stored.Exercises) != 1 || stored.Exercises[0].Name != "Sentadilla" || len(stored.Exercises[0].Sets) != 2 {
+		t.Fatalf("los ejercicios de ana cambiaron: %+v", stored.Exercises)
+	}
+	storedWorkout, err := api.memory.Workouts.FindByID(workout.ID)
+	if err != nil {
+		t.Fatalf("la sesión de ana ya no existe: %v", err)
+	}
+	if storedWorkout.FinishedAt != nil || len(storedWorkout.Sets) != 0 {
+		t.Fatalf("la sesión de ana cambió: %+v", storedWorkout)
+	}
+}
+
+// Un set de otro usuario no se puede tomar enviándolo en los sets de un ejercicio propio
+func TestReplaceSetsIgnoresForeignSet(t *testing.T) {
+	api := newTestAPI(t)
+	owner, _ := api.createUser("ana")
+	intruder, intruderToken := api.createUser("beto")
+	routine := api.createRoutine(owner.ID, "Piernas", false)
+	own := api.createRoutine(intruder.ID, "Propia", false)
+	foreignSet := routine.Exercises[0].Sets[0]
+
+	rec := api.do("PUT", "/users/routines/exercises/sets", intruderToken, exerciseRequest(0, own.Exercises[0].ID, foreignSet.ID))
+	expectStatus(t, rec, http.StatusOK)
+
+	exercise, err := api.memory.Exercises.FindByID(routine.Exercises[0].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(exercise.Sets) != 2 || exercise.Sets[0].Reps != foreignSet.Reps || exercise.Sets[0].Weight != foreignSet.Weight {
+		t.Fatalf("el set de ana cambió: %+v", exercise.Sets)
+	}
+}
+
+// Se puede entrenar una rutina pública de otro usuario, pero no una privada ni una oculta
+func TestStartWorkoutRoutineOwnership(t *testing.T) {
+	api := newTestAPI(t)
+	owner, ownerToken := api.createUser("ana")
+	_, token := api.createUser("beto")
+	private := api.createRoutine(owner.ID, "Privada", false)
+	public := api.createRoutine(owner.ID, "Pública", true)
+
+	expectStatus(t, api.do("POST", "/users/workouts", token, models.StartWorkoutRequest{RoutineID: private.ID}), http.StatusForbidden)
+	expectStatus(t, api.do("POST", "/users/workouts", token, models.StartWorkoutRequest{RoutineID: missingID}), http.StatusNotFound)
+	expectStatus(t, api.do("POST", "/users/workouts", token, models.StartWorkoutRequest{RoutineID: public.ID}), http.StatusCreated)
+
+	// Una rutina pública oculta por moderación no se puede entrenar, salvo su autor
+	hidden := api.createRoutine(owner.ID, "Oculta", true)
+	if err := api.memory.Routines.UpdateHidden(hidden.ID, true); err != nil {
+		t.Fatal(err)
+	}
+	expectStatus(t, api.do("POST", "/users/workouts", token, models.StartWorkoutRequest{RoutineID: hidden.ID}), http.StatusNotFound)
+	expectStatus(t, api.do("POST", "/users/workouts", ownerToken, models.StartWorkoutRequest{RoutineID: hidden.ID}), http.StatusCreated)
+}
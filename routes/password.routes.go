@@ -0,0 +1,155 @@
+package routes
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/danilsgit/gym-stats-backend/mailer"
+	"github.com/danilsgit/gym-stats-backend/models"
+	"github.com/danilsgit/gym-stats-backend/store"
+)
+
+// Restablecer la contraseña
+
+// passwordResetTTL es lo que dura el enlace para restablecer la contraseña
+const passwordResetTTL = time.Hour
+
+// Pedir un correo para restablecer la contraseña. La respuesta es la misma exista o no la
+// cuenta, para no revelar qué correos están registrados.
+func (s *Server) ForgotPasswordHandler(w http.ResponseWriter, r *http.Request) {
+	var req models.ForgotPasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, r, http.StatusBadRequest, CodeInvalidBody, "Error al decodificar el cuerpo de la solicitud")
+		return
+	}
+	email := strings.TrimSpace(req.Email)
+	if email == "" {
+		writeError(w, r, http.StatusBadRequest, CodeValidationFailed, "El campo email es obligatorio")
+		return
+	}
+
+	if !s.allowEmail(w, r, email, models.TokenPasswordReset) {
+		return
+	}
+
+	user, err := s.Users.FindByEmail(email)
+	switch {
+	case err == nil:
+		if err := s.sendPasswordReset(user); err != nil {
+			log.Println("Error enviando el correo para restablecer la contraseña:", err)
+		}
+	case !errors.Is(err, store.ErrNotFound):
+		writeError(w, r, http.StatusInternalServerError, CodeInternal, "Error al buscar el usuario")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode("Si el correo está registrado, te enviamos un enlace para restablecer la contraseña")
+}
+
+// sendPasswordReset invalida los enlaces anteriores del usuario y le envía uno nuevo
+func (s *Server) sendPasswordReset(user models.User) error {
+	token, err := newRandomToken()
+	if err != nil {
+		return err
+	}
+	if err := s.Tokens.InvalidateUserTokens(user.ID, models.TokenPasswordReset); err != nil {
+		return err
+	}
+	if err := s.Tokens.CreateUserToken(&models.UserToken{
+		UserID:    user.ID,
+		Purpose:   models.TokenPasswordReset,
+		TokenHash: hashToken(token),
+		ExpiresAt: time.Now().Add(passwordResetTTL),
+	}); err != nil {
+		return err
+	}
+
+	return s.Mailer.Send(mailer.Message{
+		To:      user.Email,
+		Subject: "Restablecer contraseña",
+		Body: "Hola " + user.Username + ",\n\n" +
+			"Para restablecer tu contraseña usa este enlace (válido por una hora):\n\n" +
+			appLink("/reset-password", token) + "\n\n" +
+			"Si no lo pediste, ignora este correo.",
+	})
+}
+
+// Restablecer la contraseña con el token recibido por correo. El token es de un solo uso y
+// al cambiar la contraseña se cierran todas las sesiones del usuario.
+func (s *Server) ResetPasswordHandler(w http.ResponseWriter, r *http.Request) {
+	var req models.ResetPasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, r, http.StatusBadRequest, CodeInvalidBody, "Error al decodificar el cuerpo de la solicitud")
+		return
+	}
+	if req.Token == "" || req.Password == "" {
+		writeError(w, r, http.StatusBadRequest, CodeValidationFailed, "Los campos token y password son obligatorios")
+		return
+	}
+
+	token, err := s.Tokens.ConsumeUserToken(models.TokenPasswordReset, hashToken(req.Token))
+	if errors.Is(err, store.ErrNotFound) {
+		writeError(w, r, http.StatusBadRequest, CodeInvalidLink, "El enlace no es válido o ya expiró")
+		return
+	}
+	if err != nil {
+		writeError(w, r, http.StatusInternalServerError, CodeInternal, "Error al comprobar el enlace")
+		return
+	}
+
+	hashedPassword, err := models.HashPassword(req.Password)
+	if err != nil {
+		writeError(w, r, http.StatusInternalServerError, CodeInternal, "Error al guardar la contraseña")
+		return
+	}
+	if err := s.Users.UpdatePassword(token.UserID, hashedPassword); err != nil {
+		writeError(w, r, http.StatusInternalServerError, CodeInternal, "Error al guardar la contraseña")
+		return
+	}
+	if err := s.Tokens.RevokeUser(token.UserID); err != nil {
+		writeError(w, r, http.StatusInternalServerError, CodeInternal, "Error al cerrar las sesiones")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode("Contraseña actualizada con éxito")
+}
+
+// allowEmail registra el pedido de un correo con ese propósito para la dirección. Si ya se
+// pidieron EmailLimit en EmailWindow escribe el error y devuelve false. Cada propósito tiene su
+// propio límite.
+func (s *Server) allowEmail(w http.ResponseWriter, r *http.Request, email string, purpose string) bool {
+	if s.EmailLimit <= 0 {
+		return true
+	}
+	email = strings.ToLower(email)
+	count, err := s.Tokens.CountEmailRequests(email, purpose, time.Now().Add(-s.EmailWindow))
+	if err != nil {
+		writeError(w, r, http.StatusInternalServerError, CodeInternal, "Error al comprobar el límite de correos")
+		return false
+	}
+	if count >= int64(s.EmailLimit) {
+		writeError(w, r, http.StatusTooManyRequests, CodeRateLimited, "Demasiadas solicitudes para este correo. Intenta más tarde")
+		return false
+	}
+	if err := s.Tokens.CreateEmailRequest(&models.EmailRequest{Email: email, Purpose: purpose}); err != nil {
+		writeError(w, r, http.StatusInternalServerError, CodeInternal, "Error al comprobar el límite de correos")
+		return false
+	}
+	return true
+}
+
+// appLink arma el enlace del frontend (APP_URL) con el token; sin APP_URL se envía solo el token
+func appLink(path string, token string) string {
+	appURL := strings.TrimRight(os.Getenv("APP_URL"), "/")
+	if appURL == "" {
+		return token
+	}
+	return appURL + path + "?token=" + token
+}
@@ -0,0 +1,96 @@
+package routes
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/danilsgit/gym-stats-backend/models"
+)
+
+// El enlace para restablecer la contraseña es de un solo uso y cierra las sesiones abiertas
+func TestPasswordReset(t *testing.T) {
+	api := newTestAPI(t)
+	mail := api.recordMail()
+	user, token := api.createUser("ana")
+
+	expectStatus(t, api.do("POST", "/auth/password/forgot", "", models.ForgotPasswordRequest{Email: user.Email}), http.StatusOK)
+	link := mail.lastToken(t, user.Email)
+
+	// Un enlace de restablecer la contraseña no verifica el correo
+	expectError(t, api.do("POST", "/auth/email/verify", "", models.VerifyEmailRequest{Token: link}), http.StatusBadRequest, CodeInvalidLink)
+
+	expectStatus(t, api.do("POST", "/auth/password/reset", "", models.ResetPasswordRequest{Token: link, Password: "nueva-clave"}), http.StatusOK)
+	expectError(t, api.do("POST", "/auth/password/reset", "", models.ResetPasswordRequest{Token: link, Password: "otra-clave"}), http.StatusBadRequest, CodeInvalidLink)
+
+	expectError(t, api.do("GET", "/users/routines", token, nil), http.StatusForbidden, CodeSessionRevoked)
+	expectError(t, api.do("POST", "/login", "", models.LoginRequest{Email: user.Email, Password: "secreto123"}), http.StatusUnauthorized, CodeInvalidCredentials)
+	expectStatus(t, api.do("POST", "/login", "", models.LoginRequest{Email: user.Email, Password: "nueva-clave"}), http.StatusOK)
+}
+
+// Pedir un enlace nuevo invalida el anterior
+func TestPasswordResetOnlyLatestLink(t *testing.T) {
+	api := newTestAPI(t)
+	mail := api.recordMail()
+	user, _ := api.createUser("ana")
+
+	expectStatus(t, api.do("POST", "/auth/password/forgot", "", models.ForgotPasswordRequest{Email: user.Email}), http.StatusOK)
+	first := mail.lastToken(t, user.Email)
+	expectStatus(t, api.do("POST", "/auth/password/forgot", "", models.ForgotPasswordRequest{Email: user.Email}), http.StatusOK)
+	second := mail.lastToken(t, user.Email)
+
+	expectError(t, api.do("POST", "/auth/password/reset", "", models.ResetPasswordRequest{Token: first, Password: "nueva-clave"}), http.StatusBadRequest, CodeInvalidLink)
+	expectStatus(t, api.do("POST", "/auth/password/reset", "", models.ResetPasswordRequest{Token: second, Password: "nueva-clave"}), http.StatusOK)
+}
+
+func TestPasswordResetExpiredLink(t *testing.T) {
+	api := newTestAPI(t)
+	user, _ := api.createUser("ana")
+	if err := api.memory.Tokens.CreateUserToken(&models.UserToken{
+		UserID:    user.ID,
+		Purpose:   models.TokenPasswordReset,
+		TokenHash: hashToken("enlace-vencido"),
+		ExpiresAt: time.Now().Add(-time.Minute),
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	expectError(t, api.do("POST", "/auth/password/reset", "", models.ResetPasswordRequest{Token: "enlace-vencido", Password: "nueva-clave"}), http.StatusBadRequest, CodeInvalidLink)
+	expectStatus(t, api.do("POST", "/login", "", models.LoginRequest{Email: user.Email, Password: "secreto123"}), http.StatusOK)
+}
+
+// La respuesta es la misma exista o no la cuenta
+func TestForgotPasswordUnknownEmail(t *testing.T) {
+	api := newTestAPI(t)
+	mail := api.recordMail()
+
+	expectStatus(t, api.do("POST", "/auth/password/forgot", "", models.ForgotPasswordRequest{Email: "nadie@example.com"}), http.StatusOK)
+	if sent := mail.sent("nadie@example.com"); sent != 0 {
+		t.Fatalf("se enviaron %d correos a una dirección sin cuenta", sent)
+	}
+	expectError(t, api.do("POST", "/auth/password/forgot", "", models.ForgotPasswordRequest{}), http.StatusBadRequest, CodeValidationFailed)
+}
+
+// El límite es por dirección (sin distinguir mayúsculas), también para las que no tienen cuenta,
+// y no se comparte con el reenvío de la verificación
+func TestForgotPasswordRateLimit(t *testing.T) {
+	api := newTestAPI(t)
+	mail := api.recordMail()
+	user, token := api.createUser("ana")
+
+	for i := 0; i < api.server.EmailLimit; i++ {
+		expectStatus(t, api.do("POST", "/auth/password/forgot", "", models.ForgotPasswordRequest{Email: user.Email}), http.StatusOK)
+	}
+	expectError(t, api.do("POST", "/auth/password/forgot", "", models.ForgotPasswordRequest{Email: strings.ToUpper(user.Email)}), http.StatusTooManyRequests, CodeRateLimited)
+	if sent := mail.sent(user.Email); sent != api.server.EmailLimit {
+		t.Fatalf("se enviaron %d correos, se esperaban %d", sent, api.server.EmailLimit)
+	}
+
+	for i := 0; i < api.server.EmailLimit; i++ {
+		expectStatus(t, api.do("POST", "/auth/password/forgot", "", models.ForgotPasswordRequest{Email: "nadie@example.com"}), http.StatusOK)
+	}
+	expectError(t, api.do("POST", "/auth/password/forgot", "", models.ForgotPasswordRequest{Email: "nadie@example.com"}), http.StatusTooManyRequests, CodeRateLimited)
+
+	expectStatus(t, api.do("POST", "/auth/email/resend", token, nil), http.StatusOK)
+}
@@ -0,0 +1,52 @@
+package routes
+
+import (
+	"net/http"
+
+	"github.com/danilsgit/gym-stats-backend/models"
+)
+
+// Permisos que se pueden exigir con RequirePermission
+const (
+	PermListUsers        = "users:list"
+	PermDisableUsers     = "users:disable"
+	PermUnpublishRoutine = "routines:unpublish"
+	PermHideRoutine      = "routines:hide"
+	PermReviewReports    = "reports:review"
+	PermModerationLog    = "moderation:log"
+)
+
+// rolePermissions define qué puede hacer cada rol además de lo que puede cualquier usuario
+var rolePermissions = map[string][]string{
+	models.RoleAdmin: {PermListUsers, PermDisableUsers, PermUnpublishRoutine, PermHideRoutine, PermReviewReports, PermModerationLog},
+	models.RoleCoach: {PermUnpublishRoutine, PermHideRoutine, PermReviewReports},
+	models.RoleUser:  {},
+}
+
+// hasPermission indica si el rol tiene el permiso
+func hasPermission(role string, permission string) bool {
+	for _, granted := range rolePermissions[role] {
+		if granted == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// currentUserRole devuelve el rol del usuario autenticado que JwtAuthentication guarda en el contexto
+func currentUserRole(r *http.Request) string {
+	role, _ := r.Context().Value("role").(string)
+	return role
+}
+
+// RequirePermission responde 403 si el rol del usuario autenticado no tiene el permiso.
+// Se usa dentro de JwtAuthentication, que guarda el rol en el contexto.
+func (s *Server) RequirePermission(permission string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !hasPermission(currentUserRole(r), permission) {
+			writeError(w, r, http.StatusForbidden, CodeForbidden, "No tienes permiso para realizar esta acción")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
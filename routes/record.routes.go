@@ -0,0 +1,44 @@
+package routes
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/danilsgit/gym-stats-backend/records"
+)
+
+// Récords personales del usuario
+
+// Listar los récords vigentes del usuario (o el historial completo con ?history=true)
+func (s *Server) GetUserRecordsHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := currentUserID(r)
+	if !ok {
+		writeError(w, r, http.StatusInternalServerError, CodeInternal, "No se encontró el ID del usuario en la solicitud")
+		return
+	}
+
+	// Filtrar por ejercicio si se envía
+	var exerciseId uint64
+	if exerciseIdStr := r.URL.Query().Get("exerciseId"); exerciseIdStr != "" {
+		var err error
+		exerciseId, err = strconv.ParseUint(exerciseIdStr, 10, 64)
+		if err != nil {
+			writeError(w, r, http.StatusBadRequest, CodeInvalidParameter, "El parámetro exerciseId no es válido")
+			return
+		}
+	}
+
+	history, err := s.Workouts.ListRecords(userID, uint(exerciseId))
+	if err != nil {
+		writeError(w, r, http.StatusInternalServerError, CodeInternal, "Error al obtener los récords")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	if r.URL.Query().Get("history") == "true" {
+		json.NewEncoder(w).Encode(history)
+		return
+	}
+	json.NewEncoder(w).Encode(records.Current(history))
+}
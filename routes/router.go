@@ -0,0 +1,84 @@
+package routes
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+// Router registra todas las rutas de la API con sus middlewares. El CORS se agrega en main.
+func (s *Server) Router() *mux.Router {
+	r := mux.NewRouter()
+	r.Use(RequestID)
+	r.NotFoundHandler = RequestID(http.HandlerFunc(NotFoundHandler))
+	r.MethodNotAllowedHandler = RequestID(http.HandlerFunc(MethodNotAllowedHandler))
+
+	r.HandleFunc("/", HomeHandler)
+
+	// Usuario y autenticación
+	r.HandleFunc("/users", s.PostUserHandler).Methods("POST")
+	r.HandleFunc("/login", s.LoginHandler).Methods("POST")
+	r.HandleFunc("/login/social", s.LoginSocialHandler).Methods("POST")
+	r.HandleFunc("/auth/refresh", s.RefreshTokenHandler).Methods("POST")
+	r.HandleFunc("/auth/logout", s.LogoutHandler).Methods("POST")
+	r.HandleFunc("/auth/password/forgot", s.ForgotPasswordHandler).Methods("POST")
+	r.HandleFunc("/auth/password/reset", s.ResetPasswordHandler).Methods("POST")
+	r.HandleFunc("/auth/email/verify", s.VerifyEmailHandler).Methods("POST")
+	r.Handle("/auth/email/resend", s.JwtAuthentication(http.HandlerFunc(s.ResendVerificationHandler))).Methods("POST")
+	// Calculadora de 1RM estimado
+	r.HandleFunc("/e1rm", E1RMHandler).Methods("GET")
+	// Rutinas generales
+	r.HandleFunc("/routines", s.GetRoutinesHandler).Methods("GET")
+	r.Handle("/routines/copy", s.JwtAuthentication(http.HandlerFunc(s.CopyRoutineHandler))).Methods("POST")
+	r.HandleFunc("/routines/{id}", s.GetRoutineHandler).Methods("GET")
+	r.HandleFunc("/routines/{id}/forks", s.GetRoutineForksHandler).Methods("GET")
+	r.Handle("/routines/{id}/copy", s.JwtAuthentication(http.HandlerFunc(s.CopyRoutineByIdHandler))).Methods("POST")
+	r.Handle("/routines/{id}/like", s.JwtAuthentication(http.HandlerFunc(s.LikeRoutineHandler))).Methods("PUT")
+	r.Handle("/routines/{id}/like", s.JwtAuthentication(http.HandlerFunc(s.UnlikeRoutineHandler))).Methods("DELETE")
+	r.Handle("/routines/{id}/reports", s.JwtAuthentication(http.HandlerFunc(s.ReportRoutineHandler))).Methods("POST")
+	// Rutinas del usuario
+	// Se registra antes de /users/routines/{userId} para que "page" no se tome como un ID
+	r.Handle("/users/routines/page", s.JwtAuthentication(http.HandlerFunc(s.GetUserRoutinesPageHandler))).Methods("GET")
+	// Sin autenticación
+	r.HandleFunc("/users/routines/{userId}", s.GetRoutineByUserIdHandler).Methods("GET")
+	// Con autenticación
+	r.Handle("/users/routines", s.JwtAuthentication(http.HandlerFunc(s.GetUserRoutinesHandler))).Methods("GET")
+	r.Handle("/users/routines", s.JwtAuthentication(http.HandlerFunc(s.CreateUserRoutineHandler))).Methods("POST")
+	r.Handle("/users/routines/name", s.JwtAuthentication(http.HandlerFunc(s.UpdateNameUserRoutineHandler))).Methods("PUT")
+	r.Handle("/users/routines/description", s.JwtAuthentication(http.HandlerFunc(s.UpdateDescriptionUserRoutineHandler))).Methods("PUT")
+	r.Handle("/users/routines/public", s.JwtAuthentication(http.HandlerFunc(s.UpdatePublicUserRoutineHandler))).Methods("PUT")
+	r.Handle("/users/routines/{id}", s.JwtAuthentication(http.HandlerFunc(s.DeleteUserRoutineHandler))).Methods("DELETE")
+	// Ejercicios del usuario
+	r.Handle("/users/routines/exercises/name", s.JwtAuthentication(http.HandlerFunc(s.UpdateNameUserExerciseHandler))).Methods("PUT")
+	r.Handle("/users/routines/exercises", s.JwtAuthentication(http.HandlerFunc(s.CreateUserExerciseHandler))).Methods("POST")
+	r.Handle("/users/routines/exercises/{id}", s.JwtAuthentication(http.HandlerFunc(s.DeleteUserExerciseHandler))).Methods("DELETE")
+	r.Handle("/users/routines/exercises/sets", s.JwtAuthentication(http.HandlerFunc(s.UpdateUserExerciseHandler))).Methods("PUT")
+	// Sesiones de entrenamiento del usuario
+	r.Handle("/users/workouts", s.JwtAuthentication(http.HandlerFunc(s.GetUserWorkoutsHandler))).Methods("GET")
+	r.Handle("/users/workouts", s.JwtAuthentication(http.HandlerFunc(s.StartWorkoutHandler))).Methods("POST")
+	r.Handle("/users/workouts/{id}", s.JwtAuthentication(http.HandlerFunc(s.GetUserWorkoutHandler))).Methods("GET")
+	r.Handle("/users/workouts/{id}", s.JwtAuthentication(http.HandlerFunc(s.DeleteUserWorkoutHandler))).Methods("DELETE")
+	r.Handle("/users/workouts/{id}/sets", s.JwtAuthentication(http.HandlerFunc(s.LogWorkoutSetHandler))).Methods("POST")
+	r.Handle("/users/workouts/{id}/finish", s.JwtAuthentication(http.HandlerFunc(s.FinishWorkoutHandler))).Methods("PUT")
+	// Récords personales del usuario
+	r.Handle("/users/records", s.JwtAuthentication(http.HandlerFunc(s.GetUserRecordsHandler))).Methods("GET")
+	// Estadísticas de entrenamiento
+	r.Handle("/users/stats/volume", s.JwtAuthentication(http.HandlerFunc(s.GetVolumeStatsHandler))).Methods("GET")
+	r.Handle("/users/stats/exercises/{id}/progress", s.JwtAuthentication(http.HandlerFunc(s.GetExerciseProgressHandler))).Methods("GET")
+	// Catálogo global de ejercicios
+	r.HandleFunc("/exercises/catalog", s.GetCatalogHandler).Methods("GET")
+	r.HandleFunc("/exercises/catalog/{id}", s.GetCatalogDefinitionHandler).Methods("GET")
+	// Configuración del usuario
+	r.Handle("/users/config/username", s.JwtAuthentication(http.HandlerFunc(s.PutUserInUsernameHandler))).Methods("PUT")
+	r.Handle("/users/config/password", s.JwtAuthentication(http.HandlerFunc(s.PutUserPasswordHandler))).Methods("PUT")
+	// Administración (según el rol del usuario)
+	r.Handle("/admin/users", s.JwtAuthentication(s.RequirePermission(PermListUsers, http.HandlerFunc(s.AdminListUsersHandler)))).Methods("GET")
+	r.Handle("/admin/users/{id}/disabled", s.JwtAuthentication(s.RequirePermission(PermDisableUsers, http.HandlerFunc(s.AdminDisableUserHandler)))).Methods("PUT")
+	r.Handle("/admin/routines/{id}/unpublish", s.JwtAuthentication(s.RequirePermission(PermUnpublishRoutine, http.HandlerFunc(s.AdminUnpublishRoutineHandler)))).Methods("PUT")
+	r.Handle("/admin/routines/{id}/hidden", s.JwtAuthentication(s.RequirePermission(PermHideRoutine, http.HandlerFunc(s.AdminHideRoutineHandler)))).Methods("PUT")
+	r.Handle("/admin/reports", s.JwtAuthentication(s.RequirePermission(PermReviewReports, http.HandlerFunc(s.AdminListReportsHandler)))).Methods("GET")
+	r.Handle("/admin/reports/{id}", s.JwtAuthentication(s.RequirePermission(PermReviewReports, http.HandlerFunc(s.AdminResolveReportHandler)))).Methods("PUT")
+	r.Handle("/admin/moderation/log", s.JwtAuthentication(s.RequirePermission(PermModerationLog, http.HandlerFunc(s.AdminModerationLogHandler)))).Methods("GET")
+
+	return r
+}
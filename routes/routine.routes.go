@@ -2,158 +2,153 @@ package routes
 
 import (
 	"encoding/json"
+	"errors"
 	"math"
-	"math/rand"
 	"net/http"
+	"net/url"
 	"strconv"
+	"strings"
 	"time"
 
-	"github.com/danilsgit/gym-stats-backend/db"
 	"github.com/danilsgit/gym-stats-backend/models"
-	"github.com/google/uuid"
+	"github.com/danilsgit/gym-stats-backend/store"
 	"github.com/gorilla/mux"
 )
 
-func GetRoutinesHandler(w http.ResponseWriter, r *http.Request) {
-	// Leer los parámetros de la solicitud
-	offsetStr := r.URL.Query().Get("offset")
-	limitStr := r.URL.Query().Get("limit")
-	search := r.URL.Query().Get("search")
-
-	// Convertir los parámetros de la solicitud a enteros
-	offset, err := strconv.Atoi(offsetStr)
+func (s *Server) GetRoutinesHandler(w http.ResponseWriter, r *http.Request) {
+	// Leer los parámetros de la solicitud (10 rutinas por página si no se indica el límite)
+	query, err := routineListQuery(r, 10)
 	if err != nil {
-		offset = 0 // Valor predeterminado si hay un error o no se proporciona
+		writeError(w, r, http.StatusBadRequest, CodeInvalidParameter, err.Error())
+		return
 	}
+	query.PublicOnly = true
 
-	limit, err := strconv.Atoi(limitStr)
-	if err != nil || limit <= 0 {
-		limit = 10 // Valor predeterminado si hay un error o no se proporciona
+	// Obtener las rutinas públicas que coincidan con el search junto con la cantidad total
+	page, err := s.Routines.List(query)
+	if err != nil {
+		writeRoutineListError(w, r, err)
+		return
 	}
 
-	// Obtener todas las rutinas de la base de datos junto con sus ejercicios y sets y el primer User asociado.
-	// Coincidiendo el search con el nombre de la rutina, la descripción de la rutina o el nombre del ejercicio o el nombre del usuario
-	var routines []models.Routine
-	if err := db.DB.
-		Preload("Exercises.Sets").
-		Preload("Users").
-		Joins("JOIN routine_work_exercise rwe ON rwe.routine_id = routines.id").
-		Joins("JOIN exercises e ON e.id = rwe.exercise_id").
-		Joins("JOIN user_make_routine umr ON umr.routine_id = routines.id").
-		Joins("JOIN users u ON u.id = umr.user_id").
-		Where("public = ? and (routines.name ILIKE ? OR routines.description ILIKE ? OR e.name ILIKE ? OR u.username ILIKE ?)", true, "%"+search+"%", "%"+search+"%", "%"+search+"%", "%"+search+"%").
-		Group("routines.id").
-		Limit(limit).
-		Offset(offset).
-		Find(&routines).Error; err != nil {
-		http.Error(w, "Error al obtener las rutinas", http.StatusInternalServerError)
-		return
-	}
-
-	// Contar la cantidad total de rutinas en la base de datos con el mismo filtro
-	var total int64
-	if err := db.DB.
-		Table("routines").
-		Select("routines.id").
-		Joins("JOIN routine_work_exercise rwe ON rwe.routine_id = routines.id").
-		Joins("JOIN exercises e ON e.id = rwe.exercise_id").
-		Joins("JOIN user_make_routine umr ON umr.routine_id = routines.id").
-		Joins("JOIN users u ON u.id = umr.user_id").
-		Where("public = ? and (routines.name ILIKE ? OR e.name ILIKE ? OR u.username ILIKE ?)", true, "%"+search+"%", "%"+search+"%", "%"+search+"%").
-		Group("routines.id").
-		Count(&total).Error; err != nil {
-		http.Error(w, "Error al contar las rutinas", http.StatusInternalServerError)
-	}
-
-	pages := int64(math.Ceil(float64(total) / float64(limit)))
-
-	// Construir la respuesta con las rutinas y la cantidad de rutinas
-	var result = map[string]interface{}{}
-	result["routines"] = routines
-	result["pages"] = pages
+	// Construir la respuesta con las rutinas, el total y el cursor de la página siguiente.
+	// pages se mantiene para los clientes que aún paginan por offset.
+	result := routinePageResult(page)
+	result["pages"] = int64(math.Ceil(float64(page.Total) / float64(query.Limit)))
+
+	// Los conteos para los filtros solo se calculan en la primera página; no cambian al avanzar
+	if query.Cursor == nil && query.Offset == 0 {
+		facets, err := s.Routines.Facets(query)
+		if err != nil {
+			writeError(w, r, http.StatusInternalServerError, CodeInternal, "Error al contar los filtros de las rutinas")
+			return
+		}
+		result["facets"] = facets
+	}
 
 	json.NewEncoder(w).Encode(result) // Responder con el objeto construido
 }
 
-func CopyRoutineHandler(w http.ResponseWriter, r *http.Request) {
-	userID := r.Context().Value("userID")
-	if userID == nil {
-		http.Error(w, "No se encontró el ID del usuario en la solicitud", http.StatusInternalServerError)
+// Copiar una rutina con la solicitud anterior a POST /routines/{id}/copy, que se mantiene para
+// los clientes existentes. Esos clientes envían la rutina completa con su autor (userId) y su
+// nombre, que es único para cada autor: con ellos se busca la rutina guardada, o se usa el ID si
+// el cliente lo envía. La copia se hace igual que en CopyRoutineByIdHandler, sin tomar los
+// ejercicios ni los sets que envía el cliente.
+func (s *Server) CopyRoutineHandler(w http.ResponseWriter, r *http.Request) {
+	var req models.CopyRoutineRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeDecodeError(w, r, err)
 		return
 	}
 
-	// Buscar el usuario por ID
-	var user models.User
-	if err := db.DB.Preload("Routines.Exercises.Sets").First(&user, "id = ?", userID).Error; err != nil {
-		http.Error(w, "Usuario no encontrado", http.StatusNotFound)
-		return
+	routineID := req.ID
+	if routineID == 0 {
+		if req.UserId == "" || req.Name == "" {
+			writeError(w, r, http.StatusBadRequest, CodeValidationFailed, "Falta el ID de la rutina a copiar, o su autor y su nombre")
+			return
+		}
+		id, err := s.Routines.FindIDByName(req.UserId, req.Name)
+		if errors.Is(err, store.ErrNotFound) {
+			writeError(w, r, http.StatusNotFound, CodeRoutineNotFound, "Rutina no encontrada")
+			return
+		}
+		if err != nil {
+			writeError(w, r, http.StatusInternalServerError, CodeInternal, "Error al buscar la rutina")
+			return
+		}
+		routineID = id
 	}
 
-	// Decodificar la solicitud en un CopyRoutineRequest
-	var req models.CopyRoutineRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "COD400"+err.Error(), http.StatusBadRequest)
+	r = mux.SetURLVars(r, map[string]string{"id": strconv.FormatUint(uint64(routineID), 10)})
+	s.CopyRoutineByIdHandler(w, r)
+}
+
+// Copiar una rutina pública (o propia) por ID. La copia se construye desde la base de datos
+// y no desde lo que envía el cliente, y guarda la rutina original como linaje.
+func (s *Server) CopyRoutineByIdHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := currentUserID(r)
+	if !ok {
+		writeError(w, r, http.StatusInternalServerError, CodeInternal, "No se encontró el ID del usuario en la solicitud")
 		return
 	}
 
-	// Verificar si la rutina ya es mia
-	if userID == req.UserId {
-		http.Error(w, "No puedes copiar tu propia rutina", http.StatusBadRequest)
+	// Buscar el usuario por ID
+	user, err := s.Users.FindByID(userID)
+	if err != nil {
+		writeError(w, r, http.StatusNotFound, CodeUserNotFound, "Usuario no encontrado")
 		return
 	}
 
-	// Crear la rutina y sus relaciones con ejercicios y sets
-	routine := models.Routine{Name: req.Name, Description: req.Description}
-	// Recorrer los ejercicios de la solicitud y crearlos
-	for _, exReq := range req.ExerciseRequest {
-		exercise := models.Exercise{Name: exReq.Name}
-		for _, setReq := range exReq.Sets {
-			set := models.Set{Reps: setReq.Reps, Weight: setReq.Weight, Rest: setReq.Rest, Note: setReq.Note}
-			exercise.Sets = append(exercise.Sets, set)
+	// Buscar la rutina original con sus ejercicios y sets; debe ser pública o del usuario
+	source, err := s.findOwnedRoutine(userID, pathID(r, "id"))
+	if err == errRoutineForbidden {
+		// Las rutinas ocultas por moderación no existen para los demás usuarios
+		if source.Hidden {
+			writeError(w, r, http.StatusNotFound, CodeRoutineNotFound, "Rutina no encontrada")
+			return
 		}
-		routine.Exercises = append(routine.Exercises, exercise)
+		if !source.Public {
+			writeError(w, r, http.StatusForbidden, CodeForbidden, "No puedes copiar una rutina privada")
+			return
+		}
+		err = nil
+	}
+	if err != nil {
+		writeOwnershipError(w, r, err)
+		return
 	}
 
-	// Asignarle nombre copia a la rutina
-	routine.Name = routine.Name + " (Copia)"
-
-	// Comprobar que el nombre sea único
-	// si no lo es asignarle un nuevo nombre con los últimos 4 dígitos random hasta que sea único
-	var existingRoutine models.Routine
-	if err := db.DB.Unscoped().First(&existingRoutine, "name = ?", routine.Name).Error; err == nil {
-		unique := false
-		count := 0
-		for !unique {
-			routine.Name = routine.Name + generateFourRandomDigits()
-			var stillExistingRoutine models.Routine
-			if err := db.DB.Unscoped().First(&stillExistingRoutine, "name = ?", routine.Name).Error; err != nil {
-				unique = true
-			}
-			count = count + 1
-			if count > 1000 {
-				http.Error(w, "Error: Intenta no es posible copiar esta rutina", http.StatusBadRequest)
-				return
-			}
+	// Copiar la rutina, sus ejercicios y sus sets
+	routine := models.Routine{
+		Description:  source.Description,
+		OwnerID:      user.ID,
+		ForkedFromID: &source.ID,
+	}
+	for _, sourceExercise := range source.Exercises {
+		exercise := models.Exercise{Name: sourceExercise.Name, DefinitionID: sourceExercise.DefinitionID}
+		for _, sourceSet := range sourceExercise.Sets {
+			set := models.Set{Reps: sourceSet.Reps, Weight: sourceSet.Weight, Rest: sourceSet.Rest, Note: sourceSet.Note}
+			exercise.Sets = append(exercise.Sets, set)
 		}
+		routine.Exercises = append(routine.Exercises, exercise)
 	}
 
-	// Guardar la rutina en la base de datos
-	if err := db.DB.Create(&routine).Error; err != nil {
-		http.Error(w, "ERROR AL CREAR LA RUTINA"+err.Error(), http.StatusInternalServerError)
+	// Asignarle nombre copia a la rutina, único entre las rutinas del usuario
+	name, err := s.copyRoutineName(user.ID, source.Name)
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, CodeValidationFailed, "Error: no es posible copiar esta rutina")
 		return
 	}
+	routine.Name = name
 
-	// Poner la rutina como public = false
-	routine.Public = false
-	// Actualizar en la bd
-	if err := db.DB.Save(&routine).Error; err != nil {
-		http.Error(w, "ERR"+err.Error(), http.StatusInternalServerError)
+	// Guardar la copia (las copias son privadas) y asociarla al usuario
+	err = s.Routines.Create(&routine, user.ID)
+	if errors.Is(err, store.ErrDuplicate) {
+		writeError(w, r, http.StatusConflict, CodeRoutineNameTaken, "Ya tienes una rutina con ese nombre")
 		return
 	}
-
-	// Asociar la rutina al usuario
-	if err := db.DB.Model(&user).Association("Routines").Append(&routine); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	if err != nil {
+		writeError(w, r, http.StatusInternalServerError, CodeInternal, "Error al copiar la rutina")
 		return
 	}
 
@@ -161,20 +156,37 @@ func CopyRoutineHandler(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(routine)
 }
 
-func GetRoutineHandler(w http.ResponseWriter, r *http.Request) {
-	params := mux.Vars(r)
-	routineId := params["id"]
+func (s *Server) GetRoutineHandler(w http.ResponseWriter, r *http.Request) {
+	routine, err := s.Routines.FindByID(pathID(r, "id"))
+	// Las rutinas ocultas por moderación no se muestran
+	if err != nil || routine.Hidden {
+		writeError(w, r, http.StatusNotFound, CodeRoutineNotFound, "Rutina no encontrada")
+		return
+	}
 
-	var routine models.Routine
-	if err := db.DB.Preload("Exercises.Sets").First(&routine, "id = ?", routineId).Error; err != nil {
-		http.Error(w, "Rutina no encontrada", http.StatusNotFound)
+	// Encontrar el usuario asociado a la rutina (solo su perfil público)
+	var user models.PublicUser
+	if len(routine.Users) > 0 {
+		user = routine.Users[0].Public()
+	}
+
+	// Agregar el 1RM estimado a los sets si se solicita
+	if err := applyE1RM(r, routine.Exercises); err != nil {
+		writeError(w, r, http.StatusBadRequest, CodeInvalidParameter, err.Error())
+		return
+	}
+
+	// Contar las copias de la rutina
+	forkCount, err := s.Routines.CountForks(routine.ID)
+	if err != nil {
+		writeError(w, r, http.StatusInternalServerError, CodeInternal, "Error al contar las copias de la rutina")
 		return
 	}
 
-	// Encontrar el usuario asociado a la rutina
-	var user models.User
-	if err := db.DB.Model(&routine).Association("Users").Find(&user); err != nil {
-		http.Error(w, "Usuario no encontrado", http.StatusNotFound)
+	// Contar los me gusta de la rutina
+	likeCount, err := s.Routines.CountLikes(routine.ID)
+	if err != nil {
+		writeError(w, r, http.StatusInternalServerError, CodeInternal, "Error al contar los me gusta de la rutina")
 		return
 	}
 
@@ -185,62 +197,165 @@ func GetRoutineHandler(w http.ResponseWriter, r *http.Request) {
 	result["name"] = routine.Name
 	result["description"] = routine.Description
 	result["exercises"] = routine.Exercises
+	result["forkedFromId"] = routine.ForkedFromID
+	result["forkCount"] = forkCount
+	result["likeCount"] = likeCount
+
+	// Información de la rutina original para poder volver a ella (si sigue existiendo y es pública)
+	if routine.ForkedFromID != nil {
+		if original, err := s.Routines.FindByID(*routine.ForkedFromID); err == nil && original.Public && !original.Hidden {
+			result["forkedFrom"] = map[string]interface{}{"id": original.ID, "name": original.Name}
+		}
+	}
 
 	// Devolver la rutina
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(result)
 }
 
-func GetRoutineByUserIdHandler(w http.ResponseWriter, r *http.Request) {
-	params := mux.Vars(r)
-	userId := params["userId"]
+// Listar las copias públicas de una rutina junto con el total de copias (públicas y privadas)
+func (s *Server) GetRoutineForksHandler(w http.ResponseWriter, r *http.Request) {
+	offset, err := strconv.Atoi(r.URL.Query().Get("offset"))
+	if err != nil {
+		offset = 0 // Valor predeterminado si hay un error o no se proporciona
+	}
+	limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+	if err != nil || limit <= 0 {
+		limit = 10 // Valor predeterminado si hay un error o no se proporciona
+	}
 
-	var user models.User
-	if err := db.DB.Preload("Routines.Exercises.Sets").First(&user, "id = ?", userId).Error; err != nil {
-		http.Error(w, "Usuario no encontrado", http.StatusNotFound)
+	routine, err := s.Routines.FindByID(pathID(r, "id"))
+	if err != nil || routine.Hidden {
+		writeError(w, r, http.StatusNotFound, CodeRoutineNotFound, "Rutina no encontrada")
+		return
+	}
+
+	forkCount, err := s.Routines.CountForks(routine.ID)
+	if err != nil {
+		writeError(w, r, http.StatusInternalServerError, CodeInternal, "Error al contar las copias de la rutina")
+		return
+	}
+
+	// Solo se listan las copias públicas; las privadas cuentan en el total pero no se exponen
+	forks, err := s.Routines.ListForks(routine.ID, limit, offset)
+	if err != nil {
+		writeError(w, r, http.StatusInternalServerError, CodeInternal, "Error al obtener las copias de la rutina")
 		return
 	}
 
 	var result = map[string]interface{}{}
-	result["user"] = user
-	result["routines"] = user.Routines
+	result["forkCount"] = forkCount
+	result["forks"] = forks
 
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(result)
 }
 
-// Rutinas del usuario
-func GetUserRoutinesHandler(w http.ResponseWriter, r *http.Request) {
-	userID := r.Context().Value("userID")
-	if userID == nil {
-		http.Error(w, "No se encontró el ID del usuario en la solicitud", http.StatusInternalServerError)
+func (s *Server) GetRoutineByUserIdHandler(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	userId := params["userId"]
+
+	user, err := s.Users.FindByID(userId)
+	if err != nil {
+		writeError(w, r, http.StatusNotFound, CodeUserNotFound, "Usuario no encontrado")
 		return
 	}
 
-	// Buscar el usuario por ID y obtener sus rutinas
-	var user models.User
-	if err := db.DB.Preload("Routines.Exercises.Sets").First(&user, "id = ?", userID).Error; err != nil {
-		http.Error(w, "Usuario no encontrado", http.StatusNotFound)
+	// Sin limit se devuelven todas las rutinas del perfil
+	query, err := routineListQuery(r, 0)
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, CodeInvalidParameter, err.Error())
 		return
 	}
+	// Las rutinas ocultas por moderación no se muestran en el perfil
+	query.UserID = user.ID
+	query.VisibleOnly = true
+	page, err := s.Routines.List(query)
+	if err != nil {
+		writeRoutineListError(w, r, err)
+		return
+	}
+
+	result := routinePageResult(page)
+	result["user"] = user.Public()
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(result)
+}
+
+// Rutinas del usuario. La respuesta es el arreglo de rutinas, como antes de la paginación por
+// cursor; los parámetros de búsqueda, orden y límite se aplican igual que en el listado paginado.
+func (s *Server) GetUserRoutinesHandler(w http.ResponseWriter, r *http.Request) {
+	page, ok := s.userRoutinePage(w, r)
+	if !ok {
+		return
+	}
+
+	routines := page.Routines
+	if routines == nil {
+		routines = []models.Routine{}
+	}
 
 	// Devolver las rutinas del usuario como respuesta
 	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(user.Routines)
+	json.NewEncoder(w).Encode(routines)
+}
+
+// Rutinas del usuario paginadas: las rutinas, el total con el mismo filtro y el cursor de la
+// página siguiente
+func (s *Server) GetUserRoutinesPageHandler(w http.ResponseWriter, r *http.Request) {
+	page, ok := s.userRoutinePage(w, r)
+	if !ok {
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(routinePageResult(page))
 }
 
-func CreateUserRoutineHandler(w http.ResponseWriter, r *http.Request) {
+// userRoutinePage busca las rutinas del usuario autenticado con los parámetros de la solicitud
+// (todas si no se indica el límite). Si falla, escribe el error y devuelve false.
+func (s *Server) userRoutinePage(w http.ResponseWriter, r *http.Request) (store.RoutinePage, bool) {
+	userID, ok := currentUserID(r)
+	if !ok {
+		writeError(w, r, http.StatusInternalServerError, CodeInternal, "No se encontró el ID del usuario en la solicitud")
+		return store.RoutinePage{}, false
+	}
+
+	query, err := routineListQuery(r, 0)
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, CodeInvalidParameter, err.Error())
+		return store.RoutinePage{}, false
+	}
+	query.UserID = userID
+	page, err := s.Routines.List(query)
+	if err != nil {
+		writeRoutineListError(w, r, err)
+		return store.RoutinePage{}, false
+	}
+
+	// Agregar el 1RM estimado a los sets si se solicita
+	for i := range page.Routines {
+		if err := applyE1RM(r, page.Routines[i].Exercises); err != nil {
+			writeError(w, r, http.StatusBadRequest, CodeInvalidParameter, err.Error())
+			return store.RoutinePage{}, false
+		}
+	}
+	return page, true
+}
+
+func (s *Server) CreateUserRoutineHandler(w http.ResponseWriter, r *http.Request) {
 	// Obtener el ID del usuario de la solicitud
-	userID := r.Context().Value("userID")
-	if userID == nil {
-		http.Error(w, "No se encontró el ID del usuario en la solicitud", http.StatusInternalServerError)
+	userID, ok := currentUserID(r)
+	if !ok {
+		writeError(w, r, http.StatusInternalServerError, CodeInternal, "No se encontró el ID del usuario en la solicitud")
 		return
 	}
 
 	// Buscar el usuario por ID
-	var user models.User
-	if err := db.DB.First(&user, "id = ?", userID).Error; err != nil {
-		http.Error(w, "Usuario no encontrado", http.StatusNotFound)
+	user, err := s.Users.FindByID(userID)
+	if err != nil {
+		writeError(w, r, http.StatusNotFound, CodeUserNotFound, "Usuario no encontrado")
 		return
 	}
 
@@ -248,17 +363,29 @@ func CreateUserRoutineHandler(w http.ResponseWriter, r *http.Request) {
 	var req models.RoutineRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		// Mensaje de error si la solicitud no se puede decodificar
-		http.Error(w, "COD400"+err.Error(), http.StatusBadRequest)
+		writeDecodeError(w, r, err)
+		return
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		writeError(w, r, http.StatusBadRequest, CodeValidationFailed, "El nombre de la rutina es obligatorio")
 		return
 	}
 
 	// Crear la rutina y sus relaciones con ejercicios y sets
-	routine := models.Routine{Name: req.Name, Description: req.Description}
+	routine := models.Routine{Name: req.Name, Description: req.Description, Public: true}
 	// Recorrer los ejercicios de la solicitud y crearlos
 	// for _ significa que no nos importa el índice, solo el valor
 	// Se crea exReq en cada iteración
 	for _, exReq := range req.ExerciseRequest {
 		exercise := models.Exercise{Name: exReq.Name}
+		// Vincular el ejercicio con el catálogo
+		definitionID, err := s.resolveDefinition(exReq.DefinitionID, exReq.Name)
+		if err != nil {
+			writeDefinitionError(w, r, err)
+			return
+		}
+		exercise.DefinitionID = definitionID
 		for _, setReq := range exReq.Sets {
 			set := models.Set{Reps: setReq.Reps, Weight: setReq.Weight, Rest: setReq.Rest, Note: setReq.Note}
 			// El append agrega un elemento al final de un slice
@@ -267,35 +394,32 @@ func CreateUserRoutineHandler(w http.ResponseWriter, r *http.Request) {
 		routine.Exercises = append(routine.Exercises, exercise)
 	}
 
-	// Comprobar que el nombre sea único
-	// si no lo es asignarle un nuevo nombre con los últimos 4 dígitos random hasta que sea único
-	var existingRoutine models.Routine
-	if err := db.DB.Unscoped().First(&existingRoutine, "name = ?", routine.Name).Error; err == nil {
-		unique := false
-		count := 0
-		for !unique {
-			routine.Name = routine.Name + generateFourRandomDigits()
-			var stillExistingRoutine models.Routine
-			if err := db.DB.Unscoped().First(&stillExistingRoutine, "name = ?", routine.Name).Error; err != nil {
-				unique = true
-			}
-			count = count + 1
-			if count > 1000 {
-				http.Error(w, "Error: Intenta cambiar el nombre de la rutina", http.StatusBadRequest)
-				return
-			}
-		}
+	// Comprobar que el usuario no tenga otra rutina con el mismo nombre
+	taken, err := s.Routines.NameTaken(user.ID, routine.Name, 0)
+	if err != nil {
+		writeError(w, r, http.StatusInternalServerError, CodeInternal, "Error al comprobar el nombre de la rutina")
+		return
 	}
-
-	// Guardar la rutina en la base de datos
-	if err := db.DB.Create(&routine).Error; err != nil {
-		http.Error(w, "ERROR AL CREAR LA RUTINA"+err.Error(), http.StatusInternalServerError)
+	if taken {
+		writeError(w, r, http.StatusConflict, CodeRoutineNameTaken, "Ya tienes una rutina con ese nombre")
 		return
 	}
+	routine.OwnerID = user.ID
 
-	// Asociar la rutina al usuario
-	if err := db.DB.Model(&user).Association("Routines").Append(&routine); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	// Sin el correo verificado (si la política lo exige) la rutina se crea como privada
+	if !s.canPublish(user) {
+		routine.Public = false
+	}
+
+	// Guardar la rutina con sus ejercicios y sets y asociarla al usuario. El nombre se comprobó
+	// antes, pero otra solicitud simultánea pudo usarlo; el índice único lo rechaza.
+	err = s.Routines.Create(&routine, user.ID)
+	if errors.Is(err, store.ErrDuplicate) {
+		writeError(w, r, http.StatusConflict, CodeRoutineNameTaken, "Ya tienes una rutina con ese nombre")
+		return
+	}
+	if err != nil {
+		writeError(w, r, http.StatusInternalServerError, CodeInternal, "Error al crear la rutina")
 		return
 	}
 
@@ -303,58 +427,59 @@ func CreateUserRoutineHandler(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(routine)
 }
 
-func UpdateNameUserRoutineHandler(w http.ResponseWriter, r *http.Request) {
-	userID := r.Context().Value("userID")
-	if userID == nil {
-		http.Error(w, "No se encontró el ID del usuario en la solicitud", http.StatusInternalServerError)
+func (s *Server) UpdateNameUserRoutineHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := currentUserID(r)
+	if !ok {
+		writeError(w, r, http.StatusInternalServerError, CodeInternal, "No se encontró el ID del usuario en la solicitud")
 		return
 	}
 
 	// Buscar el usuario por ID
-	var user models.User
-	if err := db.DB.Preload("Routines.Exercises.Sets").First(&user, "id = ?", userID).Error; err != nil {
-		http.Error(w, "Usuario no encontrado", http.StatusNotFound)
+	user, err := s.Users.FindByID(userID)
+	if err != nil {
+		writeError(w, r, http.StatusNotFound, CodeUserNotFound, "Usuario no encontrado")
 		return
 	}
 
 	// Decodificar la solicitud en un UpdateNameRoutineRequest
 	var req models.UpdateNameRoutineRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "COD400"+err.Error(), http.StatusBadRequest)
-		return
-	}
-
-	// Buscar la rutina por ID
-	var routine models.Routine
-	if err := db.DB.First(&routine, "id = ?", req.ID).Error; err != nil {
-		http.Error(w, "Rutina no encontrada", http.StatusNotFound)
-		return
-	}
-
-	// Comprobar que el nombre sea único
-	// si no lo es asignarle un nuevo nombre con los últimos 4 dígitos random hasta que sea único
-	var existingRoutine models.Routine
-	if err := db.DB.First(&existingRoutine, "name = ?", req.Name).Error; err == nil {
-		unique := false
-		count := 0
-		for !unique {
-			req.Name = req.Name + generateFourRandomDigits()
-			var stillExistingRoutine models.Routine
-			if err := db.DB.First(&stillExistingRoutine, "name = ?", req.Name).Error; err != nil {
-				unique = true
-			}
-			count = count + 1
-			if count > 1000 {
-				http.Error(w, "Error: Intenta cambiar el nombre de la rutina", http.StatusBadRequest)
-				return
-			}
-		}
+		writeDecodeError(w, r, err)
+		return
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		writeError(w, r, http.StatusBadRequest, CodeValidationFailed, "El nombre de la rutina es obligatorio")
+		return
+	}
+
+	// Buscar la rutina por ID y comprobar que pertenezca al usuario
+	routine, err := s.findOwnedRoutine(userID, req.ID)
+	if err != nil {
+		writeOwnershipError(w, r, err)
+		return
+	}
+
+	// Comprobar que el usuario no tenga otra rutina con el mismo nombre
+	taken, err := s.Routines.NameTaken(user.ID, req.Name, routine.ID)
+	if err != nil {
+		writeError(w, r, http.StatusInternalServerError, CodeInternal, "Error al comprobar el nombre de la rutina")
+		return
+	}
+	if taken {
+		writeError(w, r, http.StatusConflict, CodeRoutineNameTaken, "Ya tienes una rutina con ese nombre")
+		return
 	}
 
 	// Actualizar el nombre de la rutina
 	routine.Name = req.Name
-	if err := db.DB.Save(&routine).Error; err != nil {
-		http.Error(w, "ERR"+err.Error(), http.StatusInternalServerError)
+	err = s.Routines.UpdateName(routine.ID, routine.Name)
+	if errors.Is(err, store.ErrDuplicate) {
+		writeError(w, r, http.StatusConflict, CodeRoutineNameTaken, "Ya tienes una rutina con ese nombre")
+		return
+	}
+	if err != nil {
+		writeError(w, r, http.StatusInternalServerError, CodeInternal, "Error al actualizar la rutina")
 		return
 	}
 
@@ -362,38 +487,37 @@ func UpdateNameUserRoutineHandler(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(routine)
 }
 
-func UpdateDescriptionUserRoutineHandler(w http.ResponseWriter, r *http.Request) {
-	userID := r.Context().Value("userID")
-	if userID == nil {
-		http.Error(w, "No se encontró el ID del usuario en la solicitud", http.StatusInternalServerError)
+func (s *Server) UpdateDescriptionUserRoutineHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := currentUserID(r)
+	if !ok {
+		writeError(w, r, http.StatusInternalServerError, CodeInternal, "No se encontró el ID del usuario en la solicitud")
 		return
 	}
 
 	// Buscar el usuario por ID
-	var user models.User
-	if err := db.DB.Preload("Routines.Exercises.Sets").First(&user, "id = ?", userID).Error; err != nil {
-		http.Error(w, "Usuario no encontrado", http.StatusNotFound)
+	if _, err := s.Users.FindByID(userID); err != nil {
+		writeError(w, r, http.StatusNotFound, CodeUserNotFound, "Usuario no encontrado")
 		return
 	}
 
 	// Decodificar la solicitud en un UpdateNameRoutineRequest
 	var req models.UpdateDescriptionRoutineRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "COD400"+err.Error(), http.StatusBadRequest)
+		writeDecodeError(w, r, err)
 		return
 	}
 
-	// Buscar la rutina por ID
-	var routine models.Routine
-	if err := db.DB.First(&routine, "id = ?", req.ID).Error; err != nil {
-		http.Error(w, "Rutina no encontrada", http.StatusNotFound)
+	// Buscar la rutina por ID y comprobar que pertenezca al usuario
+	routine, err := s.findOwnedRoutine(userID, req.ID)
+	if err != nil {
+		writeOwnershipError(w, r, err)
 		return
 	}
 
 	// Actualizar la descripción de la rutina
 	routine.Description = req.Description
-	if err := db.DB.Save(&routine).Error; err != nil {
-		http.Error(w, "ERR"+err.Error(), http.StatusInternalServerError)
+	if err := s.Routines.UpdateDescription(routine.ID, routine.Description); err != nil {
+		writeError(w, r, http.StatusInternalServerError, CodeInternal, "Error al actualizar la rutina")
 		return
 	}
 
@@ -401,75 +525,360 @@ func UpdateDescriptionUserRoutineHandler(w http.ResponseWriter, r *http.Request)
 	json.NewEncoder(w).Encode(routine)
 }
 
-func DeleteUserRoutineHandler(w http.ResponseWriter, r *http.Request) {
+// Publicar u ocultar una rutina del usuario
+func (s *Server) UpdatePublicUserRoutineHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := currentUserID(r)
+	if !ok {
+		writeError(w, r, http.StatusInternalServerError, CodeInternal, "No se encontró el ID del usuario en la solicitud")
+		return
+	}
+
+	// Buscar el usuario por ID
+	user, err := s.Users.FindByID(userID)
+	if err != nil {
+		writeError(w, r, http.StatusNotFound, CodeUserNotFound, "Usuario no encontrado")
+		return
+	}
+
+	// Decodificar la solicitud en un UpdatePublicRoutineRequest
+	var req models.UpdatePublicRoutineRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeDecodeError(w, r, err)
+		return
+	}
+
+	// Buscar la rutina por ID y comprobar que pertenezca al usuario
+	routine, err := s.findOwnedRoutine(userID, req.ID)
+	if err != nil {
+		writeOwnershipError(w, r, err)
+		return
+	}
+
+	if req.Public && !s.canPublish(user) {
+		writeError(w, r, http.StatusForbidden, CodeEmailNotVerified, "Verifica tu correo para publicar rutinas")
+		return
+	}
+
+	// Actualizar la visibilidad de la rutina
+	routine.Public = req.Public
+	if err := s.Routines.UpdatePublic(routine.ID, routine.Public); err != nil {
+		writeError(w, r, http.StatusInternalServerError, CodeInternal, "Error al actualizar la rutina")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(routine)
+}
+
+func (s *Server) DeleteUserRoutineHandler(w http.ResponseWriter, r *http.Request) {
 	// Obtener el ID del usuario de la solicitud
-	userID := r.Context().Value("userID")
-	if userID == nil {
-		http.Error(w, "No se encontró el ID del usuario en la solicitud", http.StatusInternalServerError)
+	userID, ok := currentUserID(r)
+	if !ok {
+		writeError(w, r, http.StatusInternalServerError, CodeInternal, "No se encontró el ID del usuario en la solicitud")
 		return
 	}
 
-	// Obtener el id de la rutina de los parámetros de la solicitud
-	params := mux.Vars(r)
-	routineId := params["id"]
+	// Buscar la rutina por ID y comprobar que pertenezca al usuario
+	routine, err := s.findOwnedRoutine(userID, pathID(r, "id"))
+	if err != nil {
+		writeOwnershipError(w, r, err)
+		return
+	}
 
-	// Buscar la rutina por ID
-	var routine models.Routine
-	if err := db.DB.Preload("Exercises.Sets").First(&routine, "id = ?", routineId).Error; err != nil {
-		http.Error(w, "Rutina no encontrada", http.StatusNotFound)
+	// Eliminar cada Exercise con sus Sets y la rutina en una sola transacción
+	if err := s.Routines.Delete(routine.ID); err != nil {
+		writeError(w, r, http.StatusInternalServerError, CodeInternal, "Error al eliminar la rutina")
 		return
 	}
 
-	// Antes de eliminar la rutina, cambiarle el nombre para que no sea igual a otro
-	// Con un uuid de la librería
-	uuidToAdd := uuid.NewString()
-	routine.Name = routine.Name + uuidToAdd
+	w.WriteHeader(http.StatusOK)
+}
 
-	// Guardar la rutina con el nuevo nombre
-	if err := db.DB.Save(&routine).Error; err != nil {
-		http.Error(w, "Error al eliminar con nombre uuid", http.StatusInternalServerError)
+// Reportar una rutina pública de otro usuario para que la revise un moderador
+func (s *Server) ReportRoutineHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := currentUserID(r)
+	if !ok {
+		writeError(w, r, http.StatusInternalServerError, CodeInternal, "No se encontró el ID del usuario en la solicitud")
 		return
 	}
 
-	// Eliminar cada Exercise y sus Sets asociados
-	for _, exercise := range routine.Exercises {
-		for _, set := range exercise.Sets {
-			if err := db.DB.Delete(&set).Error; err != nil {
-				http.Error(w, "Error al eliminar los sets", http.StatusInternalServerError)
-				return
-			}
-		}
-		if err := db.DB.Delete(&exercise).Error; err != nil {
-			http.Error(w, "Error al eliminar los ejercicios", http.StatusInternalServerError)
+	var req models.ReportRoutineRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, r, http.StatusBadRequest, CodeInvalidBody, "Error al decodificar el cuerpo de la solicitud")
+		return
+	}
+	if !validReportReason(req.Reason) {
+		writeError(w, r, http.StatusBadRequest, CodeValidationFailed, "Motivo de reporte inválido")
+		return
+	}
+	if len(req.Comment) > 1000 {
+		writeError(w, r, http.StatusBadRequest, CodeValidationFailed, "El comentario no puede superar los 1000 caracteres")
+		return
+	}
+
+	// Solo se pueden reportar rutinas públicas y visibles
+	routine, err := s.Routines.FindByID(pathID(r, "id"))
+	if err != nil || !routine.Public || routine.Hidden {
+		writeError(w, r, http.StatusNotFound, CodeRoutineNotFound, "Rutina no encontrada")
+		return
+	}
+	owner, err := s.Routines.IsOwner(userID, routine.ID)
+	if err != nil {
+		writeError(w, r, http.StatusInternalServerError, CodeInternal, "Error al reportar la rutina")
+		return
+	}
+	if owner {
+		writeError(w, r, http.StatusBadRequest, CodeValidationFailed, "No puedes reportar tu propia rutina")
+		return
+	}
+
+	pending, err := s.Moderation.HasPendingReport(routine.ID, userID)
+	if err != nil {
+		writeError(w, r, http.StatusInternalServerError, CodeInternal, "Error al reportar la rutina")
+		return
+	}
+	if pending {
+		writeError(w, r, http.StatusConflict, CodeReportDuplicate, "Ya reportaste esta rutina")
+		return
+	}
+
+	report := models.RoutineReport{
+		RoutineID:  routine.ID,
+		ReporterID: userID,
+		Reason:     req.Reason,
+		Comment:    req.Comment,
+		Status:     models.ReportPending,
+	}
+	if err := s.Moderation.CreateReport(&report); err != nil {
+		writeError(w, r, http.StatusInternalServerError, CodeInternal, "Error al reportar la rutina")
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(report)
+}
+
+// Marcar una rutina con me gusta
+func (s *Server) LikeRoutineHandler(w http.ResponseWriter, r *http.Request) {
+	s.setRoutineLike(w, r, true)
+}
+
+// Quitar el me gusta de una rutina
+func (s *Server) UnlikeRoutineHandler(w http.ResponseWriter, r *http.Request) {
+	s.setRoutineLike(w, r, false)
+}
+
+// setRoutineLike marca o desmarca el me gusta del usuario y responde con el total actualizado.
+// Repetir la misma acción no es un error.
+func (s *Server) setRoutineLike(w http.ResponseWriter, r *http.Request, liked bool) {
+	userID, ok := currentUserID(r)
+	if !ok {
+		writeError(w, r, http.StatusInternalServerError, CodeInternal, "No se encontró el ID del usuario en la solicitud")
+		return
+	}
+
+	// Solo las rutinas públicas y visibles, o las propias
+	routine, err := s.Routines.FindByID(pathID(r, "id"))
+	if err != nil || routine.Hidden {
+		writeError(w, r, http.StatusNotFound, CodeRoutineNotFound, "Rutina no encontrada")
+		return
+	}
+	if !routine.Public {
+		if owner, err := s.Routines.IsOwner(userID, routine.ID); err != nil || !owner {
+			writeError(w, r, http.StatusNotFound, CodeRoutineNotFound, "Rutina no encontrada")
 			return
 		}
 	}
 
-	// Eliminar la rutina
-	if err := db.DB.Delete(&routine).Error; err != nil {
-		http.Error(w, "Error al eliminar la rutina", http.StatusInternalServerError)
+	if liked {
+		err = s.Routines.Like(userID, routine.ID)
+	} else {
+		err = s.Routines.Unlike(userID, routine.ID)
+	}
+	if err != nil {
+		writeError(w, r, http.StatusInternalServerError, CodeInternal, "Error al guardar el me gusta")
+		return
+	}
+
+	likeCount, err := s.Routines.CountLikes(routine.ID)
+	if err != nil {
+		writeError(w, r, http.StatusInternalServerError, CodeInternal, "Error al contar los me gusta de la rutina")
 		return
 	}
 
 	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"liked":     liked,
+		"likeCount": likeCount,
+	})
+}
+
+func validReportReason(reason string) bool {
+	for _, valid := range models.ReportReasons {
+		if reason == valid {
+			return true
+		}
+	}
+	return false
+}
+
+// maxRoutinePageSize limita las rutinas por página de los listados
+const maxRoutinePageSize = 100
+
+// routineListQuery lee los parámetros de los listados de rutinas: search, sort, limit, cursor y
+// offset (este último solo para los clientes que aún no usan el cursor). defaultLimit se usa si
+// no se indica limit; 0 devuelve todas las rutinas.
+func routineListQuery(r *http.Request, defaultLimit int) (store.RoutineQuery, error) {
+	params := r.URL.Query()
+	query := store.RoutineQuery{
+		Search: params.Get("search"),
+		Sort:   params.Get("sort"),
+		Limit:  defaultLimit,
+	}
+
+	if limitStr := params.Get("limit"); limitStr != "" {
+		limit, err := strconv.Atoi(limitStr)
+		if err != nil || limit <= 0 {
+			return query, errors.New("El parámetro limit debe ser un número positivo")
+		}
+		query.Limit = limit
+	}
+	if query.Limit > maxRoutinePageSize {
+		query.Limit = maxRoutinePageSize
+	}
+
+	filters, err := routineFilters(params)
+	if err != nil {
+		return query, err
+	}
+	query.Filters = filters
+
+	if cursor := params.Get("cursor"); cursor != "" {
+		decoded, err := store.DecodeCursor(cursor)
+		if err != nil {
+			return query, errors.New("El parámetro cursor no es válido")
+		}
+		query.Cursor = decoded
+	} else if offset, err := strconv.Atoi(params.Get("offset")); err == nil && offset > 0 {
+		query.Offset = offset
+	}
+	return query, nil
 }
 
-// Función para generar 4 dígitos aleatorios
-func generateFourRandomDigits() string {
-	// Crea un generador de números aleatorios local
-	src := rand.NewSource(time.Now().UnixNano())
-	r := rand.New(src)
+// routineFilters lee los filtros por facetas: minExercises, maxExercises, muscle y equipment
+// (separados por comas), minDuration y maxDuration (en minutos), author (username) y createdAfter
+// (fecha AAAA-MM-DD o RFC 3339)
+func routineFilters(params url.Values) (store.RoutineFilters, error) {
+	var filters store.RoutineFilters
+	numbers := []struct {
+		name  string
+		value *int
+	}{
+		{"minExercises", &filters.MinExercises},
+		{"maxExercises", &filters.MaxExercises},
+		{"minDuration", &filters.MinDuration},
+		{"maxDuration", &filters.MaxDuration},
+	}
+	for _, number := range numbers {
+		value := params.Get(number.name)
+		if value == "" {
+			continue
+		}
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 0 {
+			return filters, errors.New("El parámetro " + number.name + " debe ser un número positivo")
+		}
+		*number.value = parsed
+	}
+	if filters.MaxExercises > 0 && filters.MinExercises > filters.MaxExercises {
+		return filters, errors.New("minExercises no puede ser mayor que maxExercises")
+	}
+	if filters.MaxDuration > 0 && filters.MinDuration > filters.MaxDuration {
+		return filters, errors.New("minDuration no puede ser mayor que maxDuration")
+	}
 
-	// Genera un número aleatorio entre 0 y 9999 usando el generador local
-	number := r.Intn(10000)
+	filters.Muscles = listParam(params.Get("muscle"))
+	filters.Equipment = listParam(params.Get("equipment"))
+	filters.Author = strings.TrimSpace(params.Get("author"))
 
-	// Convierte el número a una cadena
-	strNumber := strconv.Itoa(number)
+	if value := params.Get("createdAfter"); value != "" {
+		createdAfter, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			createdAfter, err = time.Parse("2006-01-02", value)
+		}
+		if err != nil {
+			return filters, errors.New("El parámetro createdAfter debe ser una fecha AAAA-MM-DD o RFC 3339")
+		}
+		filters.CreatedAfter = &createdAfter
+	}
+	return filters, nil
+}
 
-	// Añade ceros al principio si es necesario para asegurar 4 dígitos
-	for len(strNumber) < 4 {
-		strNumber = "0" + strNumber
+// listParam separa un parámetro con valores separados por comas, en minúsculas y sin repetidos
+func listParam(value string) []string {
+	var values []string
+	for _, item := range strings.Split(value, ",") {
+		item = strings.ToLower(strings.TrimSpace(item))
+		if item != "" && !containsString(values, item) {
+			values = append(values, item)
+		}
 	}
+	return values
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// writeRoutineListError responde al error de un listado de rutinas
+func writeRoutineListError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, store.ErrInvalidSort):
+		writeError(w, r, http.StatusBadRequest, CodeInvalidParameter, "El parámetro sort debe ser newest, copied, liked, name o relevance (solo con search)")
+	case errors.Is(err, store.ErrInvalidCursor):
+		writeError(w, r, http.StatusBadRequest, CodeInvalidParameter, "El cursor no corresponde al orden solicitado")
+	default:
+		writeError(w, r, http.StatusInternalServerError, CodeInternal, "Error al obtener las rutinas")
+	}
+}
 
-	return strNumber
+// routinePageResult arma la respuesta de un listado: las rutinas, el total con el mismo filtro y
+// el cursor de la página siguiente (null si no hay más)
+func routinePageResult(page store.RoutinePage) map[string]interface{} {
+	routines := page.Routines
+	if routines == nil {
+		routines = []models.Routine{}
+	}
+	var nextCursor *string
+	if page.Next != nil {
+		encoded := page.Next.Encode()
+		nextCursor = &encoded
+	}
+	return map[string]interface{}{
+		"routines":   routines,
+		"total":      page.Total,
+		"nextCursor": nextCursor,
+	}
+}
+
+// copyRoutineName devuelve "<nombre> (Copia)" o, si el usuario ya tiene una rutina con ese
+// nombre, "<nombre> (Copia N)" con el primer N libre.
+func (s *Server) copyRoutineName(ownerID string, name string) (string, error) {
+	candidate := name + " (Copia)"
+	for count := 2; count <= 1000; count++ {
+		taken, err := s.Routines.NameTaken(ownerID, candidate, 0)
+		if err != nil {
+			return "", err
+		}
+		if !taken {
+			return candidate, nil
+		}
+		candidate = name + " (Copia " + strconv.Itoa(count) + ")"
+	}
+	return "", errors.New("no hay un nombre de copia disponible")
 }
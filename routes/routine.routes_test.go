@@ -0,0 +1,309 @@
+package routes
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/danilsgit/gym-stats-backend/models"
+	"github.com/danilsgit/gym-stats-backend/store"
+)
+
+func TestUserRoutineLifecycle(t *testing.T) {
+	api := newTestAPI(t)
+	_, token := api.createUser("ana")
+
+	req := models.RoutineRequest{Name: "Torso", Description: "Empuje y tirón"}
+	req.ExerciseRequest = []models.ExerciseRequest{{Name: "Press banca"}}
+	rec := api.do("POST", "/users/routines", token, req)
+	expectStatus(t, rec, http.StatusCreated)
+	var routine models.Routine
+	decode(t, rec, &routine)
+
+	// El nombre es único entre las rutinas del usuario
+	expectStatus(t, api.do("POST", "/users/routines", token, req), http.StatusConflict)
+
+	rec = api.do("PUT", "/users/routines/name", token, models.UpdateNameRoutineRequest{ID: routine.ID, Name: "Torso A"})
+	expectStatus(t, rec, http.StatusOK)
+	rec = api.do("PUT", "/users/routines/description", token, models.UpdateDescriptionRoutineRequest{ID: routine.ID, Description: "Nueva"})
+	expectStatus(t, rec, http.StatusOK)
+
+	path := fmt.Sprintf("/routines/%d", routine.ID)
+	rec = api.do("GET", path, "", nil)
+	expectStatus(t, rec, http.StatusOK)
+	var result struct {
+		Name        string            `json:"name"`
+		Description string            `json:"description"`
+		Exercises   []models.Exercise `json:"exercises"`
+	}
+	decode(t, rec, &result)
+	if result.Name != "Torso A" || result.Description != "Nueva" || len(result.Exercises) != 1 {
+		t.Fatalf("rutina inesperada: %+v", result)
+	}
+
+	expectStatus(t, api.do("DELETE", fmt.Sprintf("/users/routines/%d", routine.ID), token, nil), http.StatusOK)
+	expectStatus(t, api.do("GET", path, "", nil), http.StatusNotFound)
+}
+
+func TestUserRoutesRequireToken(t *testing.T) {
+	api := newTestAPI(t)
+
+	expectError(t, api.do("GET", "/users/routines", "", nil), http.StatusForbidden, CodeMissingToken)
+
+	expectStatus(t, api.do("GET", "/users/workouts", "token-invalido", nil), http.StatusForbidden)
+}
+
+// POST /routines/copy copia la rutina guardada, con las mismas reglas que /routines/{id}/copy,
+// tanto con el ID como con la solicitud anterior (autor y nombre)
+func TestLegacyCopyRoutine(t *testing.T) {
+	api := newTestAPI(t)
+	owner, _ := api.createUser("ana")
+	_, token := api.createUser("beto")
+	private := api.createRoutine(owner.ID, "Privada", false)
+	public := api.createRoutine(owner.ID, "Pública", true)
+
+	expectStatus(t, api.do("POST", "/routines/copy", token, models.CopyRoutineRequest{ID: private.ID}), http.StatusForbidden)
+	expectStatus(t, api.do("POST", "/routines/copy", token, models.CopyRoutineRequest{ID: missingID}), http.StatusNotFound)
+	expectStatus(t, api.do("POST", "/routines/copy", token, models.CopyRoutineRequest{Name: "Sin ID ni autor"}), http.StatusBadRequest)
+	expectStatus(t, api.do("POST", "/routines/copy", token, models.CopyRoutineRequest{UserId: owner.ID, Name: "No existe"}), http.StatusNotFound)
+	expectStatus(t, api.do("POST", "/routines/copy", token, models.CopyRoutineRequest{UserId: owner.ID, Name: private.Name}), http.StatusForbidden)
+
+	if err := api.memory.Routines.UpdateHidden(public.ID, true); err != nil {
+		t.Fatal(err)
+	}
+	expectStatus(t, api.do("POST", "/routines/copy", token, models.CopyRoutineRequest{ID: public.ID}), http.StatusNotFound)
+	if err := api.memory.Routines.UpdateHidden(public.ID, false); err != nil {
+		t.Fatal(err)
+	}
+
+	// Lo que envía el cliente además del ID no se copia
+	req := models.CopyRoutineRequest{ID: public.ID, Name: "Inventada", Public: true}
+	req.ExerciseRequest = []models.ExerciseRequest{{Name: "Inventado"}}
+	rec := api.do("POST", "/routines/copy", token, req)
+	expectStatus(t, rec, http.StatusCreated)
+	var copied models.Routine
+	decode(t, rec, &copied)
+	if copied.ForkedFromID == nil || *copied.ForkedFromID != public.ID {
+		t.Fatalf("la copia no apunta a la rutina original: %+v", copied.ForkedFromID)
+	}
+	if copied.Public || len(copied.Exercises) != 1 || copied.Exercises[0].Name != "Sentadilla" || len(copied.Exercises[0].Sets) != 2 {
+		t.Fatalf("la copia no coincide con la rutina original: %+v", copied)
+	}
+
+	// La solicitud de los clientes anteriores no tiene ID: la rutina se busca por autor y nombre
+	legacy := models.CopyRoutineRequest{UserId: owner.ID, Name: public.Name, Description: "Inventada"}
+	legacy.ExerciseRequest = []models.ExerciseRequest{{Name: "Inventado"}}
+	rec = api.do("POST", "/routines/copy", token, legacy)
+	expectStatus(t, rec, http.StatusCreated)
+	copied = models.Routine{}
+	decode(t, rec, &copied)
+	if copied.ForkedFromID == nil || *copied.ForkedFromID != public.ID || copied.Description != public.Description {
+		t.Fatalf("la copia no coincide con la rutina original: %+v", copied)
+	}
+	if len(copied.Exercises) != 1 || copied.Exercises[0].Name != "Sentadilla" {
+		t.Fatalf("la copia tomó los ejercicios del cliente: %+v", copied.Exercises)
+	}
+}
+
+// Las respuestas públicas de rutinas nunca incluyen el hash de la contraseña ni el correo del autor
+func TestPublicRoutineResponsesHideAccountData(t *testing.T) {
+	api := newTestAPI(t)
+	author, _ := api.createUser("ana")
+	routine := api.createRoutine(author.ID, "Pública", true)
+	stored, err := api.memory.Users.FindByID(author.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	paths := []string{
+		"/routines",
+		"/routines?search=Pública",
+		fmt.Sprintf("/routines/%d", routine.ID),
+		"/users/routines/" + author.ID,
+	}
+	for _, path := range paths {
+		t.Run(path, func(t *testing.T) {
+			rec := api.do("GET", path, "", nil)
+			expectStatus(t, rec, http.StatusOK)
+			body := rec.Body.String()
+			if !strings.Contains(body, `"username":"ana"`) {
+				t.Fatalf("la respuesta no incluye al autor: %s", body)
+			}
+			for _, leaked := range []string{stored.Password, stored.Email, `"password"`, `"email"`} {
+				if strings.Contains(body, leaked) {
+					t.Fatalf("la respuesta incluye %q: %s", leaked, body)
+				}
+			}
+		})
+	}
+}
+
+// GET /users/routines mantiene el arreglo de rutinas; el listado paginado está en /users/routines/page
+func TestUserRoutinesListShapes(t *testing.T) {
+	api := newTestAPI(t)
+	user, token := api.createUser("ana")
+	_, emptyToken := api.createUser("beto")
+	api.createRoutine(user.ID, "Piernas", false)
+	api.createRoutine(user.ID, "Torso", true)
+
+	rec := api.do("GET", "/users/routines?sort=name", token, nil)
+	expectStatus(t, rec, http.StatusOK)
+	var routines []models.Routine
+	decode(t, rec, &routines)
+	if len(routines) != 2 || routines[0].Name != "Piernas" || routines[1].Name != "Torso" {
+		t.Fatalf("rutinas inesperadas: %+v", routines)
+	}
+
+	// Sin rutinas la respuesta es un arreglo vacío, no null
+	rec = api.do("GET", "/users/routines", emptyToken, nil)
+	expectStatus(t, rec, http.StatusOK)
+	if body := strings.TrimSpace(rec.Body.String()); body != "[]" {
+		t.Fatalf("respuesta %s, se esperaba []", body)
+	}
+
+	rec = api.do("GET", "/users/routines/page?sort=name&limit=1", token, nil)
+	expectStatus(t, rec, http.StatusOK)
+	var page struct {
+		Routines   []models.Routine `json:"routines"`
+		Total      int64            `json:"total"`
+		NextCursor *string          `json:"nextCursor"`
+	}
+	decode(t, rec, &page)
+	if len(page.Routines) != 1 || page.Routines[0].Name != "Piernas" || page.Total != 2 || page.NextCursor == nil {
+		t.Fatalf("página inesperada: %+v", page)
+	}
+
+	rec = api.do("GET", "/users/routines/page?sort=name&limit=1&cursor="+url.QueryEscape(*page.NextCursor), token, nil)
+	expectStatus(t, rec, http.StatusOK)
+	decode(t, rec, &page)
+	if len(page.Routines) != 1 || page.Routines[0].Name != "Torso" || page.NextCursor != nil {
+		t.Fatalf("segunda página inesperada: %+v", page)
+	}
+
+	expectStatus(t, api.do("GET", "/users/routines/page", "", nil), http.StatusForbidden)
+}
+
+// forksResponse es la respuesta de GET /routines/{id}/forks
+type forksResponse struct {
+	ForkCount int64            `json:"forkCount"`
+	Forks     []models.Routine `json:"forks"`
+}
+
+// Solo se listan las copias públicas y visibles, pero el total cuenta también las privadas y las
+// ocultas; las eliminadas no cuentan
+func TestRoutineForksListAndCount(t *testing.T) {
+	api := newTestAPI(t)
+	owner, _ := api.createUser("ana")
+	original := api.createRoutine(owner.ID, "Original", true)
+	path := fmt.Sprintf("/routines/%d/forks", original.ID)
+
+	fork := func(username string) (models.Routine, string) {
+		t.Helper()
+		_, token := api.createUser(username)
+		rec := api.do("POST", fmt.Sprintf("/routines/%d/copy", original.ID), token, nil)
+		expectStatus(t, rec, http.StatusCreated)
+		var copied models.Routine
+		decode(t, rec, &copied)
+		return copied, token
+	}
+	public, publicToken := fork("beto")
+	expectStatus(t, api.do("PUT", "/users/routines/public", publicToken, models.UpdatePublicRoutineRequest{ID: public.ID, Public: true}), http.StatusOK)
+	hidden, hiddenToken := fork("carla")
+	expectStatus(t, api.do("PUT", "/users/routines/public", hiddenToken, models.UpdatePublicRoutineRequest{ID: hidden.ID, Public: true}), http.StatusOK)
+	if err := api.memory.Routines.UpdateHidden(hidden.ID, true); err != nil {
+		t.Fatal(err)
+	}
+	fork("dario") // Privada
+	deleted, deletedToken := fork("eva")
+	expectStatus(t, api.do("PUT", "/users/routines/public", deletedToken, models.UpdatePublicRoutineRequest{ID: deleted.ID, Public: true}), http.StatusOK)
+	expectStatus(t, api.do("DELETE", fmt.Sprintf("/users/routines/%d", deleted.ID), deletedToken, nil), http.StatusOK)
+
+	rec := api.do("GET", path, "", nil)
+	expectStatus(t, rec, http.StatusOK)
+	var result forksResponse
+	decode(t, rec, &result)
+	if result.ForkCount != 3 {
+		t.Fatalf("forkCount %d, se esperaba 3", result.ForkCount)
+	}
+	if len(result.Forks) != 1 || result.Forks[0].ID != public.ID {
+		t.Fatalf("copias listadas inesperadas: %+v", result.Forks)
+	}
+
+	// Las copias de una rutina oculta no se exponen
+	if err := api.memory.Routines.UpdateHidden(original.ID, true); err != nil {
+		t.Fatal(err)
+	}
+	expectStatus(t, api.do("GET", path, "", nil), http.StatusNotFound)
+}
+
+// failingOwnership es un RoutineStore que falla al comprobar el dueño de una rutina
+type failingOwnership struct{ store.RoutineStore }
+
+func (failingOwnership) IsOwner(userID string, routineID uint) (bool, error) {
+	return false, errors.New("fallo inyectado")
+}
+
+func TestReportRoutine(t *testing.T) {
+	api := newTestAPI(t)
+	owner, ownerToken := api.createUser("ana")
+	_, token := api.createUser("beto")
+	routine := api.createRoutine(owner.ID, "Pública", true)
+	path := fmt.Sprintf("/routines/%d/reports", routine.ID)
+	req := models.ReportRoutineRequest{Reason: models.ReportSpam}
+
+	expectError(t, api.do("POST", path, ownerToken, req), http.StatusBadRequest, CodeValidationFailed)
+
+	// Un fallo al comprobar el dueño no se confunde con reportar la rutina propia
+	api.server.Routines = failingOwnership{api.memory.Routines}
+	expectError(t, api.do("POST", path, token, req), http.StatusInternalServerError, CodeInternal)
+	api.server.Routines = api.memory.Routines
+
+	expectStatus(t, api.do("POST", path, token, req), http.StatusCreated)
+	expectError(t, api.do("POST", path, token, req), http.StatusConflict, CodeReportDuplicate)
+}
+
+// racingNames es un RoutineStore cuyo NameTaken nunca encuentra el nombre, como si otra solicitud
+// lo hubiera usado justo después de comprobarlo
+type racingNames struct{ store.RoutineStore }
+
+func (racingNames) NameTaken(ownerID string, name string, excludeID uint) (bool, error) {
+	return false, nil
+}
+
+// Los nombres se guardan sin espacios alrededor, no pueden quedar vacíos y no se repiten entre
+// las rutinas del usuario, aunque la comprobación previa no lo detecte
+func TestRoutineNameValidation(t *testing.T) {
+	api := newTestAPI(t)
+	_, token := api.createUser("ana")
+
+	expectError(t, api.do("POST", "/users/routines", token, models.RoutineRequest{Name: "   "}), http.StatusBadRequest, CodeValidationFailed)
+	rec := api.do("POST", "/users/routines", token, models.RoutineRequest{Name: "  Piernas  "})
+	expectStatus(t, rec, http.StatusCreated)
+	var legs models.Routine
+	decode(t, rec, &legs)
+	if legs.Name != "Piernas" {
+		t.Fatalf("nombre %q, se esperaba Piernas", legs.Name)
+	}
+	expectError(t, api.do("POST", "/users/routines", token, models.RoutineRequest{Name: "Piernas "}), http.StatusConflict, CodeRoutineNameTaken)
+
+	rec = api.do("POST", "/users/routines", token, models.RoutineRequest{Name: "Espalda"})
+	expectStatus(t, rec, http.StatusCreated)
+	var back models.Routine
+	decode(t, rec, &back)
+	expectError(t, api.do("PUT", "/users/routines/name", token, models.UpdateNameRoutineRequest{ID: back.ID, Name: " "}), http.StatusBadRequest, CodeValidationFailed)
+	expectError(t, api.do("PUT", "/users/routines/name", token, models.UpdateNameRoutineRequest{ID: back.ID, Name: " Piernas"}), http.StatusConflict, CodeRoutineNameTaken)
+
+	api.server.Routines = racingNames{api.memory.Routines}
+	expectError(t, api.do("POST", "/users/routines", token, models.RoutineRequest{Name: "Piernas"}), http.StatusConflict, CodeRoutineNameTaken)
+	expectError(t, api.do("PUT", "/users/routines/name", token, models.UpdateNameRoutineRequest{ID: back.ID, Name: "Piernas"}), http.StatusConflict, CodeRoutineNameTaken)
+
+	rec = api.do("PUT", "/users/routines/name", token, models.UpdateNameRoutineRequest{ID: back.ID, Name: "  Espalda alta "})
+	expectStatus(t, rec, http.StatusOK)
+	decode(t, rec, &back)
+	if back.Name != "Espalda alta" {
+		t.Fatalf("nombre %q, se esperaba Espalda alta", back.Name)
+	}
+}
@@ -0,0 +1,74 @@
+package routes
+
+import (
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/danilsgit/gym-stats-backend/mailer"
+	"github.com/danilsgit/gym-stats-backend/oidc"
+	"github.com/danilsgit/gym-stats-backend/store"
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+)
+
+// Server agrupa las dependencias de los handlers. Los datos se acceden solo a través de los
+// stores, así que los handlers se pueden probar con los stores en memoria.
+type Server struct {
+	Users      store.UserStore
+	Routines   store.RoutineStore
+	Exercises  store.ExerciseStore // Ejercicios de las rutinas y catálogo global
+	Tokens     store.TokenStore
+	Moderation store.ModerationStore // Reportes de rutinas y registro de auditoría
+	Workouts   store.WorkoutStore    // Sesiones de entrenamiento y récords personales
+	Stats      store.StatsStore
+	OIDC       *oidc.Verifier // Verifica los ID tokens del inicio de sesión social
+	Mailer     mailer.Mailer
+	// EmailLimit es cuántos correos de cada tipo se pueden pedir para una misma dirección en
+	// EmailWindow; 0 no limita. Los pedidos se cuentan en la base de datos para que el límite se
+	// comparta entre instancias (por ejemplo, entre las funciones de Vercel).
+	EmailLimit  int
+	EmailWindow time.Duration
+	// RequireVerifiedEmail impide publicar rutinas hasta que el usuario verifique su correo
+	RequireVerifiedEmail bool
+}
+
+// NewServer crea un Server con los stores de GORM sobre la conexión dada. Devuelve un error si
+// la configuración del correo no es válida.
+func NewServer(db *gorm.DB) (*Server, error) {
+	mail, err := mailer.FromEnv()
+	if err != nil {
+		return nil, err
+	}
+	return &Server{
+		Users:      &store.GormUserStore{DB: db},
+		Routines:   &store.GormRoutineStore{DB: db},
+		Exercises:  &store.GormExerciseStore{DB: db},
+		Tokens:     &store.GormTokenStore{DB: db},
+		Moderation: &store.GormModerationStore{DB: db},
+		Workouts:   &store.GormWorkoutStore{DB: db},
+		Stats:      &store.GormStatsStore{DB: db},
+		OIDC:       oidc.NewVerifier(oidc.ProvidersFromEnv()),
+		Mailer:     mail,
+		// Hasta 3 correos de cada tipo por dirección cada hora
+		EmailLimit:           3,
+		EmailWindow:          time.Hour,
+		RequireVerifiedEmail: os.Getenv("REQUIRE_VERIFIED_EMAIL_TO_PUBLISH") == "true",
+	}, nil
+}
+
+// currentUserID devuelve el ID del usuario autenticado que JwtAuthentication guarda en el contexto
+func currentUserID(r *http.Request) (string, bool) {
+	userID, ok := r.Context().Value("userID").(string)
+	return userID, ok && userID != ""
+}
+
+// pathID lee un ID numérico de la ruta; devuelve 0 (que nunca existe) si no es válido
+func pathID(r *http.Request, name string) uint {
+	id, err := strconv.ParseUint(mux.Vars(r)[name], 10, 64)
+	if err != nil {
+		return 0
+	}
+	return uint(id)
+}
@@ -0,0 +1,189 @@
+package routes
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/danilsgit/gym-stats-backend/mailer"
+	"github.com/danilsgit/gym-stats-backend/models"
+	"github.com/danilsgit/gym-stats-backend/store"
+)
+
+// testAPI es el router real sobre los stores en memoria
+type testAPI struct {
+	t      *testing.T
+	server *Server
+	memory *store.Memory
+	router http.Handler
+}
+
+func newTestAPI(t *testing.T) *testAPI {
+	t.Helper()
+	jwtKey = []byte("clave-de-prueba")
+	memory := store.NewMemory()
+	server := &Server{
+		Users:       memory.Users,
+		Routines:    memory.Routines,
+		Exercises:   memory.Exercises,
+		Tokens:      memory.Tokens,
+		Moderation:  memory.Moderation,
+		Workouts:    memory.Workouts,
+		Stats:       memory.Stats,
+		Mailer:      &mailer.LogMailer{Path: os.DevNull},
+		EmailLimit:  3,
+		EmailWindow: time.Hour,
+	}
+	return &testAPI{t: t, server: server, memory: memory, router: server.Router()}
+}
+
+// createUser crea un usuario con ese username y devuelve el usuario y su token de acceso
+func (api *testAPI) createUser(username string) (models.User, string) {
+	api.t.Helper()
+	return api.createUserWithRole(username, models.RoleUser)
+}
+
+// createUserWithRole crea un usuario con ese rol y devuelve el usuario y su token de acceso
+func (api *testAPI) createUserWithRole(username string, role string) (models.User, string) {
+	api.t.Helper()
+	user := models.User{Username: username, Email: username + "@example.com", Password: "secreto123", Role: role}
+	if err := api.memory.Users.Create(&user); err != nil {
+		api.t.Fatalf("crear usuario %s: %v", username, err)
+	}
+	token, _, err := api.server.issueTokens(user, "")
+	if err != nil {
+		api.t.Fatalf("generar token para %s: %v", username, err)
+	}
+	return user, token
+}
+
+// createRoutine guarda una rutina del usuario con un ejercicio de dos sets
+func (api *testAPI) createRoutine(userID string, name string, public bool) models.Routine {
+	api.t.Helper()
+	routine := models.Routine{
+		Name:        name,
+		Description: "Rutina de prueba",
+		OwnerID:     userID,
+		Public:      public,
+		Exercises: []models.Exercise{{
+			Name: "Sentadilla",
+			Sets: []models.Set{{Reps: 5, Weight: 100, Rest: 120}, {Reps: 5, Weight: 100, Rest: 120}},
+		}},
+	}
+	if err := api.memory.Routines.Create(&routine, userID); err != nil {
+		api.t.Fatalf("crear rutina %s: %v", name, err)
+	}
+	return routine
+}
+
+// do envía la solicitud al router; body se codifica como JSON si no es nil
+func (api *testAPI) do(method string, path string, token string, body interface{}) *httptest.ResponseRecorder {
+	api.t.Helper()
+	var payload bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&payload).Encode(body); err != nil {
+			api.t.Fatalf("codificar el cuerpo: %v", err)
+		}
+	}
+	req := httptest.NewRequest(method, path, &payload)
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	api.router.ServeHTTP(rec, req)
+	return rec
+}
+
+// expectStatus falla si la respuesta no tiene el estado esperado
+func expectStatus(t *testing.T, rec *httptest.ResponseRecorder, status int) {
+	t.Helper()
+	if rec.Code != status {
+		t.Fatalf("estado %d, se esperaba %d: %s", rec.Code, status, rec.Body.String())
+	}
+}
+
+// decode decodifica el cuerpo JSON de la respuesta
+func decode(t *testing.T, rec *httptest.ResponseRecorder, v interface{}) {
+	t.Helper()
+	if err := json.Unmarshal(rec.Body.Bytes(), v); err != nil {
+		t.Fatalf("decodificar la respuesta: %v: %s", err, rec.Body.String())
+	}
+}
+
+// expectError falla si la respuesta no es un error con ese estado y ese código
+func expectError(t *testing.T, rec *httptest.ResponseRecorder, status int, code string) {
+	t.Helper()
+	expectStatus(t, rec, status)
+	var body struct {
+		Error struct {
+			Code string `json:"code"`
+		} `json:"error"`
+	}
+	decode(t, rec, &body)
+	if body.Error.Code != code {
+		t.Fatalf("código %q, se esperaba %q: %s", body.Error.Code, code, rec.Body.String())
+	}
+}
+
+// recordingMailer guarda los correos enviados para leer los enlaces en las pruebas
+type recordingMailer struct {
+	mu       sync.Mutex
+	messages []mailer.Message
+}
+
+func (m *recordingMailer) Send(msg mailer.Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.messages = append(m.messages, msg)
+	return nil
+}
+
+// recordMail hace que el servidor guarde los correos en lugar de enviarlos
+func (api *testAPI) recordMail() *recordingMailer {
+	api.t.Helper()
+	// Sin APP_URL los correos llevan solo el token
+	api.t.Setenv("APP_URL", "")
+	m := &recordingMailer{}
+	api.server.Mailer = m
+	return m
+}
+
+// lastToken devuelve el token del último correo enviado a la dirección
+func (m *recordingMailer) lastToken(t *testing.T, to string) string {
+	t.Helper()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i := len(m.messages) - 1; i >= 0; i-- {
+		if m.messages[i].To != to {
+			continue
+		}
+		// Saludo, instrucciones, enlace y despedida separados por líneas en blanco
+		parts := strings.Split(m.messages[i].Body, "\n\n")
+		if len(parts) < 3 {
+			t.Fatalf("correo sin enlace: %q", m.messages[i].Body)
+		}
+		return parts[2]
+	}
+	t.Fatalf("no se envió ningún correo a %s", to)
+	return ""
+}
+
+// sent cuenta los correos enviados a la dirección
+func (m *recordingMailer) sent(to string) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	count := 0
+	for _, msg := range m.messages {
+		if msg.To == to {
+			count++
+		}
+	}
+	return count
+}
@@ -0,0 +1,181 @@
+package routes
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/danilsgit/gym-stats-backend/analytics"
+)
+
+// Estadísticas de entrenamiento del usuario
+
+// maxStatsWeeks limita el rango de fechas de las estadísticas
+const maxStatsWeeks = 104
+
+// Volumen semanal del usuario (sets, repeticiones y tonelaje) por músculo o ejercicio.
+// Parámetros: from y to (AAAA-MM-DD, por defecto las últimas 12 semanas), groupBy (muscle,
+// exercise o total) y source (logged, los sets registrados, o planned, los de las rutinas).
+func (s *Server) GetVolumeStatsHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := currentUserID(r)
+	if !ok {
+		writeError(w, r, http.StatusInternalServerError, CodeInternal, "No se encontró el ID del usuario en la solicitud")
+		return
+	}
+
+	from, to, ok := statsDateRange(w, r, 12)
+	if !ok {
+		return
+	}
+	groupBy := r.URL.Query().Get("groupBy")
+	if groupBy == "" {
+		groupBy = analytics.GroupByMuscle
+	}
+	if !analytics.ValidGroupBy(groupBy) {
+		writeError(w, r, http.StatusBadRequest, CodeInvalidParameter, analytics.ErrInvalidGroupBy.Error())
+		return
+	}
+	source := r.URL.Query().Get("source")
+	if source == "" {
+		source = analytics.SourceLogged
+	}
+	if !analytics.ValidSource(source) {
+		writeError(w, r, http.StatusBadRequest, CodeInvalidParameter, analytics.ErrInvalidSource.Error())
+		return
+	}
+
+	entries, err := s.Stats.VolumeEntries(userID, source, from, to)
+	if err != nil {
+		writeError(w, r, http.StatusInternalServerError, CodeInternal, "Error al obtener los sets")
+		return
+	}
+
+	var result = map[string]interface{}{}
+	result["from"] = from.Format("2006-01-02")
+	result["to"] = to.Format("2006-01-02")
+	result["groupBy"] = groupBy
+	result["source"] = source
+	result["weeks"] = analytics.WeeklyVolume(entries, from, to, groupBy)
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(result)
+}
+
+// statsDateRange lee from y to (AAAA-MM-DD, en UTC). to incluye todo el día; sin fechas se usan
+// las últimas defaultWeeks semanas. Responde 400 y devuelve false si el rango no es válido.
+func statsDateRange(w http.ResponseWriter, r *http.Request, defaultWeeks int) (time.Time, time.Time, bool) {
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	to := today
+	if value := r.URL.Query().Get("to"); value != "" {
+		parsed, err := time.Parse("2006-01-02", value)
+		if err != nil {
+			writeError(w, r, http.StatusBadRequest, CodeInvalidParameter, "El parámetro to debe ser una fecha AAAA-MM-DD")
+			return time.Time{}, time.Time{}, false
+		}
+		to = parsed
+	}
+	from := analytics.WeekStart(to).AddDate(0, 0, -7*(defaultWeeks-1))
+	if value := r.URL.Query().Get("from"); value != "" {
+		parsed, err := time.Parse("2006-01-02", value)
+		if err != nil {
+			writeError(w, r, http.StatusBadRequest, CodeInvalidParameter, "El parámetro from debe ser una fecha AAAA-MM-DD")
+			return time.Time{}, time.Time{}, false
+		}
+		from = parsed
+	}
+
+	if from.After(to) {
+		writeError(w, r, http.StatusBadRequest, CodeInvalidParameter, "from no puede ser posterior a to")
+		return time.Time{}, time.Time{}, false
+	}
+	if to.Sub(from) > maxStatsWeeks*7*24*time.Hour {
+		writeError(w, r, http.StatusBadRequest, CodeInvalidParameter, "El rango de fechas no puede superar las 104 semanas")
+		return time.Time{}, time.Time{}, false
+	}
+	return from, to.Add(24*time.Hour - time.Nanosecond), true
+}
+
+// Progresión de un ejercicio del usuario: por período (bucket day, week o month; por defecto
+// week), el peso del top set, el volumen, el mejor 1RM estimado y los récords de repeticiones.
+// source elige la serie, sin mezclarlas: logged (por defecto), los sets registrados en las
+// sesiones, o planned, los sets del ejercicio que regían en cada sesión con su rutina, en la
+// fecha de la sesión (editar la rutina no cambia las sesiones anteriores).
+// from y to como en el volumen (por defecto las últimas 52 semanas).
+//
+// Solo se usan las sesiones del propio usuario. La serie planned exige que el ejercicio sea de
+// una rutina del usuario; la serie logged también se puede consultar para un ejercicio de otro
+// usuario que se haya entrenado (por ejemplo, de una rutina pública), y entonces el nombre es el
+// que quedó en los sets registrados. En cualquier otro caso se responde 404.
+func (s *Server) GetExerciseProgressHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := currentUserID(r)
+	if !ok {
+		writeError(w, r, http.StatusInternalServerError, CodeInternal, "No se encontró el ID del usuario en la solicitud")
+		return
+	}
+
+	from, to, ok := statsDateRange(w, r, 52)
+	if !ok {
+		return
+	}
+	bucket := r.URL.Query().Get("bucket")
+	if bucket == "" {
+		bucket = analytics.BucketWeek
+	}
+	if !analytics.ValidBucket(bucket) {
+		writeError(w, r, http.StatusBadRequest, CodeInvalidParameter, analytics.ErrInvalidBucket.Error())
+		return
+	}
+	source := r.URL.Query().Get("source")
+	if source == "" {
+		source = analytics.SourceLogged
+	}
+	if !analytics.ValidSource(source) {
+		writeError(w, r, http.StatusBadRequest, CodeInvalidParameter, analytics.ErrInvalidSource.Error())
+		return
+	}
+
+	exerciseID := pathID(r, "id")
+	owner, err := s.Exercises.IsOwner(userID, exerciseID)
+	if err != nil {
+		writeError(w, r, http.StatusInternalServerError, CodeInternal, "Error al obtener el ejercicio")
+		return
+	}
+	if !owner && source == analytics.SourcePlanned {
+		writeError(w, r, http.StatusNotFound, CodeExerciseNotFound, "Ejercicio no encontrado")
+		return
+	}
+
+	// Todo el historial hasta to, para detectar los récords de repeticiones contra los sets anteriores
+	entries, err := s.Stats.ExerciseEntries(userID, exerciseID, source, to)
+	if err != nil {
+		writeError(w, r, http.StatusInternalServerError, CodeInternal, "Error al obtener los sets")
+		return
+	}
+	if !owner && len(entries) == 0 {
+		writeError(w, r, http.StatusNotFound, CodeExerciseNotFound, "Ejercicio no encontrado")
+		return
+	}
+
+	// El nombre actual del ejercicio si es del usuario; si no (o si se eliminó), el del último set
+	name := ""
+	if owner {
+		if exercise, err := s.Exercises.FindByID(exerciseID); err == nil {
+			name = exercise.Name
+		}
+	}
+	for i := len(entries) - 1; i >= 0 && name == ""; i-- {
+		name = entries[i].Exercise
+	}
+
+	var result = map[string]interface{}{}
+	result["exerciseId"] = exerciseID
+	result["name"] = name
+	result["bucket"] = bucket
+	result["source"] = source
+	result["from"] = from.Format("2006-01-02")
+	result["to"] = to.Format("2006-01-02")
+	result["points"] = analytics.Progress(entries, bucket, from, to)
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(result)
+}
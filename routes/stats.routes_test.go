@@ -0,0 +1,206 @@
+package routes
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/danilsgit/gym-stats-backend/analytics"
+	"github.com/danilsgit/gym-stats-backend/models"
+)
+
+// statsFixture es la historia de las pruebas de estadísticas: ana entrena su rutina ahora (la
+// primera semana), después edita el primer set planificado a 1x1 y elimina el segundo, y vuelve
+// a entrenar la semana siguiente. Un set registrado de 3x120 queda en la segunda semana.
+type statsFixture struct {
+	api      *testAPI
+	token    string
+	exercise models.Exercise
+	weeks    [2]string // Semanas ISO de las dos sesiones
+	starts   [2]string // Lunes de esas semanas (AAAA-MM-DD)
+	from, to string    // Rango de las dos semanas para las consultas
+}
+
+func newStatsFixture(t *testing.T) statsFixture {
+	t.Helper()
+	api := newTestAPI(t)
+	user, token := api.createUser("ana")
+	other, _ := api.createUser("beto")
+	routine := api.createRoutine(user.ID, "Piernas", false)
+	exercise := routine.Exercises[0]
+
+	// Los sets planificados cuentan en las sesiones que empiezan después de crearlos o editarlos,
+	// así que las fechas son relativas a ahora
+	first := models.WorkoutSession{UserID: user.ID, RoutineID: routine.ID, RoutineName: routine.Name, StartedAt: time.Now()}
+	if err := api.memory.Workouts.Create(&first); err != nil {
+		t.Fatal(err)
+	}
+	// Las sesiones de otro usuario con la misma rutina no cuentan
+	foreign := models.WorkoutSession{UserID: other.ID, RoutineID: routine.ID, RoutineName: routine.Name, StartedAt: first.StartedAt}
+	if err := api.memory.Workouts.Create(&foreign); err != nil {
+		t.Fatal(err)
+	}
+
+	rec := api.do("PUT", "/users/routines/exercises/sets", token, exerciseRequest(0, exercise.ID, exercise.Sets[0].ID))
+	expectStatus(t, rec, http.StatusOK)
+
+	start := analytics.WeekStart(first.StartedAt)
+	second := models.WorkoutSession{UserID: user.ID, RoutineID: routine.ID, RoutineName: routine.Name, StartedAt: start.AddDate(0, 0, 8).Add(18 * time.Hour)}
+	if err := api.memory.Workouts.Create(&second); err != nil {
+		t.Fatal(err)
+	}
+	set := models.WorkoutSet{WorkoutSessionID: second.ID, ExerciseID: exercise.ID, ExerciseName: exercise.Name, Reps: 3, Weight: 120, PerformedAt: second.StartedAt}
+	if err := api.memory.Workouts.LogSet(user.ID, &set); err != nil {
+		t.Fatal(err)
+	}
+
+	fixture := statsFixture{api: api, token: token, exercise: exercise}
+	for i, session := range []models.WorkoutSession{first, second} {
+		year, week := session.StartedAt.UTC().ISOWeek()
+		fixture.weeks[i] = fmt.Sprintf("%d-W%02d", year, week)
+		fixture.starts[i] = analytics.WeekStart(session.StartedAt).Format("2006-01-02")
+	}
+	fixture.from = start.Format("2006-01-02")
+	fixture.to = start.AddDate(0, 0, 13).Format("2006-01-02")
+	return fixture
+}
+
+// volumeResponse es la respuesta de GET /users/stats/volume
+type volumeResponse struct {
+	Source string                 `json:"source"`
+	Weeks  []analytics.WeekVolume `json:"weeks"`
+}
+
+// source=planned cuenta los sets de la rutina que regían en cada sesión, en la fecha de la
+// sesión; source=logged, los sets registrados en su propia fecha
+func TestVolumeStatsSource(t *testing.T) {
+	f := newStatsFixture(t)
+
+	tests := []struct {
+		source string
+		weeks  []analytics.WeekVolume
+	}{
+		// En la primera sesión regía el segundo set (eliminado después); el primero se editó
+		// después de la sesión, así que sus valores no son los que se planificaron
+		{analytics.SourcePlanned, []analytics.WeekVolume{
+			{Week: f.weeks[0], Start: f.starts[0], Sets: 1, Reps: 5, Tonnage: 500, Groups: []analytics.GroupVolume{{Key: "Sentadilla", Sets: 1, Reps: 5, Tonnage: 500}}},
+			{Week: f.weeks[1], Start: f.starts[1], Sets: 1, Reps: 1, Tonnage: 1, Groups: []analytics.GroupVolume{{Key: "Sentadilla", Sets: 1, Reps: 1, Tonnage: 1}}},
+		}},
+		{analytics.SourceLogged, []analytics.WeekVolume{
+			{Week: f.weeks[0], Start: f.starts[0]},
+			{Week: f.weeks[1], Start: f.starts[1], Sets: 1, Reps: 3, Tonnage: 360, Groups: []analytics.GroupVolume{{Key: "Sentadilla", Sets: 1, Reps: 3, Tonnage: 360}}},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.source, func(t *testing.T) {
+			rec := f.api.do("GET", "/users/stats/volume?groupBy=exercise&from="+f.from+"&to="+f.to+"&source="+tt.source, f.token, nil)
+			expectStatus(t, rec, http.StatusOK)
+			var result volumeResponse
+			decode(t, rec, &result)
+			if result.Source != tt.source || len(result.Weeks) != len(tt.weeks) {
+				t.Fatalf("respuesta inesperada: %+v", result)
+			}
+			for i, want := range tt.weeks {
+				got := result.Weeks[i]
+				if got.Week != want.Week || got.Start != want.Start || got.Sets != want.Sets || got.Reps != want.Reps || got.Tonnage != want.Tonnage || len(got.Groups) != len(want.Groups) {
+					t.Fatalf("semana %d: %+v, se esperaba %+v", i, got, want)
+				}
+				for j := range want.Groups {
+					if got.Groups[j] != want.Groups[j] {
+						t.Fatalf("semana %s: grupos %+v, se esperaban %+v", got.Week, got.Groups, want.Groups)
+					}
+				}
+			}
+		})
+	}
+
+	expectStatus(t, f.api.do("GET", "/users/stats/volume?source=otro", f.token, nil), http.StatusBadRequest)
+}
+
+// progressResponse es la respuesta de GET /users/stats/exercises/{id}/progress
+type progressResponse struct {
+	Name   string                    `json:"name"`
+	Source string                    `json:"source"`
+	Points []analytics.ProgressPoint `json:"points"`
+}
+
+// Las series logged y planned no se mezclan, y editar la rutina no cambia las sesiones anteriores
+func TestExerciseProgressSource(t *testing.T) {
+	f := newStatsFixture(t)
+
+	path := fmt.Sprintf("/users/stats/exercises/%d/progress?from=%s&to=%s", f.exercise.ID, f.from, f.to)
+	tests := []struct {
+		query  string
+		source string
+		points []analytics.ProgressPoint
+	}{
+		{"", analytics.SourceLogged, []analytics.ProgressPoint{
+			{Period: f.weeks[1], Start: f.starts[1], Sets: 1, TopSetWeight: 120, TopSetReps: 3, Volume: 360},
+		}},
+		{"&source=logged", analytics.SourceLogged, []analytics.ProgressPoint{
+			{Period: f.weeks[1], Start: f.starts[1], Sets: 1, TopSetWeight: 120, TopSetReps: 3, Volume: 360},
+		}},
+		{"&source=planned", analytics.SourcePlanned, []analytics.ProgressPoint{
+			{Period: f.weeks[0], Start: f.starts[0], Sets: 1, TopSetWeight: 100, TopSetReps: 5, Volume: 500},
+			{Period: f.weeks[1], Start: f.starts[1], Sets: 1, TopSetWeight: 1, TopSetReps: 1, Volume: 1},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run("source="+tt.source+tt.query, func(t *testing.T) {
+			rec := f.api.do("GET", path+tt.query, f.token, nil)
+			expectStatus(t, rec, http.StatusOK)
+			var result progressResponse
+			decode(t, rec, &result)
+			if result.Source != tt.source || len(result.Points) != len(tt.points) {
+				t.Fatalf("respuesta inesperada: %+v", result)
+			}
+			for i, want := range tt.points {
+				got := result.Points[i]
+				if got.Period != want.Period || got.Start != want.Start || got.Sets != want.Sets || got.TopSetWeight != want.TopSetWeight || got.TopSetReps != want.TopSetReps || got.Volume != want.Volume {
+					t.Fatalf("punto %d: %+v, se esperaba %+v", i, got, want)
+				}
+			}
+		})
+	}
+
+	expectStatus(t, f.api.do("GET", path+"&source=otro", f.token, nil), http.StatusBadRequest)
+}
+
+// Un ejercicio de otro usuario solo tiene la serie logged, y solo si se entrenó
+func TestExerciseProgressForeignExercise(t *testing.T) {
+	api := newTestAPI(t)
+	owner, ownerToken := api.createUser("ana")
+	user, token := api.createUser("beto")
+	public := api.createRoutine(owner.ID, "Pública", true)
+	private := api.createRoutine(owner.ID, "Privada", false)
+	exercise := public.Exercises[0]
+
+	workout := models.WorkoutSession{UserID: user.ID, RoutineID: public.ID, RoutineName: public.Name, StartedAt: time.Date(2024, 2, 14, 18, 0, 0, 0, time.UTC)}
+	if err := api.memory.Workouts.Create(&workout); err != nil {
+		t.Fatal(err)
+	}
+	set := models.WorkoutSet{WorkoutSessionID: workout.ID, ExerciseID: exercise.ID, ExerciseName: exercise.Name, Reps: 5, Weight: 80, PerformedAt: workout.StartedAt}
+	if err := api.memory.Workouts.LogSet(user.ID, &set); err != nil {
+		t.Fatal(err)
+	}
+
+	// ana renombra el ejercicio: beto sigue viendo el nombre con el que lo registró
+	rec := api.do("PUT", "/users/routines/exercises/name", ownerToken, models.UpdateNameExerciseRequest{ID: exercise.ID, Name: "Nombre nuevo"})
+	expectStatus(t, rec, http.StatusOK)
+
+	path := fmt.Sprintf("/users/stats/exercises/%d/progress?from=2024-02-01&to=2024-03-31", exercise.ID)
+	rec = api.do("GET", path, token, nil)
+	expectStatus(t, rec, http.StatusOK)
+	var result progressResponse
+	decode(t, rec, &result)
+	if result.Name != "Sentadilla" || len(result.Points) != 1 || result.Points[0].TopSetWeight != 80 {
+		t.Fatalf("respuesta inesperada: %+v", result)
+	}
+
+	// Los sets planificados son de la rutina de ana
+	expectStatus(t, api.do("GET", path+"&source=planned", token, nil), http.StatusNotFound)
+	// Un ejercicio que beto nunca entrenó
+	expectStatus(t, api.do("GET", fmt.Sprintf("/users/stats/exercises/%d/progress", private.Exercises[0].ID), token, nil), http.StatusNotFound)
+	expectStatus(t, api.do("GET", fmt.Sprintf("/users/stats/exercises/%d/progress", missingID), token, nil), http.StatusNotFound)
+}
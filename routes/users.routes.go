@@ -2,63 +2,68 @@ package routes
 
 import (
 	"encoding/json"
+	"log"
 	"net/http"
 
-	"github.com/danilsgit/gym-stats-backend/db"
 	"github.com/danilsgit/gym-stats-backend/models"
+	"golang.org/x/crypto/bcrypt"
 )
 
 // Usuario
 
-func PostUserHandler(w http.ResponseWriter, r *http.Request) {
-	var user models.User
-	errDecoder := json.NewDecoder(r.Body).Decode(&user)
+func (s *Server) PostUserHandler(w http.ResponseWriter, r *http.Request) {
+	// Solo se leen los campos del registro; el rol y el estado de la cuenta no los elige quien se registra
+	var req models.RegisterRequest
+	errDecoder := json.NewDecoder(r.Body).Decode(&req)
 
 	if errDecoder != nil {
-		http.Error(w, "Error al decodificar el cuerpo de la solicitud", http.StatusBadRequest)
+		writeError(w, r, http.StatusBadRequest, CodeInvalidBody, "Error al decodificar el cuerpo de la solicitud")
 		return
 	}
+	user := models.User{Username: req.Username, Email: req.Email, Password: req.Password}
 	// Error si los campos no son válidos / están vacíos
 	if user.Username == "" || user.Email == "" || user.Password == "" {
-		http.Error(w, "Todos los campos son obligatorios", http.StatusBadRequest)
+		writeError(w, r, http.StatusBadRequest, CodeValidationFailed, "Todos los campos son obligatorios")
 		return
 	}
 
 	// Comprobar si el correo ya existe en la BD
-	var existingUser models.User
-	result := db.DB.Where("email = ?", user.Email).First(&existingUser)
-	if result.Error == nil {
-		http.Error(w, "El correo ya está en uso", http.StatusBadRequest)
+	if _, err := s.Users.FindByEmail(user.Email); err == nil {
+		writeError(w, r, http.StatusBadRequest, CodeEmailTaken, "El correo ya está en uso")
 		return
 	}
 
 	// Comprobar si el username ya existe en la BD
-	result = db.DB.Where("username = ?", user.Username).First(&existingUser)
-	if result.Error == nil {
-		http.Error(w, "El nombre de usuario ya está en uso", http.StatusBadRequest)
+	if _, err := s.Users.FindByUsername(user.Username); err == nil {
+		writeError(w, r, http.StatusBadRequest, CodeUsernameTaken, "El nombre de usuario ya está en uso")
 		return
 	}
 
-	createdUser := db.DB.Create(&user)
-
-	err := createdUser.Error
+	user.Role = models.RoleUser
+	// El correo se verifica con el enlace que se envía a continuación
+	user.EmailVerified = false
+	err := s.Users.Create(&user)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		w.Write([]byte(err.Error()))
+		writeError(w, r, http.StatusInternalServerError, CodeInternal, "Error al crear el usuario")
 		return
 	} else {
 		w.WriteHeader(http.StatusCreated)
 	}
 
-	json.NewEncoder(w).Encode(&user)
+	// Si el correo no se puede enviar el usuario puede pedir otro desde /auth/email/resend
+	if err := s.sendEmailVerification(user); err != nil {
+		log.Println("Error enviando el correo de verificación:", err)
+	}
+
+	json.NewEncoder(w).Encode(user.Private())
 }
 
 // Editar username del usuario
-func PutUserInUsernameHandler(w http.ResponseWriter, r *http.Request) {
+func (s *Server) PutUserInUsernameHandler(w http.ResponseWriter, r *http.Request) {
 	// Obtener el ID del usuario de la solicitud
-	userID := r.Context().Value("userID")
-	if userID == nil {
-		http.Error(w, "No se encontró el ID del usuario en la solicitud", http.StatusInternalServerError)
+	userID, ok := currentUserID(r)
+	if !ok {
+		writeError(w, r, http.StatusInternalServerError, CodeInternal, "No se encontró el ID del usuario en la solicitud")
 		return
 	}
 
@@ -68,34 +73,32 @@ func PutUserInUsernameHandler(w http.ResponseWriter, r *http.Request) {
 	}
 	err := json.NewDecoder(r.Body).Decode(&updateInfo)
 	if err != nil {
-		http.Error(w, "Error al decodificar el cuerpo de la solicitud", http.StatusBadRequest)
+		writeError(w, r, http.StatusBadRequest, CodeInvalidBody, "Error al decodificar el cuerpo de la solicitud")
 		return
 	}
 
 	// Error si el campo está vacío
 	if updateInfo.Username == "" {
-		http.Error(w, "El campo username es obligatorio", http.StatusBadRequest)
+		writeError(w, r, http.StatusBadRequest, CodeValidationFailed, "El campo username es obligatorio")
 		return
 	}
 
 	// Error si otro user tiene el mismo username
-	var existingUser models.User
-	result := db.DB.Where("username = ?", updateInfo.Username).First(&existingUser)
-	if result.Error == nil {
-		http.Error(w, "El nombre de usuario ya está en uso", http.StatusBadRequest)
+	if _, err := s.Users.FindByUsername(updateInfo.Username); err == nil {
+		writeError(w, r, http.StatusBadRequest, CodeUsernameTaken, "El nombre de usuario ya está en uso")
 		return
 	}
 
 	// Buscar el usuario por ID
-	var user models.User
-	if err := db.DB.First(&user, "id = ?", userID).Error; err != nil {
-		http.Error(w, "Usuario no encontrado", http.StatusNotFound)
+	user, err := s.Users.FindByID(userID)
+	if err != nil {
+		writeError(w, r, http.StatusNotFound, CodeUserNotFound, "Usuario no encontrado")
 		return
 	}
 
 	// Actualizar el username del usuario
-	if err := db.DB.Model(&user).Update("username", updateInfo.Username).Error; err != nil {
-		http.Error(w, "Error al actualizar el usuario", http.StatusInternalServerError)
+	if err := s.Users.UpdateUsername(user.ID, updateInfo.Username); err != nil {
+		writeError(w, r, http.StatusInternalServerError, CodeInternal, "Error al actualizar el usuario")
 		return
 	}
 
@@ -103,3 +106,75 @@ func PutUserInUsernameHandler(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode("Usuario actualizado con éxito")
 }
+
+// Cambiar la contraseña del usuario o, si se registró con un proveedor social, agregarle una
+// para poder iniciar sesión con su correo. Cierra todas las sesiones y devuelve tokens nuevos.
+func (s *Server) PutUserPasswordHandler(w http.ResponseWriter, r *http.Request) {
+	// Obtener el ID del usuario de la solicitud
+	userID, ok := currentUserID(r)
+	if !ok {
+		writeError(w, r, http.StatusInternalServerError, CodeInternal, "No se encontró el ID del usuario en la solicitud")
+		return
+	}
+
+	var req models.UpdatePasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, r, http.StatusBadRequest, CodeInvalidBody, "Error al decodificar el cuerpo de la solicitud")
+		return
+	}
+
+	// Error si el campo está vacío
+	if req.NewPassword == "" {
+		writeError(w, r, http.StatusBadRequest, CodeValidationFailed, "El campo newPassword es obligatorio")
+		return
+	}
+
+	// Buscar el usuario por ID
+	user, err := s.Users.FindByID(userID)
+	if err != nil {
+		writeError(w, r, http.StatusNotFound, CodeUserNotFound, "Usuario no encontrado")
+		return
+	}
+
+	// Si ya tiene contraseña se debe confirmar la actual
+	if user.Password != "" {
+		if bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.CurrentPassword)) != nil {
+			writeError(w, r, http.StatusUnauthorized, CodeInvalidCredentials, "Contraseña actual incorrecta")
+			return
+		}
+	}
+
+	// Hashear la nueva contraseña igual que en User.BeforeCreate
+	hashedPassword, err := models.HashPassword(req.NewPassword)
+	if err != nil {
+		writeError(w, r, http.StatusInternalServerError, CodeInternal, "Error al guardar la contraseña")
+		return
+	}
+	if err := s.Users.UpdatePassword(user.ID, hashedPassword); err != nil {
+		writeError(w, r, http.StatusInternalServerError, CodeInternal, "Error al guardar la contraseña")
+		return
+	}
+	// Cerrar las sesiones abiertas; los tokens de acceso anteriores ya no sirven por la versión
+	if err := s.Tokens.RevokeUser(user.ID); err != nil {
+		writeError(w, r, http.StatusInternalServerError, CodeInternal, "Error al cerrar las sesiones")
+		return
+	}
+
+	// Iniciar una sesión nueva para quien cambió la contraseña
+	user, err = s.Users.FindByID(user.ID)
+	if err != nil {
+		writeError(w, r, http.StatusNotFound, CodeUserNotFound, "Usuario no encontrado")
+		return
+	}
+	tokenString, refreshToken, err := s.issueTokens(user, "")
+	if err != nil {
+		writeError(w, r, http.StatusInternalServerError, CodeInternal, "Error al generar el token JWT")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"token":        tokenString,
+		"refreshToken": refreshToken,
+	})
+}
@@ -0,0 +1,74 @@
+package routes
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/danilsgit/gym-stats-backend/models"
+)
+
+func TestPutUserPasswordRequiresCurrentPassword(t *testing.T) {
+	api := newTestAPI(t)
+	user, token := api.createUser("ana")
+
+	for _, current := range []string{"", "otra-clave"} {
+		rec := api.do("PUT", "/users/config/password", token, models.UpdatePasswordRequest{CurrentPassword: current, NewPassword: "nueva-clave"})
+		expectError(t, rec, http.StatusUnauthorized, CodeInvalidCredentials)
+	}
+	expectError(t, api.do("PUT", "/users/config/password", token, models.UpdatePasswordRequest{CurrentPassword: "secreto123"}), http.StatusBadRequest, CodeValidationFailed)
+
+	// La contraseña no cambió y la sesión sigue abierta
+	expectStatus(t, api.do("POST", "/login", "", models.LoginRequest{Email: user.Email, Password: "secreto123"}), http.StatusOK)
+	expectStatus(t, api.do("GET", "/users/routines", token, nil), http.StatusOK)
+}
+
+// Cambiar la contraseña cierra las sesiones abiertas y devuelve una sesión nueva
+func TestPutUserPasswordRevokesOldTokens(t *testing.T) {
+	api := newTestAPI(t)
+	user, token := api.createUser("ana")
+	_, otherRefresh := api.startSession(user)
+
+	rec := api.do("PUT", "/users/config/password", token, models.UpdatePasswordRequest{CurrentPassword: "secreto123", NewPassword: "nueva-clave"})
+	expectStatus(t, rec, http.StatusOK)
+	var tokens tokenResponse
+	decode(t, rec, &tokens)
+
+	expectError(t, api.do("GET", "/users/routines", token, nil), http.StatusForbidden, CodeSessionRevoked)
+	expectError(t, api.do("POST", "/auth/refresh", "", models.RefreshTokenRequest{RefreshToken: otherRefresh}), http.StatusUnauthorized, CodeSessionRevoked)
+	expectStatus(t, api.do("GET", "/users/routines", tokens.Token, nil), http.StatusOK)
+
+	// Un token con la versión anterior no sirve aunque su sesión siga activa
+	stored, err := api.memory.Tokens.FindByHash(hashToken(tokens.RefreshToken))
+	if err != nil {
+		t.Fatal(err)
+	}
+	oldVersion, err := GenerateJWT(user, stored.FamilyID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectError(t, api.do("GET", "/users/routines", oldVersion, nil), http.StatusForbidden, CodeSessionRevoked)
+
+	expectError(t, api.do("POST", "/login", "", models.LoginRequest{Email: user.Email, Password: "secreto123"}), http.StatusUnauthorized, CodeInvalidCredentials)
+	expectStatus(t, api.do("POST", "/login", "", models.LoginRequest{Email: user.Email, Password: "nueva-clave"}), http.StatusOK)
+}
+
+// Una cuenta creada con el inicio de sesión social no tiene contraseña y puede elegir la primera
+func TestPutUserPasswordSocialAccount(t *testing.T) {
+	api := newTestAPI(t)
+	user := models.User{Username: "ana", Email: "ana@example.com"}
+	if err := api.memory.Users.Create(&user); err != nil {
+		t.Fatal(err)
+	}
+	token, _ := api.startSession(user)
+	expectError(t, api.do("POST", "/login", "", models.LoginRequest{Email: user.Email, Password: "primera-clave"}), http.StatusBadRequest, CodeSocialLoginRequired)
+
+	rec := api.do("PUT", "/users/config/password", token, models.UpdatePasswordRequest{NewPassword: "primera-clave"})
+	expectStatus(t, rec, http.StatusOK)
+
+	expectStatus(t, api.do("POST", "/login", "", models.LoginRequest{Email: user.Email, Password: "primera-clave"}), http.StatusOK)
+	// Desde ahora se pide la contraseña actual
+	var tokens tokenResponse
+	decode(t, rec, &tokens)
+	rec = api.do("PUT", "/users/config/password", tokens.Token, models.UpdatePasswordRequest{NewPassword: "segunda-clave"})
+	expectError(t, rec, http.StatusUnauthorized, CodeInvalidCredentials)
+}
@@ -0,0 +1,108 @@
+package routes
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/danilsgit/gym-stats-backend/mailer"
+	"github.com/danilsgit/gym-stats-backend/models"
+	"github.com/danilsgit/gym-stats-backend/store"
+)
+
+// Verificación del correo
+
+// emailVerificationTTL es lo que dura el enlace para verificar el correo
+const emailVerificationTTL = 48 * time.Hour
+
+// Verificar el correo con el token recibido al registrarse
+func (s *Server) VerifyEmailHandler(w http.ResponseWriter, r *http.Request) {
+	var req models.VerifyEmailRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Token == "" {
+		writeError(w, r, http.StatusBadRequest, CodeValidationFailed, "El campo token es obligatorio")
+		return
+	}
+
+	token, err := s.Tokens.ConsumeUserToken(models.TokenEmailVerification, hashToken(req.Token))
+	if errors.Is(err, store.ErrNotFound) {
+		writeError(w, r, http.StatusBadRequest, CodeInvalidLink, "El enlace no es válido o ya expiró")
+		return
+	}
+	if err != nil {
+		writeError(w, r, http.StatusInternalServerError, CodeInternal, "Error al comprobar el enlace")
+		return
+	}
+
+	if err := s.Users.MarkEmailVerified(token.UserID); err != nil {
+		writeError(w, r, http.StatusInternalServerError, CodeInternal, "Error al verificar el correo")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode("Correo verificado con éxito")
+}
+
+// Reenviar el correo de verificación al usuario autenticado
+func (s *Server) ResendVerificationHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := currentUserID(r)
+	if !ok {
+		writeError(w, r, http.StatusInternalServerError, CodeInternal, "No se encontró el ID del usuario en la solicitud")
+		return
+	}
+
+	user, err := s.Users.FindByID(userID)
+	if err != nil {
+		writeError(w, r, http.StatusNotFound, CodeUserNotFound, "Usuario no encontrado")
+		return
+	}
+	if user.EmailVerified {
+		writeError(w, r, http.StatusBadRequest, CodeEmailAlreadyVerified, "El correo ya está verificado")
+		return
+	}
+
+	if !s.allowEmail(w, r, user.Email, models.TokenEmailVerification) {
+		return
+	}
+
+	if err := s.sendEmailVerification(user); err != nil {
+		writeError(w, r, http.StatusInternalServerError, CodeInternal, "Error al enviar el correo de verificación")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode("Te enviamos un nuevo enlace de verificación")
+}
+
+// sendEmailVerification invalida los enlaces anteriores del usuario y le envía uno nuevo
+func (s *Server) sendEmailVerification(user models.User) error {
+	token, err := newRandomToken()
+	if err != nil {
+		return err
+	}
+	if err := s.Tokens.InvalidateUserTokens(user.ID, models.TokenEmailVerification); err != nil {
+		return err
+	}
+	if err := s.Tokens.CreateUserToken(&models.UserToken{
+		UserID:    user.ID,
+		Purpose:   models.TokenEmailVerification,
+		TokenHash: hashToken(token),
+		ExpiresAt: time.Now().Add(emailVerificationTTL),
+	}); err != nil {
+		return err
+	}
+
+	return s.Mailer.Send(mailer.Message{
+		To:      user.Email,
+		Subject: "Verifica tu correo",
+		Body: "Hola " + user.Username + ",\n\n" +
+			"Para verificar tu correo usa este enlace (válido por 48 horas):\n\n" +
+			appLink("/verify-email", token) + "\n\n" +
+			"Si no creaste una cuenta, ignora este correo.",
+	})
+}
+
+// canPublish indica si la política de verificación permite que el usuario publique rutinas
+func (s *Server) canPublish(user models.User) bool {
+	return !s.RequireVerifiedEmail || user.EmailVerified
+}
@@ -0,0 +1,122 @@
+package routes
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/danilsgit/gym-stats-backend/models"
+)
+
+// Al registrarse se envía el enlace de verificación; sirve una sola vez
+func TestEmailVerification(t *testing.T) {
+	api := newTestAPI(t)
+	mail := api.recordMail()
+
+	expectStatus(t, api.do("POST", "/users", "", models.RegisterRequest{Username: "ana", Email: "ana@example.com", Password: "secreto123"}), http.StatusCreated)
+	link := mail.lastToken(t, "ana@example.com")
+
+	// Un enlace de verificación no restablece la contraseña
+	expectError(t, api.do("POST", "/auth/password/reset", "", models.ResetPasswordRequest{Token: link, Password: "nueva-clave"}), http.StatusBadRequest, CodeInvalidLink)
+
+	expectStatus(t, api.do("POST", "/auth/email/verify", "", models.VerifyEmailRequest{Token: link}), http.StatusOK)
+	expectError(t, api.do("POST", "/auth/email/verify", "", models.VerifyEmailRequest{Token: link}), http.StatusBadRequest, CodeInvalidLink)
+	expectError(t, api.do("POST", "/auth/email/verify", "", nil), http.StatusBadRequest, CodeValidationFailed)
+
+	user, err := api.memory.Users.FindByEmail("ana@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !user.EmailVerified {
+		t.Fatal("el correo no quedó verificado")
+	}
+	token, _ := api.startSession(user)
+	expectError(t, api.do("POST", "/auth/email/resend", token, nil), http.StatusBadRequest, CodeEmailAlreadyVerified)
+}
+
+// Reenviar la verificación invalida el enlace anterior
+func TestResendVerification(t *testing.T) {
+	api := newTestAPI(t)
+	mail := api.recordMail()
+	user, token := api.createUser("ana")
+
+	expectStatus(t, api.do("POST", "/auth/email/resend", token, nil), http.StatusOK)
+	first := mail.lastToken(t, user.Email)
+	expectStatus(t, api.do("POST", "/auth/email/resend", token, nil), http.StatusOK)
+	second := mail.lastToken(t, user.Email)
+
+	expectError(t, api.do("POST", "/auth/email/verify", "", models.VerifyEmailRequest{Token: first}), http.StatusBadRequest, CodeInvalidLink)
+	expectStatus(t, api.do("POST", "/auth/email/verify", "", models.VerifyEmailRequest{Token: second}), http.StatusOK)
+
+	expectError(t, api.do("POST", "/auth/email/resend", "", nil), http.StatusForbidden, CodeMissingToken)
+}
+
+func TestVerifyEmailExpiredLink(t *testing.T) {
+	api := newTestAPI(t)
+	user, _ := api.createUser("ana")
+	if err := api.memory.Tokens.CreateUserToken(&models.UserToken{
+		UserID:    user.ID,
+		Purpose:   models.TokenEmailVerification,
+		TokenHash: hashToken("enlace-vencido"),
+		ExpiresAt: time.Now().Add(-time.Minute),
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	expectError(t, api.do("POST", "/auth/email/verify", "", models.VerifyEmailRequest{Token: "enlace-vencido"}), http.StatusBadRequest, CodeInvalidLink)
+}
+
+// El reenvío tiene su propio límite: agotarlo no impide pedir el enlace de la contraseña
+func TestResendVerificationRateLimit(t *testing.T) {
+	api := newTestAPI(t)
+	mail := api.recordMail()
+	user, token := api.createUser("ana")
+
+	for i := 0; i < api.server.EmailLimit; i++ {
+		expectStatus(t, api.do("POST", "/auth/email/resend", token, nil), http.StatusOK)
+	}
+	expectError(t, api.do("POST", "/auth/email/resend", token, nil), http.StatusTooManyRequests, CodeRateLimited)
+	if sent := mail.sent(user.Email); sent != api.server.EmailLimit {
+		t.Fatalf("se enviaron %d correos, se esperaban %d", sent, api.server.EmailLimit)
+	}
+
+	expectStatus(t, api.do("POST", "/auth/password/forgot", "", models.ForgotPasswordRequest{Email: user.Email}), http.StatusOK)
+}
+
+// Con REQUIRE_VERIFIED_EMAIL_TO_PUBLISH las rutinas de un correo sin verificar quedan privadas
+func TestPublishRequiresVerifiedEmail(t *testing.T) {
+	api := newTestAPI(t)
+	mail := api.recordMail()
+	api.server.RequireVerifiedEmail = true
+	user, token := api.createUser("ana")
+
+	rec := api.do("POST", "/users/routines", token, models.RoutineRequest{Name: "Piernas", Public: true})
+	expectStatus(t, rec, http.StatusCreated)
+	var routine models.Routine
+	decode(t, rec, &routine)
+	if routine.Public {
+		t.Fatal("la rutina de un correo sin verificar se creó pública")
+	}
+	expectError(t, api.do("PUT", "/users/routines/public", token, models.UpdatePublicRoutineRequest{ID: routine.ID, Public: true}), http.StatusForbidden, CodeEmailNotVerified)
+	// Hacerla privada siempre se puede
+	expectStatus(t, api.do("PUT", "/users/routines/public", token, models.UpdatePublicRoutineRequest{ID: routine.ID, Public: false}), http.StatusOK)
+
+	expectStatus(t, api.do("POST", "/auth/email/resend", token, nil), http.StatusOK)
+	expectStatus(t, api.do("POST", "/auth/email/verify", "", models.VerifyEmailRequest{Token: mail.lastToken(t, user.Email)}), http.StatusOK)
+	expectStatus(t, api.do("PUT", "/users/routines/public", token, models.UpdatePublicRoutineRequest{ID: routine.ID, Public: true}), http.StatusOK)
+}
+
+// Sin la política se publica sin verificar el correo
+func TestPublishWithoutVerificationPolicy(t *testing.T) {
+	api := newTestAPI(t)
+	_, token := api.createUser("ana")
+
+	rec := api.do("POST", "/users/routines", token, models.RoutineRequest{Name: "Piernas", Public: true})
+	expectStatus(t, rec, http.StatusCreated)
+	var routine models.Routine
+	decode(t, rec, &routine)
+	if !routine.Public {
+		t.Fatal("la rutina se creó privada")
+	}
+	expectStatus(t, api.do("PUT", "/users/routines/public", token, models.UpdatePublicRoutineRequest{ID: routine.ID, Public: true}), http.StatusOK)
+}
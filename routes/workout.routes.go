@@ -0,0 +1,230 @@
+package routes
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/danilsgit/gym-stats-backend/models"
+)
+
+// Sesiones de entrenamiento del usuario
+
+// Listar las sesiones de entrenamiento del usuario
+func (s *Server) GetUserWorkoutsHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := currentUserID(r)
+	if !ok {
+		writeError(w, r, http.StatusInternalServerError, CodeInternal, "No se encontró el ID del usuario en la solicitud")
+		return
+	}
+
+	workouts, err := s.Workouts.ListByUser(userID)
+	if err != nil {
+		writeError(w, r, http.StatusInternalServerError, CodeInternal, "Error al obtener las sesiones de entrenamiento")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(workouts)
+}
+
+// Obtener una sesión de entrenamiento del usuario
+func (s *Server) GetUserWorkoutHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := currentUserID(r)
+	if !ok {
+		writeError(w, r, http.StatusInternalServerError, CodeInternal, "No se encontró el ID del usuario en la solicitud")
+		return
+	}
+
+	workout, err := s.findOwnedWorkout(userID, pathID(r, "id"))
+	if err != nil {
+		writeOwnershipError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(workout)
+}
+
+// Iniciar una sesión de entrenamiento a partir de una rutina propia o pública
+func (s *Server) StartWorkoutHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := currentUserID(r)
+	if !ok {
+		writeError(w, r, http.StatusInternalServerError, CodeInternal, "No se encontró el ID del usuario en la solicitud")
+		return
+	}
+
+	var req models.StartWorkoutRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, r, http.StatusBadRequest, CodeInvalidBody, "Error al decodificar la solicitud")
+		return
+	}
+
+	// La rutina debe ser del usuario o pública. Las rutinas ocultas por moderación no existen
+	// para los demás usuarios.
+	routine, err := s.findOwnedRoutine(userID, req.RoutineID)
+	if err == errRoutineForbidden && routine.Hidden {
+		err = errRoutineNotFound
+	} else if err == errRoutineForbidden && routine.Public {
+		err = nil
+	}
+	if err != nil {
+		writeOwnershipError(w, r, err)
+		return
+	}
+
+	workout := models.WorkoutSession{
+		UserID:      userID,
+		RoutineID:   routine.ID,
+		RoutineName: routine.Name,
+		StartedAt:   time.Now(),
+		Notes:       req.Notes,
+	}
+	if err := s.Workouts.Create(&workout); err != nil {
+		writeError(w, r, http.StatusInternalServerError, CodeInternal, "Error al iniciar la sesión de entrenamiento")
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(workout)
+}
+
+// Registrar un set realizado en una sesión sin finalizar
+func (s *Server) LogWorkoutSetHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := currentUserID(r)
+	if !ok {
+		writeError(w, r, http.StatusInternalServerError, CodeInternal, "No se encontró el ID del usuario en la solicitud")
+		return
+	}
+
+	workout, err := s.findOwnedWorkout(userID, pathID(r, "id"))
+	if err != nil {
+		writeOwnershipError(w, r, err)
+		return
+	}
+	if workout.FinishedAt != nil {
+		writeError(w, r, http.StatusBadRequest, CodeWorkoutFinished, "La sesión de entrenamiento ya fue finalizada")
+		return
+	}
+
+	var req models.LogWorkoutSetRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, r, http.StatusBadRequest, CodeInvalidBody, "Error al decodificar la solicitud")
+		return
+	}
+	if req.Reps <= 0 || req.Weight < 0 || req.Rest < 0 {
+		writeError(w, r, http.StatusBadRequest, CodeValidationFailed, "Las repeticiones deben ser mayores a cero y el peso y el descanso no pueden ser negativos")
+		return
+	}
+
+	// El ejercicio debe pertenecer a la rutina de la sesión
+	exercise, err := s.Exercises.FindInRoutine(workout.RoutineID, req.ExerciseID)
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, CodeValidationFailed, "El ejercicio no pertenece a la rutina de la sesión")
+		return
+	}
+
+	workoutSet := models.WorkoutSet{
+		WorkoutSessionID: workout.ID,
+		ExerciseID:       exercise.ID,
+		ExerciseName:     exercise.Name,
+		Reps:             req.Reps,
+		Weight:           req.Weight,
+		Rest:             req.Rest,
+		Note:             req.Note,
+		PerformedAt:      time.Now(),
+	}
+
+	// El set planificado, si se envía, debe ser del mismo ejercicio
+	if req.SetID != 0 {
+		for i := range exercise.Sets {
+			if exercise.Sets[i].ID == req.SetID {
+				workoutSet.SetID = &exercise.Sets[i].ID
+				break
+			}
+		}
+		if workoutSet.SetID == nil {
+			writeError(w, r, http.StatusBadRequest, CodeValidationFailed, "El set no pertenece al ejercicio")
+			return
+		}
+	}
+
+	// Guardar el set y los récords personales que supere
+	if err := s.Workouts.LogSet(workout.UserID, &workoutSet); err != nil {
+		writeError(w, r, http.StatusInternalServerError, CodeInternal, "Error al registrar el set")
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(workoutSet)
+}
+
+// Finalizar una sesión de entrenamiento con su duración y notas
+func (s *Server) FinishWorkoutHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := currentUserID(r)
+	if !ok {
+		writeError(w, r, http.StatusInternalServerError, CodeInternal, "No se encontró el ID del usuario en la solicitud")
+		return
+	}
+
+	workout, err := s.findOwnedWorkout(userID, pathID(r, "id"))
+	if err != nil {
+		writeOwnershipError(w, r, err)
+		return
+	}
+	if workout.FinishedAt != nil {
+		writeError(w, r, http.StatusBadRequest, CodeWorkoutFinished, "La sesión de entrenamiento ya fue finalizada")
+		return
+	}
+
+	var req models.FinishWorkoutRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, r, http.StatusBadRequest, CodeInvalidBody, "Error al decodificar la solicitud")
+		return
+	}
+	if req.DurationSeconds < 0 {
+		writeError(w, r, http.StatusBadRequest, CodeValidationFailed, "La duración no puede ser negativa")
+		return
+	}
+
+	finishedAt := time.Now()
+	workout.FinishedAt = &finishedAt
+	workout.DurationSeconds = req.DurationSeconds
+	if workout.DurationSeconds == 0 {
+		workout.DurationSeconds = int(finishedAt.Sub(workout.StartedAt).Seconds())
+	}
+	if req.Notes != "" {
+		workout.Notes = req.Notes
+	}
+
+	if err := s.Workouts.Finish(&workout); err != nil {
+		writeError(w, r, http.StatusInternalServerError, CodeInternal, "Error al finalizar la sesión de entrenamiento")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(workout)
+}
+
+// Eliminar una sesión de entrenamiento, sus sets y los récords que marcó
+func (s *Server) DeleteUserWorkoutHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := currentUserID(r)
+	if !ok {
+		writeError(w, r, http.StatusInternalServerError, CodeInternal, "No se encontró el ID del usuario en la solicitud")
+		return
+	}
+
+	workout, err := s.findOwnedWorkout(userID, pathID(r, "id"))
+	if err != nil {
+		writeOwnershipError(w, r, err)
+		return
+	}
+
+	// Al borrar los récords de la sesión vuelven a quedar vigentes los anteriores
+	if err := s.Workouts.Delete(workout.ID); err != nil {
+		writeError(w, r, http.StatusInternalServerError, CodeInternal, "Error al eliminar la sesión de entrenamiento")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
@@ -0,0 +1,140 @@
+package routes
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/danilsgit/gym-stats-backend/models"
+	"github.com/danilsgit/gym-stats-backend/records"
+)
+
+func TestWorkoutLifecycle(t *testing.T) {
+	api := newTestAPI(t)
+	user, token := api.createUser("ana")
+	routine := api.createRoutine(user.ID, "Piernas", false)
+	exercise := routine.Exercises[0]
+
+	rec := api.do("POST", "/users/workouts", token, models.StartWorkoutRequest{RoutineID: routine.ID})
+	expectStatus(t, rec, http.StatusCreated)
+	var workout models.WorkoutSession
+	decode(t, rec, &workout)
+	if workout.RoutineName != "Piernas" || workout.UserID != user.ID {
+		t.Fatalf("sesión inesperada: %+v", workout)
+	}
+	path := fmt.Sprintf("/users/workouts/%d", workout.ID)
+
+	// El primer set marca récords; el segundo, más liviano, ninguno de peso
+	rec = api.do("POST", path+"/sets", token, models.LogWorkoutSetRequest{ExerciseID: exercise.ID, SetID: exercise.Sets[0].ID, Reps: 5, Weight: 100})
+	expectStatus(t, rec, http.StatusCreated)
+	var first models.WorkoutSet
+	decode(t, rec, &first)
+	if first.SetID == nil || *first.SetID != exercise.Sets[0].ID || len(first.Records) == 0 {
+		t.Fatalf("primer set inesperado: %+v", first)
+	}
+
+	rec = api.do("POST", path+"/sets", token, models.LogWorkoutSetRequest{ExerciseID: exercise.ID, Reps: 5, Weight: 80})
+	expectStatus(t, rec, http.StatusCreated)
+	var second models.WorkoutSet
+	decode(t, rec, &second)
+	for _, record := range second.Records {
+		if record.Type == records.TypeWeight {
+			t.Fatalf("un set más liviano no es récord de peso: %+v", record)
+		}
+	}
+
+	rec = api.do("PUT", path+"/finish", token, models.FinishWorkoutRequest{DurationSeconds: 3600, Notes: "Bien"})
+	expectStatus(t, rec, http.StatusOK)
+
+	rec = api.do("GET", path, token, nil)
+	expectStatus(t, rec, http.StatusOK)
+	decode(t, rec, &workout)
+	if workout.FinishedAt == nil || workout.DurationSeconds != 3600 || workout.Notes != "Bien" || len(workout.Sets) != 2 {
+		t.Fatalf("sesión finalizada inesperada: %+v", workout)
+	}
+
+	// No se pueden registrar sets en una sesión finalizada
+	rec = api.do("POST", path+"/sets", token, models.LogWorkoutSetRequest{ExerciseID: exercise.ID, Reps: 5, Weight: 100})
+	expectStatus(t, rec, http.StatusBadRequest)
+
+	rec = api.do("GET", "/users/workouts", token, nil)
+	expectStatus(t, rec, http.StatusOK)
+	var workouts []models.WorkoutSession
+	decode(t, rec, &workouts)
+	if len(workouts) != 1 {
+		t.Fatalf("se esperaba 1 sesión, hay %d", len(workouts))
+	}
+
+	rec = api.do("DELETE", path, token, nil)
+	expectStatus(t, rec, http.StatusOK)
+	expectStatus(t, api.do("GET", path, token, nil), http.StatusNotFound)
+
+	// Los récords de la sesión eliminada desaparecen
+	rec = api.do("GET", "/users/records?history=true", token, nil)
+	expectStatus(t, rec, http.StatusOK)
+	var history []models.PersonalRecord
+	decode(t, rec, &history)
+	if len(history) != 0 {
+		t.Fatalf("quedaron %d récords de la sesión eliminada", len(history))
+	}
+}
+
+func TestLogWorkoutSetValidatesExerciseAndSet(t *testing.T) {
+	api := newTestAPI(t)
+	user, token := api.createUser("ana")
+	routine := api.createRoutine(user.ID, "Piernas", false)
+	other := api.createRoutine(user.ID, "Empuje", false)
+
+	rec := api.do("POST", "/users/workouts", token, models.StartWorkoutRequest{RoutineID: routine.ID})
+	expectStatus(t, rec, http.StatusCreated)
+	var workout models.WorkoutSession
+	decode(t, rec, &workout)
+	path := fmt.Sprintf("/users/workouts/%d/sets", workout.ID)
+
+	tests := []struct {
+		name string
+		req  models.LogWorkoutSetRequest
+	}{
+		{"ejercicio de otra rutina", models.LogWorkoutSetRequest{ExerciseID: other.Exercises[0].ID, Reps: 5, Weight: 100}},
+		{"set de otro ejercicio", models.LogWorkoutSetRequest{ExerciseID: routine.Exercises[0].ID, SetID: other.Exercises[0].Sets[0].ID, Reps: 5, Weight: 100}},
+		{"sin repeticiones", models.LogWorkoutSetRequest{ExerciseID: routine.Exercises[0].ID, Weight: 100}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expectStatus(t, api.do("POST", path, token, tt.req), http.StatusBadRequest)
+		})
+	}
+}
+
+func TestGetUserRecordsCurrentAndByExercise(t *testing.T) {
+	api := newTestAPI(t)
+	user, token := api.createUser("ana")
+	routine := api.createRoutine(user.ID, "Piernas", false)
+	exercise := routine.Exercises[0]
+
+	rec := api.do("POST", "/users/workouts", token, models.StartWorkoutRequest{RoutineID: routine.ID})
+	var workout models.WorkoutSession
+	decode(t, rec, &workout)
+	for _, weight := range []float64{100, 110} {
+		rec = api.do("POST", fmt.Sprintf("/users/workouts/%d/sets", workout.ID), token, models.LogWorkoutSetRequest{ExerciseID: exercise.ID, Reps: 5, Weight: weight})
+		expectStatus(t, rec, http.StatusCreated)
+	}
+
+	rec = api.do("GET", fmt.Sprintf("/users/records?exerciseId=%d", exercise.ID), token, nil)
+	expectStatus(t, rec, http.StatusOK)
+	var current []models.PersonalRecord
+	decode(t, rec, &current)
+	for _, record := range current {
+		if record.Type == records.TypeWeight && record.Value != 110 {
+			t.Fatalf("el récord de peso vigente es %v, se esperaba 110", record.Value)
+		}
+	}
+
+	rec = api.do("GET", "/users/records?exerciseId=999", token, nil)
+	decode(t, rec, &current)
+	if len(current) != 0 {
+		t.Fatalf("se esperaban 0 récords de otro ejercicio, hay %d", len(current))
+	}
+
+	expectStatus(t, api.do("GET", "/users/records?exerciseId=x", token, nil), http.StatusBadRequest)
+}
@@ -0,0 +1,161 @@
+package store
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/danilsgit/gym-stats-backend/models"
+)
+
+// Órdenes de los listados de rutinas
+const (
+	SortRelevance = "relevance" // Las más relevantes para la búsqueda (solo con search)
+	SortNewest    = "newest"
+	SortCopied    = "copied" // Las más copiadas
+	SortLiked     = "liked"  // Las que tienen más me gusta
+	SortName      = "name"   // Alfabético
+)
+
+var (
+	ErrInvalidSort   = errors.New("orden no válido")
+	ErrInvalidCursor = errors.New("cursor no válido")
+)
+
+// RoutineQuery filtra, ordena y pagina un listado de rutinas
+type RoutineQuery struct {
+	Search string
+	// UserID limita el listado a las rutinas del usuario
+	UserID string
+	// PublicOnly deja solo las rutinas públicas y no ocultas que tienen ejercicios y autor
+	PublicOnly bool
+	// VisibleOnly deja fuera las rutinas ocultas por moderación (PublicOnly ya lo incluye)
+	VisibleOnly bool
+	Filters     RoutineFilters
+	// Sort es uno de los Sort*; vacío ordena por relevancia si hay búsqueda o por las más nuevas si no
+	Sort  string
+	Limit int
+	// Cursor pide la página siguiente a la que lo devolvió; nil para la primera
+	Cursor *Cursor
+	// Offset solo se usa sin cursor, para los clientes que aún paginan por offset
+	Offset int
+}
+
+// RoutinePage es una página de un listado de rutinas
+type RoutinePage struct {
+	Routines []models.Routine
+	Total    int64   // Rutinas que cumplen el filtro, en todas las páginas
+	Next     *Cursor // nil si no hay más páginas
+}
+
+// Cursor es la posición de la última rutina de una página según el orden del listado. Los
+// clientes lo reciben codificado (Encode) y lo devuelven tal cual.
+type Cursor struct {
+	Sort  string     `json:"s"`
+	Time  *time.Time `json:"t,omitempty"`
+	Count int64      `json:"c,omitempty"`
+	Rank  float64    `json:"r,omitempty"`
+	Name  string     `json:"n,omitempty"`
+	ID    uint       `json:"i"`
+}
+
+// Encode devuelve el cursor como texto opaco para la URL
+func (c Cursor) Encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// DecodeCursor lee un cursor devuelto por Encode
+func DecodeCursor(value string) (*Cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var cursor Cursor
+	if err := json.Unmarshal(data, &cursor); err != nil || cursor.ID == 0 || !validSort(cursor.Sort) {
+		return nil, ErrInvalidCursor
+	}
+	if (cursor.Sort == SortNewest) != (cursor.Time != nil) {
+		return nil, ErrInvalidCursor
+	}
+	return &cursor, nil
+}
+
+func validSort(sort string) bool {
+	switch sort {
+	case SortRelevance, SortNewest, SortCopied, SortLiked, SortName:
+		return true
+	}
+	return false
+}
+
+// pageLimit es cuántas filas se piden: una más que el límite para saber si hay otra página
+func pageLimit(limit int) int {
+	if limit <= 0 {
+		return -1 // Sin límite
+	}
+	return limit + 1
+}
+
+// resolveSort valida el orden pedido (o elige el predeterminado) y que el cursor sea de ese orden
+func (q RoutineQuery) resolveSort(hasSearch bool) (string, error) {
+	sort := q.Sort
+	if sort == "" {
+		sort = SortNewest
+		if hasSearch {
+			sort = SortRelevance
+		}
+	}
+	if !validSort(sort) || (sort == SortRelevance && !hasSearch) {
+		return "", ErrInvalidSort
+	}
+	if q.Cursor != nil && q.Cursor.Sort != sort {
+		return "", ErrInvalidCursor
+	}
+	return sort, nil
+}
+
+// routineCursor devuelve la posición de la rutina en el orden dado
+func routineCursor(sort string, routine models.Routine) Cursor {
+	cursor := Cursor{Sort: sort, ID: routine.ID}
+	switch sort {
+	case SortRelevance:
+		cursor.Rank = routine.SearchRank
+	case SortNewest:
+		createdAt := routine.CreatedAt
+		cursor.Time = &createdAt
+	case SortCopied:
+		cursor.Count = routine.ForkCount
+	case SortLiked:
+		cursor.Count = routine.LikeCount
+	case SortName:
+		cursor.Name = routine.Name
+	}
+	return cursor
+}
+
+// cursorBefore indica si a va antes que b en el orden del listado. El ID desempata para que el
+// orden sea estable: en los órdenes descendentes también va de mayor a menor.
+func cursorBefore(a Cursor, b Cursor) bool {
+	switch a.Sort {
+	case SortRelevance:
+		if a.Rank != b.Rank {
+			return a.Rank > b.Rank
+		}
+	case SortNewest:
+		if !a.Time.Equal(*b.Time) {
+			return a.Time.After(*b.Time)
+		}
+	case SortCopied, SortLiked:
+		if a.Count != b.Count {
+			return a.Count > b.Count
+		}
+	case SortName:
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+		return a.ID < b.ID
+	}
+	return a.ID > b.ID
+}
@@ -0,0 +1,106 @@
+package store
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"github.com/danilsgit/gym-stats-backend/models"
+)
+
+// RoutineFilters son los filtros de la búsqueda por facetas; los campos vacíos o en cero no filtran
+type RoutineFilters struct {
+	MinExercises int
+	MaxExercises int
+	// Muscles deja las rutinas que trabajan todos los músculos indicados (principales o secundarios,
+	// igual que el filtro del catálogo)
+	Muscles []string
+	// Equipment deja las rutinas que se pueden hacer solo con el equipamiento indicado; los
+	// ejercicios que no están vinculados al catálogo no cuentan
+	Equipment []string
+	// MinDuration y MaxDuration son la duración estimada en minutos (ver estimatedMinutes)
+	MinDuration int
+	MaxDuration int
+	// Author es el username del autor
+	Author       string
+	CreatedAfter *time.Time
+}
+
+// secondsPerRep es el tiempo que se estima por repetición para calcular la duración de una rutina
+const secondsPerRep = 3
+
+// FacetCount es la cantidad de rutinas que tienen un valor (un músculo, un equipamiento)
+type FacetCount struct {
+	Value string `json:"value"`
+	Count int64  `json:"count"`
+}
+
+// FacetRange es la cantidad de rutinas en un rango; Min y Max se incluyen y Max es nil en el último
+type FacetRange struct {
+	Min   int   `json:"min"`
+	Max   *int  `json:"max,omitempty"`
+	Count int64 `json:"count"`
+}
+
+// RoutineFacets son los conteos de un listado de rutinas para mostrar los filtros. Se calculan
+// con todos los filtros aplicados, igual que el total del listado.
+type RoutineFacets struct {
+	Muscles   []FacetCount `json:"muscles"`
+	Equipment []FacetCount `json:"equipment"`
+	Exercises []FacetRange `json:"exercises"` // Cantidad de ejercicios
+	Duration  []FacetRange `json:"duration"`  // Duración estimada en minutos
+}
+
+// Rangos de las facetas de cantidad de ejercicios y de duración; el último no tiene máximo
+var (
+	exerciseBuckets = []int{1, 4, 7, 10}
+	durationBuckets = []int{0, 30, 60, 90}
+)
+
+// estimatedMinutes estima la duración de la rutina: secondsPerRep por repetición más el descanso
+// (en segundos) de cada set, redondeado a minutos
+func estimatedMinutes(routine models.Routine) int {
+	var seconds float64
+	for _, exercise := range routine.Exercises {
+		for _, set := range exercise.Sets {
+			seconds += float64(set.Reps*secondsPerRep) + set.Rest
+		}
+	}
+	return int(math.Round(seconds / 60))
+}
+
+// facetRanges cuenta los valores que caen en cada rango que empieza en bounds[i]
+func facetRanges(bounds []int, values []int) []FacetRange {
+	ranges := make([]FacetRange, len(bounds))
+	for i, min := range bounds {
+		ranges[i].Min = min
+		if i+1 < len(bounds) {
+			max := bounds[i+1] - 1
+			ranges[i].Max = &max
+		}
+	}
+	for _, value := range values {
+		for i := len(bounds) - 1; i >= 0; i-- {
+			if value >= bounds[i] {
+				ranges[i].Count++
+				break
+			}
+		}
+	}
+	return ranges
+}
+
+// facetCounts ordena los conteos de mayor a menor y, si empatan, por valor
+func facetCounts(counts map[string]int64) []FacetCount {
+	facets := make([]FacetCount, 0, len(counts))
+	for value, count := range counts {
+		facets = append(facets, FacetCount{Value: value, Count: count})
+	}
+	sort.Slice(facets, func(i, j int) bool {
+		if facets[i].Count != facets[j].Count {
+			return facets[i].Count > facets[j].Count
+		}
+		return facets[i].Value < facets[j].Value
+	})
+	return facets
+}
@@ -0,0 +1,1028 @@
+package store
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/danilsgit/gym-stats-backend/analytics"
+	"github.com/danilsgit/gym-stats-backend/models"
+	"github.com/danilsgit/gym-stats-backend/records"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// forkCountExpr calcula en la consulta cuántas veces se ha copiado cada rutina
+const forkCountExpr = "(SELECT COUNT(*) FROM routines forks WHERE forks.forked_from_id = routines.id AND forks.deleted_at IS NULL)"
+
+// likeCountExpr calcula en la consulta cuántos me gusta tiene cada rutina
+const likeCountExpr = "(SELECT COUNT(*) FROM routine_likes likes WHERE likes.routine_id = routines.id)"
+
+// forkCountSelect y likeCountSelect agregan ForkCount y LikeCount a las rutinas consultadas
+const (
+	forkCountSelect = forkCountExpr + " AS fork_count"
+	likeCountSelect = likeCountExpr + " AS like_count"
+)
+
+// notFound traduce el error de GORM a ErrNotFound
+func notFound(err error) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrNotFound
+	}
+	return err
+}
+
+// duplicate convierte la violación de una restricción única de Postgres (SQLSTATE 23505) en ErrDuplicate
+func duplicate(err error) error {
+	var pgErr interface{ SQLState() string }
+	if errors.As(err, &pgErr) && pgErr.SQLState() == "23505" {
+		return ErrDuplicate
+	}
+	return err
+}
+
+// GormUserStore implementa UserStore sobre GORM
+type GormUserStore struct {
+	DB *gorm.DB
+}
+
+func (s *GormUserStore) FindByID(id string) (models.User, error) {
+	var user models.User
+	err := s.DB.First(&user, "id = ?", id).Error
+	return user, notFound(err)
+}
+
+func (s *GormUserStore) FindByEmail(email string) (models.User, error) {
+	var user models.User
+	err := s.DB.Where("email = ?", email).First(&user).Error
+	return user, notFound(err)
+}
+
+func (s *GormUserStore) FindByUsername(username string) (models.User, error) {
+	var user models.User
+	err := s.DB.Where("username = ?", username).First(&user).Error
+	return user, notFound(err)
+}
+
+func (s *GormUserStore) Create(user *models.User) error {
+	return s.DB.Create(user).Error
+}
+
+func (s *GormUserStore) UpdateUsername(id string, username string) error {
+	return s.DB.Model(&models.User{}).Where("id = ?", id).Update("username", username).Error
+}
+
+func (s *GormUserStore) UpdatePassword(id string, hashedPassword string) error {
+	return s.DB.Model(&models.User{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"password":      hashedPassword,
+		"token_version": gorm.Expr("token_version + 1"),
+	}).Error
+}
+
+func (s *GormUserStore) MarkEmailVerified(id string) error {
+	return s.DB.Model(&models.User{}).Where("id = ?", id).Update("email_verified", true).Error
+}
+
+func (s *GormUserStore) List(search string, limit int, offset int) ([]models.User, int64, error) {
+	query := s.DB.Model(&models.User{})
+	if search != "" {
+		query = query.Where("username ILIKE ? OR email ILIKE ?", "%"+search+"%", "%"+search+"%")
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var users []models.User
+	if err := query.Order("created_at DESC").Limit(limit).Offset(offset).Find(&users).Error; err != nil {
+		return nil, 0, err
+	}
+	return users, total, nil
+}
+
+func (s *GormUserStore) SetDisabled(id string, disabled bool) error {
+	result := s.DB.Model(&models.User{}).Where("id = ?", id).Update("disabled", disabled)
+	if result.Error == nil && result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return result.Error
+}
+
+func (s *GormUserStore) FindIdentity(provider string, subject string) (models.UserIdentity, error) {
+	var identity models.UserIdentity
+	err := s.DB.Where("provider = ? AND subject = ?", provider, subject).First(&identity).Error
+	return identity, notFound(err)
+}
+
+func (s *GormUserStore) CreateIdentity(identity *models.UserIdentity) error {
+	return s.DB.Create(identity).Error
+}
+
+// GormRoutineStore implementa RoutineStore sobre GORM
+type GormRoutineStore struct {
+	DB *gorm.DB
+}
+
+func (s *GormRoutineStore) List(q RoutineQuery) (RoutinePage, error) {
+	tsquery := searchTSQuery(q.Search)
+	sort, err := q.resolveSort(tsquery != "")
+	if err != nil {
+		return RoutinePage{}, err
+	}
+
+	query := s.filter(q, tsquery)
+
+	// El total usa el mismo filtro que la página, sin el cursor
+	var page RoutinePage
+	if err := query.Count(&page.Total).Error; err != nil {
+		return RoutinePage{}, err
+	}
+
+	// Expresión por la que se ordena; el ID desempata
+	var key string
+	var keyVars []interface{}
+	var cursorValue interface{}
+	desc := true
+	switch sort {
+	case SortRelevance:
+		key, keyVars = "ts_rank_cd(routines.search_vector, "+routineTSQuery+")", []interface{}{tsquery, tsquery, tsquery}
+		if q.Cursor != nil {
+			cursorValue = q.Cursor.Rank
+		}
+	case SortNewest:
+		key = "routines.created_at"
+		if q.Cursor != nil {
+			cursorValue = *q.Cursor.Time
+		}
+	case SortCopied, SortLiked:
+		key = forkCountExpr
+		if sort == SortLiked {
+			key = likeCountExpr
+		}
+		if q.Cursor != nil {
+			cursorValue = q.Cursor.Count
+		}
+	case SortName:
+		key, desc = "routines.name", false
+		if q.Cursor != nil {
+			cursorValue = q.Cursor.Name
+		}
+	}
+	direction, after := "DESC", "<"
+	if !desc {
+		direction, after = "ASC", ">"
+	}
+
+	if q.Cursor != nil {
+		vars := append(append([]interface{}{}, keyVars...), cursorValue, q.Cursor.ID)
+		query = query.Where("("+key+", routines.id) "+after+" (?, ?)", vars...)
+	} else if q.Offset > 0 {
+		query = query.Offset(q.Offset)
+	}
+
+	// Obtener las rutinas junto con sus ejercicios y sets y los usuarios asociados. Se pide una
+	// más que el límite para saber si hay otra página.
+	selects, orderKey := "routines.*, "+forkCountSelect+", "+likeCountSelect, key
+	if sort == SortRelevance {
+		selects, orderKey = selects+", "+key+" AS search_rank", "search_rank"
+	}
+	var routines []models.Routine
+	if err := query.
+		Select(selects, keyVars...).
+		Preload("Exercises.Sets").
+		Preload("Exercises.Definition").
+		Preload("Users").
+		Order(orderKey + " " + direction + ", routines.id " + direction).
+		Limit(pageLimit(q.Limit)).
+		Find(&routines).Error; err != nil {
+		return RoutinePage{}, err
+	}
+
+	if q.Limit > 0 && len(routines) > q.Limit {
+		routines = routines[:q.Limit]
+		next := routineCursor(sort, routines[len(routines)-1])
+		page.Next = &next
+	}
+	page.Routines = routines
+	return page, nil
+}
+
+// filter aplica a las rutinas el filtro del listado, sin el orden ni el cursor
+func (s *GormRoutineStore) filter(q RoutineQuery, tsquery string) *gorm.DB {
+	query := s.DB.Model(&models.Routine{})
+	if q.UserID != "" {
+		query = query.Where("routines.id IN (SELECT routine_id FROM user_make_routine WHERE user_id = ?)", q.UserID)
+	}
+	if q.PublicOnly {
+		// Solo rutinas públicas y visibles que tengan ejercicios y autor
+		query = query.
+			Where("routines.public = ? AND routines.hidden = ?", true, false).
+			Where("EXISTS (SELECT 1 FROM routine_work_exercise rwe JOIN exercises e ON e.id = rwe.exercise_id AND e.deleted_at IS NULL WHERE rwe.routine_id = routines.id)").
+			Where("EXISTS (SELECT 1 FROM user_make_routine umr WHERE umr.routine_id = routines.id)")
+	} else if q.VisibleOnly {
+		query = query.Where("routines.hidden = ?", false)
+	}
+	// Búsqueda de texto completo sobre el nombre, los ejercicios, la descripción y el autor (ver la migración 0014)
+	if tsquery != "" {
+		query = query.Where("routines.search_vector @@ "+routineTSQuery, tsquery, tsquery, tsquery)
+	}
+
+	f := q.Filters
+	if f.MinExercises > 0 {
+		query = query.Where(exerciseCountExpr+" >= ?", f.MinExercises)
+	}
+	if f.MaxExercises > 0 {
+		query = query.Where(exerciseCountExpr+" <= ?", f.MaxExercises)
+	}
+	for _, muscle := range f.Muscles {
+		muscleJSON, _ := json.Marshal([]string{muscle})
+		query = query.Where(routineDefinitionsSQL+" AND (d.primary_muscles @> ?::jsonb OR d.secondary_muscles @> ?::jsonb))", string(muscleJSON), string(muscleJSON))
+	}
+	if len(f.Equipment) > 0 {
+		// Ningún ejercicio puede necesitar equipamiento fuera de la lista
+		equipmentJSON, _ := json.Marshal(f.Equipment)
+		query = query.Where("NOT "+routineDefinitionsSQL+" AND NOT ("+jsonbArray("d.equipment")+" <@ ?::jsonb))", string(equipmentJSON))
+	}
+	if f.MinDuration > 0 {
+		query = query.Where(durationMinutesExpr+" >= ?", f.MinDuration)
+	}
+	if f.MaxDuration > 0 {
+		query = query.Where(durationMinutesExpr+" <= ?", f.MaxDuration)
+	}
+	if f.Author != "" {
+		query = query.Where("routines.id IN (SELECT umr.routine_id FROM user_make_routine umr JOIN users u ON u.id = umr.user_id WHERE u.username = ?)", f.Author)
+	}
+	if f.CreatedAfter != nil {
+		query = query.Where("routines.created_at > ?", *f.CreatedAfter)
+	}
+	return query
+}
+
+// exerciseCountExpr calcula en la consulta cuántos ejercicios tiene cada rutina
+const exerciseCountExpr = "(SELECT COUNT(*) FROM routine_work_exercise rwe JOIN exercises e ON e.id = rwe.exercise_id AND e.deleted_at IS NULL WHERE rwe.routine_id = routines.id)"
+
+// durationMinutesExpr calcula en la consulta la duración estimada de cada rutina igual que
+// estimatedMinutes (3 segundos por repetición, secondsPerRep, más el descanso)
+const durationMinutesExpr = "(SELECT ROUND(COALESCE(SUM(sets.reps * 3 + sets.rest), 0)::numeric / 60)::int FROM routine_work_exercise rwe JOIN exercises e ON e.id = rwe.exercise_id AND e.deleted_at IS NULL JOIN sets ON sets.exercise_id = e.id AND sets.deleted_at IS NULL WHERE rwe.routine_id = routines.id)"
+
+// routineDefinitionsSQL abre un EXISTS sobre los ejercicios del catálogo de cada rutina (alias d);
+// quien lo usa agrega la condición y cierra el paréntesis
+const routineDefinitionsSQL = "EXISTS (SELECT 1 FROM routine_work_exercise rwe JOIN exercises e ON e.id = rwe.exercise_id AND e.deleted_at IS NULL JOIN exercise_definitions d ON d.id = e.definition_id WHERE rwe.routine_id = routines.id"
+
+// jsonbArray devuelve la columna jsonb o un arreglo vacío si es NULL o null, como guarda GORM los slices vacíos
+func jsonbArray(column string) string {
+	return "(CASE WHEN jsonb_typeof(" + column + ") = 'array' THEN " + column + " ELSE '[]'::jsonb END)"
+}
+
+func (s *GormRoutineStore) Facets(q RoutineQuery) (RoutineFacets, error) {
+	ids := s.filter(q, searchTSQuery(q.Search)).Select("routines.id")
+
+	// Músculos y equipamiento: rutinas distintas por cada valor de sus ejercicios del catálogo
+	valueCounts := func(values string) ([]FacetCount, error) {
+		var rows []FacetCount
+		err := s.DB.Raw(`SELECT v.value AS value, COUNT(DISTINCT rwe.routine_id) AS count
+			FROM routine_work_exercise rwe
+			JOIN exercises e ON e.id = rwe.exercise_id AND e.deleted_at IS NULL
+			JOIN exercise_definitions d ON d.id = e.definition_id
+			CROSS JOIN LATERAL jsonb_array_elements_text(`+values+`) AS v(value)
+			WHERE rwe.routine_id IN (?)
+			GROUP BY v.value`, ids).Scan(&rows).Error
+		if err != nil {
+			return nil, err
+		}
+		counts := map[string]int64{}
+		for _, row := range rows {
+			counts[row.Value] = row.Count
+		}
+		return facetCounts(counts), nil
+	}
+
+	var facets RoutineFacets
+	var err error
+	if facets.Muscles, err = valueCounts(jsonbArray("d.primary_muscles") + " || " + jsonbArray("d.secondary_muscles")); err != nil {
+		return RoutineFacets{}, err
+	}
+	if facets.Equipment, err = valueCounts(jsonbArray("d.equipment")); err != nil {
+		return RoutineFacets{}, err
+	}
+
+	// Cantidad de ejercicios y duración de cada rutina; los rangos se cuentan igual que en memoria
+	var sizes []struct {
+		Exercises int
+		Duration  int
+	}
+	if err := s.filter(q, searchTSQuery(q.Search)).
+		Select(exerciseCountExpr + " AS exercises, " + durationMinutesExpr + " AS duration").
+		Scan(&sizes).Error; err != nil {
+		return RoutineFacets{}, err
+	}
+	exercises := make([]int, len(sizes))
+	durations := make([]int, len(sizes))
+	for i, size := range sizes {
+		exercises[i], durations[i] = size.Exercises, size.Duration
+	}
+	facets.Exercises = facetRanges(exerciseBuckets, exercises)
+	facets.Duration = facetRanges(durationBuckets, durations)
+	return facets, nil
+}
+
+// routineTSQuery busca cada palabra como prefijo en español, en inglés y sin stemming (para los
+// usernames), igual que se indexa routines.search_vector. Recibe tres veces el texto de searchTSQuery.
+const routineTSQuery = "(to_tsquery('spanish', immutable_unaccent(?)) || to_tsquery('english', immutable_unaccent(?)) || to_tsquery('simple', immutable_unaccent(?)))"
+
+// searchTSQuery convierte la búsqueda del usuario en una consulta de to_tsquery: solo letras y
+// números, cada palabra como prefijo y todas obligatorias ("press banca" -> "press:* & banca:*")
+func searchTSQuery(search string) string {
+	words := strings.FieldsFunc(search, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for i, word := range words {
+		words[i] = strings.ToLower(word) + ":*"
+	}
+	return strings.Join(words, " & ")
+}
+
+func (s *GormRoutineStore) FindByID(id uint) (models.Routine, error) {
+	var routine models.Routine
+	err := s.DB.
+		Preload("Exercises.Sets").
+		Preload("Exercises.Definition").
+		Preload("Users").
+		First(&routine, "id = ?", id).Error
+	return routine, notFound(err)
+}
+
+func (s *GormRoutineStore) ListForks(id uint, limit int, offset int) ([]models.Routine, error) {
+	var forks []models.Routine
+	err := s.DB.
+		Select("routines.*, "+forkCountSelect+", "+likeCountSelect).
+		Preload("Users").
+		Where("forked_from_id = ? AND public = ? AND hidden = ?", id, true, false).
+		Order("created_at DESC").
+		Limit(limit).
+		Offset(offset).
+		Find(&forks).Error
+	return forks, err
+}
+
+func (s *GormRoutineStore) CountForks(id uint) (int64, error) {
+	var count int64
+	err := s.DB.Model(&models.Routine{}).Where("forked_from_id = ?", id).Count(&count).Error
+	return count, err
+}
+
+func (s *GormRoutineStore) Like(userID string, routineID uint) error {
+	// Dar me gusta dos veces no es un error
+	return s.DB.Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&models.RoutineLike{UserID: userID, RoutineID: routineID}).Error
+}
+
+func (s *GormRoutineStore) Unlike(userID string, routineID uint) error {
+	return s.DB.Where("user_id = ? AND routine_id = ?", userID, routineID).Delete(&models.RoutineLike{}).Error
+}
+
+func (s *GormRoutineStore) CountLikes(id uint) (int64, error) {
+	var count int64
+	err := s.DB.Model(&models.RoutineLike{}).Where("routine_id = ?", id).Count(&count).Error
+	return count, err
+}
+
+func (s *GormRoutineStore) IsOwner(userID string, routineID uint) (bool, error) {
+	var count int64
+	err := s.DB.
+		Table("user_make_routine").
+		Where("user_id = ? AND routine_id = ?", userID, routineID).
+		Count(&count).Error
+	return count > 0, err
+}
+
+func (s *GormRoutineStore) NameTaken(ownerID string, name string, excludeID uint) (bool, error) {
+	var count int64
+	err := s.DB.Model(&models.Routine{}).
+		Where("owner_id = ? AND name = ? AND id <> ?", ownerID, name, excludeID).
+		Count(&count).Error
+	return count > 0, err
+}
+
+func (s *GormRoutineStore) FindIDByName(ownerID string, name string) (uint, error) {
+	var routine models.Routine
+	err := s.DB.Select("id").First(&routine, "owner_id = ? AND name = ?", ownerID, name).Error
+	return routine.ID, notFound(err)
+}
+
+func (s *GormRoutineStore) Create(routine *models.Routine, userID string) error {
+	// El índice idx_routines_owner_name rechaza otra rutina del dueño con el mismo nombre
+	return duplicate(s.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(routine).Error; err != nil {
+			return err
+		}
+		// La columna public tiene default true, por lo que el false se debe guardar aparte
+		if !routine.Public {
+			if err := tx.Model(routine).Update("public", false).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Model(&models.User{ID: userID}).Association("Routines").Append(routine)
+	}))
+}
+
+func (s *GormRoutineStore) UpdateName(id uint, name string) error {
+	return duplicate(s.DB.Model(&models.Routine{ID: id}).Update("name", name).Error)
+}
+
+func (s *GormRoutineStore) UpdateDescription(id uint, description string) error {
+	return s.DB.Model(&models.Routine{ID: id}).Update("description", description).Error
+}
+
+func (s *GormRoutineStore) UpdatePublic(id uint, public bool) error {
+	return s.DB.Model(&models.Routine{ID: id}).Update("public", public).Error
+}
+
+func (s *GormRoutineStore) UpdateHidden(id uint, hidden bool) error {
+	return s.DB.Model(&models.Routine{ID: id}).Update("hidden", hidden).Error
+}
+
+func (s *GormRoutineStore) Delete(id uint) error {
+	return s.DB.Transaction(func(tx *gorm.DB) error {
+		var routine models.Routine
+		if err := tx.Preload("Exercises").First(&routine, "id = ?", id).Error; err != nil {
+			return notFound(err)
+		}
+		for _, exercise := range routine.Exercises {
+			if err := tx.Where("exercise_id = ?", exercise.ID).Delete(&models.Set{}).Error; err != nil {
+				return err
+			}
+			if err := tx.Delete(&exercise).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Delete(&routine).Error
+	})
+}
+
+// GormExerciseStore implementa ExerciseStore sobre GORM
+type GormExerciseStore struct {
+	DB *gorm.DB
+}
+
+func (s *GormExerciseStore) FindByID(id uint) (models.Exercise, error) {
+	var exercise models.Exercise
+	err := s.DB.Preload("Sets").First(&exercise, "id = ?", id).Error
+	return exercise, notFound(err)
+}
+
+func (s *GormExerciseStore) IsOwner(userID string, exerciseID uint) (bool, error) {
+	var count int64
+	err := s.DB.
+		Table("routine_work_exercise rwe").
+		Joins("JOIN user_make_routine umr ON umr.routine_id = rwe.routine_id").
+		Where("rwe.exercise_id = ? AND umr.user_id = ?", exerciseID, userID).
+		Count(&count).Error
+	return count > 0, err
+}
+
+func (s *GormExerciseStore) Create(routineID uint, exercise *models.Exercise) error {
+	return s.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(exercise).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.Routine{ID: routineID}).Association("Exercises").Append(exercise)
+	})
+}
+
+func (s *GormExerciseStore) Update(exercise *models.Exercise) error {
+	return s.DB.Model(exercise).Select("name", "definition_id").Updates(exercise).Error
+}
+
+func (s *GormExerciseStore) ReplaceSets(exerciseID uint, sets []SetInput) ([]models.Set, error) {
+	// Crear un slice para almacenar los sets finales
+	var finalSets []models.Set
+
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
+		// Obtener todos los sets actuales del ejercicio
+		var currentSets []models.Set
+		if err := tx.Where("exercise_id = ?", exerciseID).Find(&currentSets).Error; err != nil {
+			return err
+		}
+
+		// Crear un mapa de sets actuales por ID
+		currentSetsMap := make(map[uint]models.Set)
+		for _, set := range currentSets {
+			currentSetsMap[set.ID] = set
+		}
+
+		// Procesar sets de la solicitud
+		for _, input := range sets {
+			if input.ID != 0 { // Si el set tiene un ID, actualizar
+				if set, ok := currentSetsMap[input.ID]; ok {
+					// Solo se guarda si cambió, para que UpdatedAt indique desde cuándo rige el set
+					if set.Reps != input.Reps || set.Weight != input.Weight || set.Rest != input.Rest || set.Note != input.Note {
+						set.Reps = input.Reps
+						set.Weight = input.Weight
+						set.Rest = input.Rest
+						set.Note = input.Note
+						if err := tx.Save(&set).Error; err != nil {
+							return err
+						}
+					}
+					finalSets = append(finalSets, set) // Añadir al slice de sets finales
+					delete(currentSetsMap, set.ID)     // Eliminar de mapa para no considerarlo para eliminación
+				}
+			} else { // Si el set es nuevo, crear
+				newSet := models.Set{
+					ExerciseID: exerciseID,
+					Reps:       input.Reps,
+					Weight:     input.Weight,
+					Rest:       input.Rest,
+					Note:       input.Note,
+				}
+				if err := tx.Create(&newSet).Error; err != nil {
+					return err
+				}
+				finalSets = append(finalSets, newSet) // Añadir al slice de sets finales
+			}
+		}
+
+		// Eliminar sets que no están en la solicitud
+		for id := range currentSetsMap {
+			if err := tx.Delete(&models.Set{}, id).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return finalSets, nil
+}
+
+func (s *GormExerciseStore) Delete(id uint) error {
+	return s.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("exercise_id = ?", id).Delete(&models.Set{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.Exercise{}, id).Error
+	})
+}
+
+func (s *GormExerciseStore) ResolveDefinition(definitionID uint, name string) (*uint, error) {
+	if definitionID != 0 {
+		var definition models.ExerciseDefinition
+		if err := s.DB.Select("id").First(&definition, "id = ?", definitionID).Error; err != nil {
+			return nil, notFound(err)
+		}
+		return &definition.ID, nil
+	}
+
+	normalized := strings.ToLower(strings.TrimSpace(name))
+	if normalized == "" {
+		return nil, nil
+	}
+
+	var definitions []models.ExerciseDefinition
+	if err := s.DB.
+		Select("id").
+		Where("lower(name_en) = ? OR lower(name_es) = ? OR EXISTS (SELECT 1 FROM jsonb_array_elements_text(aliases) alias WHERE lower(alias) = ?)", normalized, normalized, normalized).
+		Limit(1).
+		Find(&definitions).Error; err != nil {
+		return nil, err
+	}
+	if len(definitions) == 0 {
+		return nil, nil
+	}
+	return &definitions[0].ID, nil
+}
+
+func (s *GormExerciseStore) FindInRoutine(routineID uint, exerciseID uint) (models.Exercise, error) {
+	var exercise models.Exercise
+	err := s.DB.
+		Preload("Sets").
+		Joins("JOIN routine_work_exercise rwe ON rwe.exercise_id = exercises.id").
+		Where("rwe.routine_id = ? AND exercises.id = ?", routineID, exerciseID).
+		First(&exercise).Error
+	return exercise, notFound(err)
+}
+
+func (s *GormExerciseStore) ListDefinitions(q CatalogQuery) ([]models.ExerciseDefinition, int64, error) {
+	query := s.DB.Model(&models.ExerciseDefinition{})
+	if q.Search != "" {
+		like := "%" + q.Search + "%"
+		query = query.Where("name_en ILIKE ? OR name_es ILIKE ? OR aliases::text ILIKE ?", like, like, like)
+	}
+	if q.Muscle != "" {
+		// Coincide con músculos principales o secundarios
+		muscleJSON, _ := json.Marshal([]string{q.Muscle})
+		query = query.Where("primary_muscles @> ?::jsonb OR secondary_muscles @> ?::jsonb", string(muscleJSON), string(muscleJSON))
+	}
+	if q.Equipment != "" {
+		equipmentJSON, _ := json.Marshal([]string{q.Equipment})
+		query = query.Where("equipment @> ?::jsonb", string(equipmentJSON))
+	}
+	if q.Pattern != "" {
+		query = query.Where("movement_pattern = ?", q.Pattern)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var definitions []models.ExerciseDefinition
+	err := query.Order("name_en").Limit(q.Limit).Offset(q.Offset).Find(&definitions).Error
+	return definitions, total, err
+}
+
+func (s *GormExerciseStore) FindDefinition(id uint) (models.ExerciseDefinition, error) {
+	var definition models.ExerciseDefinition
+	err := s.DB.First(&definition, "id = ?", id).Error
+	return definition, notFound(err)
+}
+
+// GormTokenStore implementa TokenStore sobre GORM
+type GormTokenStore struct {
+	DB *gorm.DB
+}
+
+func (s *GormTokenStore) Create(token *models.RefreshToken) error {
+	return s.DB.Create(token).Error
+}
+
+func (s *GormTokenStore) FindByHash(hash string) (models.RefreshToken, error) {
+	var token models.RefreshToken
+	err := s.DB.First(&token, "token_hash = ?", hash).Error
+	return token, notFound(err)
+}
+
+func (s *GormTokenStore) MarkUsed(id uint) (bool, error) {
+	// La condición used_at IS NULL evita que dos solicitudes simultáneas roten el mismo token
+	result := s.DB.Model(&models.RefreshToken{}).
+		Where("id = ? AND used_at IS NULL", id).
+		Update("used_at", time.Now())
+	return result.RowsAffected > 0, result.Error
+}
+
+func (s *GormTokenStore) RevokeFamily(familyID string) error {
+	return s.DB.Model(&models.RefreshToken{}).
+		Where("family_id = ? AND revoked_at IS NULL", familyID).
+		Update("revoked_at", time.Now()).Error
+}
+
+func (s *GormTokenStore) FamilyActive(familyID string) (bool, error) {
+	var count int64
+	err := s.DB.Model(&models.RefreshToken{}).
+		Where("family_id = ? AND revoked_at IS NULL", familyID).
+		Count(&count).Error
+	return count > 0, err
+}
+
+func (s *GormTokenStore) RevokeUser(userID string) error {
+	return s.DB.Model(&models.RefreshToken{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", time.Now()).Error
+}
+
+func (s *GormTokenStore) CreateUserToken(token *models.UserToken) error {
+	return s.DB.Create(token).Error
+}
+
+func (s *GormTokenStore) ConsumeUserToken(purpose string, hash string) (models.UserToken, error) {
+	var token models.UserToken
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&token, "token_hash = ? AND purpose = ?", hash, purpose).Error; err != nil {
+			return notFound(err)
+		}
+		if token.UsedAt != nil || time.Now().After(token.ExpiresAt) {
+			return ErrNotFound
+		}
+		// La condición used_at IS NULL evita que dos solicitudes simultáneas usen el mismo token
+		now := time.Now()
+		result := tx.Model(&token).Where("used_at IS NULL").Update("used_at", now)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrNotFound
+		}
+		return nil
+	})
+	return token, err
+}
+
+func (s *GormTokenStore) InvalidateUserTokens(userID string, purpose string) error {
+	return s.DB.Model(&models.UserToken{}).
+		Where("user_id = ? AND purpose = ? AND used_at IS NULL", userID, purpose).
+		Update("used_at", time.Now()).Error
+}
+
+func (s *GormTokenStore) CreateEmailRequest(request *models.EmailRequest) error {
+	return s.DB.Create(request).Error
+}
+
+func (s *GormTokenStore) CountEmailRequests(email string, purpose string, since time.Time) (int64, error) {
+	var count int64
+	err := s.DB.Model(&models.EmailRequest{}).
+		Where("email = ? AND purpose = ? AND created_at > ?", email, purpose, since).
+		Count(&count).Error
+	return count, err
+}
+
+// GormModerationStore implementa ModerationStore sobre GORM
+type GormModerationStore struct {
+	DB *gorm.DB
+}
+
+func (s *GormModerationStore) CreateReport(report *models.RoutineReport) error {
+	return s.DB.Create(report).Error
+}
+
+func (s *GormModerationStore) HasPendingReport(routineID uint, reporterID string) (bool, error) {
+	var count int64
+	err := s.DB.Model(&models.RoutineReport{}).
+		Where("routine_id = ? AND reporter_id = ? AND status = ?", routineID, reporterID, models.ReportPending).
+		Count(&count).Error
+	return count > 0, err
+}
+
+func (s *GormModerationStore) ListReports(status string, limit int, offset int) ([]models.RoutineReport, int64, error) {
+	query := s.DB.Model(&models.RoutineReport{})
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var reports []models.RoutineReport
+	err := query.
+		Preload("Routine").
+		Order("created_at").
+		Limit(limit).
+		Offset(offset).
+		Find(&reports).Error
+	return reports, total, err
+}
+
+func (s *GormModerationStore) FindReport(id uint) (models.RoutineReport, error) {
+	var report models.RoutineReport
+	err := s.DB.Preload("Routine").First(&report, "id = ?", id).Error
+	return report, notFound(err)
+}
+
+func (s *GormModerationStore) Apply(change *ModerationChange) error {
+	if err := change.validate(); err != nil {
+		return err
+	}
+	entry := &change.Entry
+	return s.DB.Transaction(func(tx *gorm.DB) error {
+		if change.RoutinePublic != nil || change.RoutineHidden != nil {
+			updates := map[string]interface{}{}
+			if change.RoutinePublic != nil {
+				updates["public"] = *change.RoutinePublic
+			}
+			if change.RoutineHidden != nil {
+				updates["hidden"] = *change.RoutineHidden
+			}
+			result := tx.Model(&models.Routine{}).Where("id = ?", *entry.RoutineID).Updates(updates)
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected == 0 {
+				return ErrNotFound
+			}
+		}
+
+		if change.UserDisabled != nil {
+			if err := (&GormUserStore{DB: tx}).SetDisabled(*entry.TargetUserID, *change.UserDisabled); err != nil {
+				return err
+			}
+			if *change.UserDisabled {
+				if err := (&GormTokenStore{DB: tx}).RevokeUser(*entry.TargetUserID); err != nil {
+					return err
+				}
+			}
+		}
+
+		if change.ReportStatus != "" {
+			if err := tx.Model(&models.RoutineReport{}).
+				Where("routine_id = ? AND status = ?", *entry.RoutineID, models.ReportPending).
+				Updates(map[string]interface{}{
+					"status":      change.ReportStatus,
+					"action":      entry.Action,
+					"resolved_by": entry.ModeratorID,
+					"resolved_at": time.Now(),
+				}).Error; err != nil {
+				return err
+			}
+		}
+
+		return tx.Create(entry).Error
+	})
+}
+
+func (s *GormModerationStore) ListActions(limit int, offset int) ([]models.ModerationAction, int64, error) {
+	var total int64
+	if err := s.DB.Model(&models.ModerationAction{}).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var actions []models.ModerationAction
+	err := s.DB.Order("created_at DESC").Limit(limit).Offset(offset).Find(&actions).Error
+	return actions, total, err
+}
+
+// GormWorkoutStore implementa WorkoutStore sobre GORM
+type GormWorkoutStore struct {
+	DB *gorm.DB
+}
+
+// preloadWorkoutSets carga los sets de la sesión en orden y los récords que marcó cada uno
+func preloadWorkoutSets(db *gorm.DB) *gorm.DB {
+	return db.
+		Preload("Sets", func(tx *gorm.DB) *gorm.DB { return tx.Order("performed_at") }).
+		Preload("Sets.Records")
+}
+
+func (s *GormWorkoutStore) ListByUser(userID string) ([]models.WorkoutSession, error) {
+	var workouts []models.WorkoutSession
+	err := preloadWorkoutSets(s.DB).
+		Where("user_id = ?", userID).
+		Order("started_at DESC").
+		Find(&workouts).Error
+	return workouts, err
+}
+
+func (s *GormWorkoutStore) FindByID(id uint) (models.WorkoutSession, error) {
+	var workout models.WorkoutSession
+	err := preloadWorkoutSets(s.DB).First(&workout, "id = ?", id).Error
+	return workout, notFound(err)
+}
+
+func (s *GormWorkoutStore) Create(workout *models.WorkoutSession) error {
+	return s.DB.Create(workout).Error
+}
+
+func (s *GormWorkoutStore) LogSet(userID string, set *models.WorkoutSet) error {
+	return s.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(set).Error; err != nil {
+			return err
+		}
+
+		var history []models.PersonalRecord
+		if err := tx.Where("user_id = ? AND exercise_id = ?", userID, set.ExerciseID).Find(&history).Error; err != nil {
+			return err
+		}
+		newRecords := records.Detect(records.Current(history), *set)
+		for i := range newRecords {
+			newRecords[i].UserID = userID
+			if err := tx.Create(&newRecords[i]).Error; err != nil {
+				return err
+			}
+		}
+		set.Records = newRecords
+		return nil
+	})
+}
+
+func (s *GormWorkoutStore) Finish(workout *models.WorkoutSession) error {
+	return s.DB.Model(workout).Select("finished_at", "duration_seconds", "notes").Updates(workout).Error
+}
+
+func (s *GormWorkoutStore) Delete(id uint) error {
+	return s.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("workout_session_id = ?", id).Delete(&models.WorkoutSet{}).Error; err != nil {
+			return err
+		}
+		// Al borrar los récords de la sesión vuelven a quedar vigentes los anteriores
+		if err := tx.Where("workout_session_id = ?", id).Delete(&models.PersonalRecord{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.WorkoutSession{}, id).Error
+	})
+}
+
+func (s *GormWorkoutStore) ListRecords(userID string, exerciseID uint) ([]models.PersonalRecord, error) {
+	query := s.DB.Where("user_id = ?", userID)
+	if exerciseID != 0 {
+		query = query.Where("exercise_id = ?", exerciseID)
+	}
+
+	var history []models.PersonalRecord
+	err := query.Order("achieved_at DESC").Find(&history).Error
+	return history, err
+}
+
+// GormStatsStore implementa StatsStore sobre GORM
+type GormStatsStore struct {
+	DB *gorm.DB
+}
+
+// existedAtSession filtra las filas de la rutina (ejercicios o sets) que existían cuando empezó
+// la sesión ws: creadas antes y no eliminadas antes
+func existedAtSession(table string) string {
+	return "(" + table + ".created_at <= ws.started_at AND (" + table + ".deleted_at IS NULL OR " + table + ".deleted_at > ws.started_at))"
+}
+
+// plannedSetAtSession filtra los sets que regían cuando empezó la sesión ws. Los valores de un
+// set editado después no son los que se planificaron, así que no cuenta en esa sesión: editar la
+// rutina no cambia las sesiones anteriores.
+const plannedSetAtSession = "(sets.updated_at <= ws.started_at)"
+
+// statsSetRow es un set con los músculos principales de su ejercicio en el catálogo (jsonb)
+type statsSetRow struct {
+	PerformedAt time.Time
+	Exercise    string
+	Muscles     *string
+	Reps        int
+	Weight      float64
+}
+
+func (s *GormStatsStore) VolumeEntries(userID string, source string, from time.Time, to time.Time) ([]analytics.SetEntry, error) {
+	var rows []statsSetRow
+	var err error
+	if source == analytics.SourcePlanned {
+		err = s.DB.Raw(`SELECT ws.started_at AS performed_at, e.name AS exercise, d.primary_muscles::text AS muscles, sets.reps, sets.weight
+			FROM workout_sessions ws
+			JOIN routine_work_exercise rwe ON rwe.routine_id = ws.routine_id
+			JOIN exercises e ON e.id = rwe.exercise_id AND `+existedAtSession("e")+`
+			JOIN sets ON sets.exercise_id = e.id AND `+existedAtSession("sets")+` AND `+plannedSetAtSession+`
+			LEFT JOIN exercise_definitions d ON d.id = e.definition_id
+			WHERE ws.user_id = ? AND ws.deleted_at IS NULL AND ws.started_at BETWEEN ? AND ?`, userID, from, to).
+			Scan(&rows).Error
+	} else {
+		err = s.DB.Raw(`SELECT wset.performed_at, wset.exercise_name AS exercise, d.primary_muscles::text AS muscles, wset.reps, wset.weight
+			FROM workout_sets wset
+			JOIN workout_sessions ws ON ws.id = wset.workout_session_id AND ws.deleted_at IS NULL
+			LEFT JOIN exercises e ON e.id = wset.exercise_id
+			LEFT JOIN exercise_definitions d ON d.id = e.definition_id
+			WHERE ws.user_id = ? AND wset.deleted_at IS NULL AND wset.performed_at BETWEEN ? AND ?`, userID, from, to).
+			Scan(&rows).Error
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]analytics.SetEntry, len(rows))
+	for i, row := range rows {
+		entries[i] = analytics.SetEntry{
+			PerformedAt: row.PerformedAt,
+			Exercise:    row.Exercise,
+			Reps:        row.Reps,
+			Weight:      row.Weight,
+		}
+		// Los ejercicios sin catálogo (o con músculos null) quedan sin asignar. Si los músculos del
+		// catálogo no se pueden leer, el set se cuenta igual, sin asignar, y se registra el error.
+		if row.Muscles != nil {
+			if err := json.Unmarshal([]byte(*row.Muscles), &entries[i].Muscles); err != nil {
+				log.Printf("estadísticas: músculos del ejercicio %q no válidos: %v", row.Exercise, err)
+				entries[i].Muscles = nil
+			}
+		}
+	}
+	return entries, nil
+}
+
+func (s *GormStatsStore) ExerciseEntries(userID string, exerciseID uint, source string, to time.Time) ([]analytics.SetEntry, error) {
+	var rows []statsSetRow
+	var err error
+	if source == analytics.SourcePlanned {
+		err = s.DB.Raw(`SELECT ws.started_at AS performed_at, e.name AS exercise, sets.reps, sets.weight
+			FROM workout_sessions ws
+			JOIN routine_work_exercise rwe ON rwe.routine_id = ws.routine_id AND rwe.exercise_id = ?
+			JOIN exercises e ON e.id = rwe.exercise_id AND `+existedAtSession("e")+`
+			JOIN sets ON sets.exercise_id = e.id AND `+existedAtSession("sets")+` AND `+plannedSetAtSession+`
+			WHERE ws.user_id = ? AND ws.deleted_at IS NULL AND ws.started_at <= ?
+			ORDER BY sets.id`, exerciseID, userID, to).
+			Scan(&rows).Error
+	} else {
+		err = s.DB.Raw(`SELECT wset.performed_at, wset.exercise_name AS exercise, wset.reps, wset.weight
+			FROM workout_sets wset
+			JOIN workout_sessions ws ON ws.id = wset.workout_session_id AND ws.deleted_at IS NULL
+			WHERE ws.user_id = ? AND wset.exercise_id = ? AND wset.deleted_at IS NULL AND wset.performed_at <= ?`, userID, exerciseID, to).
+			Scan(&rows).Error
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]analytics.SetEntry, len(rows))
+	for i, row := range rows {
+		entries[i] = analytics.SetEntry{PerformedAt: row.PerformedAt, Exercise: row.Exercise, Reps: row.Reps, Weight: row.Weight}
+	}
+	sortEntries(entries)
+	return entries, nil
+}
+
+// sortEntries ordena los sets cronológicamente, manteniendo el orden de los que empatan
+func sortEntries(entries []analytics.SetEntry) {
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].PerformedAt.Before(entries[j].PerformedAt)
+	})
+}
@@ -0,0 +1,535 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/danilsgit/gym-stats-backend/analytics"
+	"github.com/danilsgit/gym-stats-backend/migrations"
+	"github.com/danilsgit/gym-stats-backend/models"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// Estas pruebas necesitan Postgres: se ejecutan solo si TEST_DATABASE_URL tiene el DSN de una
+// base de pruebas, por ejemplo
+//
+//	TEST_DATABASE_URL="host=localhost user=postgres password=postgres dbname=gym_test port=5432" go test ./store/
+//
+// En CI (.github/workflows/test.yml) la base la da un servicio de Postgres y las pruebas fallan
+// en lugar de saltarse si falta TEST_DATABASE_URL.
+
+var errInjected = errors.New("fallo inyectado")
+
+// openTestDB abre una conexión nueva a la base de pruebas con las migraciones aplicadas. Cada
+// conexión tiene sus propios callbacks, así que los fallos inyectados no afectan a las demás.
+func openTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		if os.Getenv("CI") != "" {
+			t.Fatal("TEST_DATABASE_URL no está definida en CI")
+		}
+		t.Skip("TEST_DATABASE_URL no está definida")
+	}
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("conectar a la base de pruebas: %v", err)
+	}
+	if _, err := migrations.Up(db); err != nil {
+		t.Fatalf("aplicar las migraciones: %v", err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	return db
+}
+
+// failOn hace fallar las operaciones (create, update o delete) sobre la tabla en esta conexión
+func failOn(t *testing.T, db *gorm.DB, operation string, table string) {
+	t.Helper()
+	fail := func(tx *gorm.DB) {
+		if tx.Statement.Table == table {
+			tx.AddError(errInjected)
+		}
+	}
+	var err error
+	name := "test:fail_" + operation + "_" + table
+	switch operation {
+	case "create":
+		err = db.Callback().Create().Before("gorm:create").Register(name, fail)
+	case "update":
+		err = db.Callback().Update().Before("gorm:update").Register(name, fail)
+	case "delete":
+		err = db.Callback().Delete().Before("gorm:delete").Register(name, fail)
+	}
+	if err != nil {
+		t.Fatalf("registrar el callback: %v", err)
+	}
+}
+
+// count cuenta las filas de la tabla que cumplen la condición, incluidas las eliminadas
+func count(t *testing.T, db *gorm.DB, table string, query string, args ...interface{}) int64 {
+	t.Helper()
+	var n int64
+	if err := db.Table(table).Where(query, args...).Count(&n).Error; err != nil {
+		t.Fatalf("contar %s: %v", table, err)
+	}
+	return n
+}
+
+// createTestRoutine crea un usuario y una rutina con un ejercicio de dos sets
+func createTestRoutine(t *testing.T, db *gorm.DB) (models.User, models.Routine) {
+	t.Helper()
+	suffix := fmt.Sprint(time.Now().UnixNano())
+	user := models.User{Username: "tx" + suffix, Email: "tx" + suffix + "@example.com", Password: "secreto123"}
+	if err := (&GormUserStore{DB: db}).Create(&user); err != nil {
+		t.Fatalf("crear usuario: %v", err)
+	}
+	routine := models.Routine{
+		Name:    "Transacción " + suffix,
+		OwnerID: user.ID,
+		Exercises: []models.Exercise{{
+			Name: "Ejercicio " + suffix,
+			Sets: []models.Set{{Reps: 5, Weight: 100}, {Reps: 5, Weight: 100}},
+		}},
+	}
+	if err := (&GormRoutineStore{DB: db}).Create(&routine, user.ID); err != nil {
+		t.Fatalf("crear rutina: %v", err)
+	}
+	return user, routine
+}
+
+func TestRoutineCreateRollsBackOnSetsFailure(t *testing.T) {
+	db := openTestDB(t)
+	failOn(t, db, "create", "sets")
+
+	suffix := fmt.Sprint(time.Now().UnixNano())
+	user := models.User{Username: "tx" + suffix, Email: "tx" + suffix + "@example.com", Password: "secreto123"}
+	if err := (&GormUserStore{DB: db}).Create(&user); err != nil {
+		t.Fatalf("crear usuario: %v", err)
+	}
+	routine := models.Routine{
+		Name:    "Transacción " + suffix,
+		OwnerID: user.ID,
+		Exercises: []models.Exercise{{
+			Name: "Ejercicio " + suffix,
+			Sets: []models.Set{{Reps: 5, Weight: 100}},
+		}},
+	}
+
+	err := (&GormRoutineStore{DB: db}).Create(&routine, user.ID)
+	if !errors.Is(err, errInjected) {
+		t.Fatalf("error %v, se esperaba el fallo inyectado", err)
+	}
+
+	if n := count(t, db, "routines", "name = ?", routine.Name); n != 0 {
+		t.Errorf("quedaron %d rutinas", n)
+	}
+	if n := count(t, db, "user_make_routine", "user_id = ?", user.ID); n != 0 {
+		t.Errorf("quedaron %d filas en user_make_routine", n)
+	}
+	if n := count(t, db, "exercises", "name = ?", "Ejercicio "+suffix); n != 0 {
+		t.Errorf("quedaron %d ejercicios", n)
+	}
+}
+
+func TestReplaceSetsRollsBackOnCreateFailure(t *testing.T) {
+	_, routine := createTestRoutine(t, openTestDB(t))
+	exercise := routine.Exercises[0]
+
+	db := openTestDB(t)
+	failOn(t, db, "create", "sets")
+
+	// Se actualiza el primer set y falla al crear el nuevo: el primero no debe cambiar
+	_, err := (&GormExerciseStore{DB: db}).ReplaceSets(exercise.ID, []SetInput{
+		{ID: exercise.Sets[0].ID, Reps: 99, Weight: 99},
+		{Reps: 1, Weight: 1},
+	})
+	if !errors.Is(err, errInjected) {
+		t.Fatalf("error %v, se esperaba el fallo inyectado", err)
+	}
+
+	if n := count(t, db, "sets", "exercise_id = ? AND deleted_at IS NULL", exercise.ID); n != 2 {
+		t.Errorf("el ejercicio tiene %d sets, se esperaban 2", n)
+	}
+	if n := count(t, db, "sets", "id = ? AND reps = ?", exercise.Sets[0].ID, 5); n != 1 {
+		t.Errorf("el primer set se modificó")
+	}
+}
+
+func TestRoutineDeleteRollsBackOnFailure(t *testing.T) {
+	_, routine := createTestRoutine(t, openTestDB(t))
+	exercise := routine.Exercises[0]
+
+	db := openTestDB(t)
+	failOn(t, db, "delete", "routines")
+
+	err := (&GormRoutineStore{DB: db}).Delete(routine.ID)
+	if !errors.Is(err, errInjected) {
+		t.Fatalf("error %v, se esperaba el fallo inyectado", err)
+	}
+
+	if n := count(t, db, "routines", "id = ? AND deleted_at IS NULL", routine.ID); n != 1 {
+		t.Errorf("la rutina se eliminó")
+	}
+	if n := count(t, db, "exercises", "id = ? AND deleted_at IS NULL", exercise.ID); n != 1 {
+		t.Errorf("el ejercicio se eliminó")
+	}
+	if n := count(t, db, "sets", "exercise_id = ? AND deleted_at IS NULL", exercise.ID); n != 2 {
+		t.Errorf("quedan %d sets, se esperaban 2", n)
+	}
+}
+
+func TestExerciseDeleteRollsBackOnFailure(t *testing.T) {
+	_, routine := createTestRoutine(t, openTestDB(t))
+	exercise := routine.Exercises[0]
+
+	db := openTestDB(t)
+	failOn(t, db, "delete", "exercises")
+
+	err := (&GormExerciseStore{DB: db}).Delete(exercise.ID)
+	if !errors.Is(err, errInjected) {
+		t.Fatalf("error %v, se esperaba el fallo inyectado", err)
+	}
+
+	if n := count(t, db, "exercises", "id = ? AND deleted_at IS NULL", exercise.ID); n != 1 {
+		t.Errorf("el ejercicio se eliminó")
+	}
+	if n := count(t, db, "sets", "exercise_id = ? AND deleted_at IS NULL", exercise.ID); n != 2 {
+		t.Errorf("quedan %d sets, se esperaban 2", n)
+	}
+}
+
+func TestModerationApplyRollsBackWhenLogFails(t *testing.T) {
+	author, routine := createTestRoutine(t, openTestDB(t))
+
+	db := openTestDB(t)
+	report := models.RoutineReport{RoutineID: routine.ID, ReporterID: author.ID, Reason: models.ReportSpam}
+	if err := (&GormModerationStore{DB: db}).CreateReport(&report); err != nil {
+		t.Fatalf("crear reporte: %v", err)
+	}
+	failOn(t, db, "create", "moderation_actions")
+
+	hidden, disabled := true, true
+	err := (&GormModerationStore{DB: db}).Apply(&ModerationChange{
+		Entry:         models.ModerationAction{ModeratorID: author.ID, Action: models.ModerationSuspend, RoutineID: &routine.ID, TargetUserID: &author.ID},
+		RoutineHidden: &hidden,
+		UserDisabled:  &disabled,
+		ReportStatus:  models.ReportActioned,
+	})
+	if !errors.Is(err, errInjected) {
+		t.Fatalf("error %v, se esperaba el fallo inyectado", err)
+	}
+
+	if n := count(t, db, "routines", "id = ? AND hidden = ?", routine.ID, false); n != 1 {
+		t.Errorf("la rutina quedó oculta")
+	}
+	if n := count(t, db, "users", "id = ? AND disabled = ?", author.ID, false); n != 1 {
+		t.Errorf("el usuario quedó deshabilitado")
+	}
+	if n := count(t, db, "routine_reports", "id = ? AND status = ?", report.ID, models.ReportPending); n != 1 {
+		t.Errorf("el reporte se resolvió")
+	}
+}
+
+// Los sets planificados cuentan en una sesión solo si regían cuando empezó
+func TestPlannedEntriesUseSetsInEffectAtSession(t *testing.T) {
+	db := openTestDB(t)
+	user, routine := createTestRoutine(t, db)
+	exercise := routine.Exercises[0]
+
+	session := models.WorkoutSession{UserID: user.ID, RoutineID: routine.ID, RoutineName: routine.Name, StartedAt: time.Now().Add(time.Hour)}
+	if err := (&GormWorkoutStore{DB: db}).Create(&session); err != nil {
+		t.Fatalf("crear sesión: %v", err)
+	}
+	// El primer set se edita después de la sesión; el segundo se elimina después de la sesión
+	if err := db.Exec("UPDATE sets SET reps = 1, updated_at = ? WHERE id = ?", session.StartedAt.Add(time.Hour), exercise.Sets[0].ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Exec("UPDATE sets SET deleted_at = ? WHERE id = ?", session.StartedAt.Add(time.Hour), exercise.Sets[1].ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	// Un set creado después de la sesión
+	late := models.Set{ExerciseID: exercise.ID, Reps: 9, Weight: 9, CreatedAt: session.StartedAt.Add(time.Hour), UpdatedAt: session.StartedAt.Add(time.Hour)}
+	if err := db.Create(&late).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	stats := &GormStatsStore{DB: db}
+	entries, err := stats.ExerciseEntries(user.ID, exercise.ID, analytics.SourcePlanned, session.StartedAt.Add(24*time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Reps != 5 || entries[0].PerformedAt.Sub(session.StartedAt).Abs() > time.Millisecond {
+		t.Fatalf("sets planificados inesperados: %+v", entries)
+	}
+
+	weekly, err := stats.VolumeEntries(user.ID, analytics.SourcePlanned, session.StartedAt.Add(-time.Hour), session.StartedAt.Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(weekly) != 1 || weekly[0].Reps != 5 {
+		t.Fatalf("volumen planificado inesperado: %+v", weekly)
+	}
+}
+
+// Los correos pedidos se cuentan por dirección y propósito dentro de la ventana
+func TestCountEmailRequests(t *testing.T) {
+	db := openTestDB(t)
+	tokens := &GormTokenStore{DB: db}
+	email := fmt.Sprintf("limite-%d@example.com", time.Now().UnixNano())
+
+	for _, purpose := range []string{models.TokenPasswordReset, models.TokenPasswordReset, models.TokenEmailVerification} {
+		if err := tokens.CreateEmailRequest(&models.EmailRequest{Email: email, Purpose: purpose}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		purpose string
+		since   time.Time
+		want    int64
+	}{
+		{models.TokenPasswordReset, time.Now().Add(-time.Hour), 2},
+		{models.TokenEmailVerification, time.Now().Add(-time.Hour), 1},
+		{models.TokenPasswordReset, time.Now().Add(time.Minute), 0},
+	}
+	for _, tt := range tests {
+		got, err := tokens.CountEmailRequests(email, tt.purpose, tt.since)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.want {
+			t.Fatalf("%s desde %s: %d pedidos, se esperaban %d", tt.purpose, tt.since, got, tt.want)
+		}
+	}
+}
+
+// createSearchUser crea un usuario para que las pruebas de búsqueda filtren solo sus rutinas
+func createSearchUser(t *testing.T, db *gorm.DB) models.User {
+	t.Helper()
+	suffix := fmt.Sprint(time.Now().UnixNano())
+	user := models.User{Username: "busqueda" + suffix, Email: "busqueda" + suffix + "@example.com", Password: "secreto123"}
+	if err := (&GormUserStore{DB: db}).Create(&user); err != nil {
+		t.Fatalf("crear usuario: %v", err)
+	}
+	return user
+}
+
+// createSearchRoutine crea una rutina del usuario con los ejercicios dados
+func createSearchRoutine(t *testing.T, db *gorm.DB, user models.User, name string, description string, exercises ...models.Exercise) models.Routine {
+	t.Helper()
+	routine := models.Routine{Name: name, Description: description, OwnerID: user.ID, Public: true, Exercises: exercises}
+	if err := (&GormRoutineStore{DB: db}).Create(&routine, user.ID); err != nil {
+		t.Fatalf("crear rutina %s: %v", name, err)
+	}
+	return routine
+}
+
+// La búsqueda no distingue acentos ni mayúsculas, busca prefijos y exige todas las palabras
+func TestRoutineSearchIgnoresAccents(t *testing.T) {
+	db := openTestDB(t)
+	routines := &GormRoutineStore{DB: db}
+	user := createSearchUser(t, db)
+	routine := createSearchRoutine(t, db, user, "Sentadillas búlgaras", "Para los glúteos",
+		models.Exercise{Name: "Zancada", Sets: []models.Set{{Reps: 8, Weight: 20}}})
+
+	tests := []struct {
+		search string
+		found  bool
+	}{
+		{"bulgaras", true},
+		{"BÚLGARAS", true},
+		{"búlg", true},
+		{"gluteos", true},
+		{"sentadillas glúteos", true},
+		{"zancada", true},
+		{"sentadillas rusas", false},
+	}
+	for _, tt := range tests {
+		page, err := routines.List(RoutineQuery{UserID: user.ID, Search: tt.search, Limit: 10})
+		if err != nil {
+			t.Fatalf("buscar %q: %v", tt.search, err)
+		}
+		found := len(page.Routines) == 1 && page.Routines[0].ID == routine.ID
+		if found != tt.found || page.Total != int64(len(page.Routines)) {
+			t.Fatalf("buscar %q: %d rutinas (total %d), se esperaba encontrarla: %v", tt.search, len(page.Routines), page.Total, tt.found)
+		}
+	}
+}
+
+// Por relevancia pesa más el nombre que los ejercicios y estos más que la descripción, y el orden
+// se mantiene al pedir las páginas con el cursor
+func TestRoutineSearchRelevanceAcrossPages(t *testing.T) {
+	db := openTestDB(t)
+	routines := &GormRoutineStore{DB: db}
+	user := createSearchUser(t, db)
+	sets := []models.Set{{Reps: 8, Weight: 20}}
+	inDescription := createSearchRoutine(t, db, user, "Rutina tres", "Incluye peso muerto",
+		models.Exercise{Name: "Sentadilla", Sets: sets})
+	inName := createSearchRoutine(t, db, user, "Peso muerto diario", "Sin descripción",
+		models.Exercise{Name: "Remo", Sets: sets})
+	inExercise := createSearchRoutine(t, db, user, "Rutina dos", "Espalda baja",
+		models.Exercise{Name: "Peso muerto rumano", Sets: sets})
+
+	want := []uint{inName.ID, inExercise.ID, inDescription.ID}
+	var got []uint
+	var cursor *Cursor
+	for page := 0; page < len(want); page++ {
+		result, err := routines.List(RoutineQuery{UserID: user.ID, Search: "peso muerto", Limit: 1, Cursor: cursor})
+		if err != nil {
+			t.Fatalf("página %d: %v", page, err)
+		}
+		if result.Total != int64(len(want)) || len(result.Routines) != 1 {
+			t.Fatalf("página %d: %d rutinas (total %d)", page, len(result.Routines), result.Total)
+		}
+		got = append(got, result.Routines[0].ID)
+		cursor = result.Next
+		if (cursor == nil) != (page == len(want)-1) {
+			t.Fatalf("página %d: cursor %+v", page, cursor)
+		}
+		if cursor != nil && cursor.Sort != SortRelevance {
+			t.Fatalf("página %d: orden %q, se esperaba por relevancia", page, cursor.Sort)
+		}
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("orden %v, se esperaba %v", got, want)
+		}
+	}
+}
+
+// Las facetas cuentan rutinas distintas (no ejercicios) y respetan la búsqueda
+func TestRoutineFacetCounts(t *testing.T) {
+	db := openTestDB(t)
+	routines := &GormRoutineStore{DB: db}
+	user := createSearchUser(t, db)
+	suffix := fmt.Sprint(time.Now().UnixNano())
+	squat := models.ExerciseDefinition{Slug: "squat-" + suffix, NameEn: "Squat " + suffix, NameEs: "Sentadilla " + suffix,
+		PrimaryMuscles: []string{"quadriceps"}, SecondaryMuscles: []string{"glutes"}, Equipment: []string{"barbell"}}
+	curl := models.ExerciseDefinition{Slug: "curl-" + suffix, NameEn: "Curl " + suffix, NameEs: "Curl " + suffix,
+		PrimaryMuscles: []string{"biceps"}, Equipment: []string{"dumbbell"}}
+	for _, definition := range []*models.ExerciseDefinition{&squat, &curl} {
+		if err := db.Create(definition).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Piernas: un ejercicio de 2 sets (4,5 minutos). Completa: dos sentadillas y un curl de
+	// 10 sets (40 minutos en total)
+	twoSets := []models.Set{{Reps: 5, Weight: 100, Rest: 120}, {Reps: 5, Weight: 100, Rest: 120}}
+	var tenSets []models.Set
+	for i := 0; i < 10; i++ {
+		tenSets = append(tenSets, models.Set{Reps: 10, Weight: 12, Rest: 180})
+	}
+	createSearchRoutine(t, db, user, "Piernas", "",
+		models.Exercise{Name: "Sentadilla", DefinitionID: &squat.ID, Sets: twoSets})
+	createSearchRoutine(t, db, user, "Completa", "",
+		models.Exercise{Name: "Sentadilla", DefinitionID: &squat.ID, Sets: twoSets},
+		models.Exercise{Name: "Sentadilla pausada", DefinitionID: &squat.ID},
+		models.Exercise{Name: "Curl", DefinitionID: &curl.ID, Sets: tenSets})
+
+	facets, err := routines.Facets(RoutineQuery{UserID: user.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectFacetCounts(t, "músculos", facets.Muscles, []FacetCount{{"glutes", 2}, {"quadriceps", 2}, {"biceps", 1}})
+	expectFacetCounts(t, "equipamiento", facets.Equipment, []FacetCount{{"barbell", 2}, {"dumbbell", 1}})
+	expectFacetRanges(t, "ejercicios", facets.Exercises, []int64{2, 0, 0, 0})
+	expectFacetRanges(t, "duración", facets.Duration, []int64{1, 1, 0, 0})
+
+	facets, err = routines.Facets(RoutineQuery{UserID: user.ID, Search: "piernas"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectFacetCounts(t, "músculos con búsqueda", facets.Muscles, []FacetCount{{"glutes", 1}, {"quadriceps", 1}})
+	expectFacetRanges(t, "duración con búsqueda", facets.Duration, []int64{1, 0, 0, 0})
+}
+
+func expectFacetCounts(t *testing.T, name string, got []FacetCount, want []FacetCount) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("%s: %+v, se esperaba %+v", name, got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("%s: %+v, se esperaba %+v", name, got, want)
+		}
+	}
+}
+
+// expectFacetRanges compara los conteos de cada rango
+func expectFacetRanges(t *testing.T, name string, got []FacetRange, want []int64) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("%s: %d rangos, se esperaban %d", name, len(got), len(want))
+	}
+	for i := range want {
+		if got[i].Count != want[i] {
+			t.Fatalf("%s: el rango desde %d tiene %d rutinas, se esperaban %d", name, got[i].Min, got[i].Count, want[i])
+		}
+	}
+}
+
+// ListForks devuelve solo las copias públicas y visibles; CountForks cuenta todas las no eliminadas
+func TestRoutineForksListAndCount(t *testing.T) {
+	db := openTestDB(t)
+	routines := &GormRoutineStore{DB: db}
+	user, original := createTestRoutine(t, db)
+
+	var forks []models.Routine
+	for i, state := range []string{"pública", "oculta", "privada", "eliminada"} {
+		fork := models.Routine{Name: fmt.Sprintf("Copia %d %s", i, state), OwnerID: user.ID, Public: state != "privada", ForkedFromID: &original.ID}
+		if err := routines.Create(&fork, user.ID); err != nil {
+			t.Fatalf("crear copia %s: %v", state, err)
+		}
+		forks = append(forks, fork)
+	}
+	if err := routines.UpdateHidden(forks[1].ID, true); err != nil {
+		t.Fatal(err)
+	}
+	if err := routines.Delete(forks[3].ID); err != nil {
+		t.Fatal(err)
+	}
+
+	listed, err := routines.ListForks(original.ID, 10, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(listed) != 1 || listed[0].ID != forks[0].ID {
+		t.Fatalf("copias listadas inesperadas: %+v", listed)
+	}
+	total, err := routines.CountForks(original.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != 3 {
+		t.Fatalf("%d copias, se esperaban 3", total)
+	}
+}
+
+// El índice idx_routines_owner_name se informa como ErrDuplicate al crear y al renombrar
+func TestRoutineNameUniqueViolation(t *testing.T) {
+	db := openTestDB(t)
+	routines := &GormRoutineStore{DB: db}
+	user, routine := createTestRoutine(t, db)
+
+	other := models.Routine{Name: routine.Name, OwnerID: user.ID}
+	if err := routines.Create(&other, user.ID); !errors.Is(err, ErrDuplicate) {
+		t.Fatalf("crear con un nombre repetido: %v, se esperaba ErrDuplicate", err)
+	}
+	other = models.Routine{Name: routine.Name + " 2", OwnerID: user.ID}
+	if err := routines.Create(&other, user.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := routines.UpdateName(other.ID, routine.Name); !errors.Is(err, ErrDuplicate) {
+		t.Fatalf("renombrar con un nombre repetido: %v, se esperaba ErrDuplicate", err)
+	}
+}
@@ -0,0 +1,1550 @@
+package store
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/danilsgit/gym-stats-backend/analytics"
+	"github.com/danilsgit/gym-stats-backend/models"
+	"github.com/danilsgit/gym-stats-backend/records"
+	"gorm.io/gorm"
+)
+
+// memoryData es el estado compartido por los stores en memoria. Guarda las filas sin
+// relaciones cargadas, igual que las tablas, y las relaciones en mapas aparte.
+type memoryData struct {
+	mu sync.RWMutex
+
+	users     map[string]models.User
+	routines  map[uint]models.Routine
+	exercises map[uint]models.Exercise
+	sets      map[uint]models.Set
+
+	definitions []models.ExerciseDefinition // catálogo global
+
+	routineUsers     map[uint][]string             // user_make_routine
+	routineExercises map[uint][]uint               // routine_work_exercise
+	likes            map[uint]map[string]time.Time // routine_likes: usuario -> fecha
+
+	refreshTokens map[uint]models.RefreshToken
+	identities    []models.UserIdentity
+	userTokens    []models.UserToken
+	emailRequests []models.EmailRequest
+
+	reports           []models.RoutineReport
+	moderationActions []models.ModerationAction
+
+	workouts        map[uint]models.WorkoutSession
+	workoutSets     map[uint]models.WorkoutSet
+	personalRecords []models.PersonalRecord
+
+	lastRoutineID    uint
+	lastExerciseID   uint
+	lastSetID        uint
+	lastTokenID      uint
+	lastWorkoutID    uint
+	lastWorkoutSetID uint
+}
+
+// MemoryUserStore implementa UserStore en memoria
+type MemoryUserStore struct{ data *memoryData }
+
+// MemoryRoutineStore implementa RoutineStore en memoria
+type MemoryRoutineStore struct{ data *memoryData }
+
+// MemoryExerciseStore implementa ExerciseStore en memoria
+type MemoryExerciseStore struct{ data *memoryData }
+
+// MemoryTokenStore implementa TokenStore en memoria
+type MemoryTokenStore struct{ data *memoryData }
+
+// MemoryModerationStore implementa ModerationStore en memoria
+type MemoryModerationStore struct{ data *memoryData }
+
+// MemoryWorkoutStore implementa WorkoutStore en memoria
+type MemoryWorkoutStore struct{ data *memoryData }
+
+// MemoryStatsStore implementa StatsStore en memoria
+type MemoryStatsStore struct{ data *memoryData }
+
+// Memory agrupa los stores en memoria
+type Memory struct {
+	Users      *MemoryUserStore
+	Routines   *MemoryRoutineStore
+	Exercises  *MemoryExerciseStore
+	Tokens     *MemoryTokenStore
+	Moderation *MemoryModerationStore
+	Workouts   *MemoryWorkoutStore
+	Stats      *MemoryStatsStore
+}
+
+// NewMemory crea stores en memoria que comparten los mismos datos
+func NewMemory() *Memory {
+	data := &memoryData{
+		users:            map[string]models.User{},
+		routines:         map[uint]models.Routine{},
+		exercises:        map[uint]models.Exercise{},
+		sets:             map[uint]models.Set{},
+		routineUsers:     map[uint][]string{},
+		routineExercises: map[uint][]uint{},
+		likes:            map[uint]map[string]time.Time{},
+		refreshTokens:    map[uint]models.RefreshToken{},
+		workouts:         map[uint]models.WorkoutSession{},
+		workoutSets:      map[uint]models.WorkoutSet{},
+	}
+	return &Memory{
+		Users:      &MemoryUserStore{data},
+		Routines:   &MemoryRoutineStore{data},
+		Exercises:  &MemoryExerciseStore{data},
+		Tokens:     &MemoryTokenStore{data},
+		Moderation: &MemoryModerationStore{data},
+		Workouts:   &MemoryWorkoutStore{data},
+		Stats:      &MemoryStatsStore{data},
+	}
+}
+
+// Usuarios
+
+func (s *MemoryUserStore) FindByID(id string) (models.User, error) {
+	s.data.mu.RLock()
+	defer s.data.mu.RUnlock()
+	user, ok := s.data.users[id]
+	if !ok || user.DeletedAt.Valid {
+		return models.User{}, ErrNotFound
+	}
+	return user, nil
+}
+
+func (s *MemoryUserStore) FindByEmail(email string) (models.User, error) {
+	return s.findBy(func(user models.User) bool { return user.Email == email })
+}
+
+func (s *MemoryUserStore) FindByUsername(username string) (models.User, error) {
+	return s.findBy(func(user models.User) bool { return user.Username == username })
+}
+
+func (s *MemoryUserStore) findBy(match func(models.User) bool) (models.User, error) {
+	s.data.mu.RLock()
+	defer s.data.mu.RUnlock()
+	for _, user := range s.data.users {
+		if !user.DeletedAt.Valid && match(user) {
+			return user, nil
+		}
+	}
+	return models.User{}, ErrNotFound
+}
+
+func (s *MemoryUserStore) Create(user *models.User) error {
+	s.data.mu.Lock()
+	defer s.data.mu.Unlock()
+	for _, existing := range s.data.users {
+		if existing.Email == user.Email || existing.Username == user.Username {
+			return ErrDuplicate
+		}
+	}
+	// Mismo hook que ejecuta GORM: genera el ID y hashea la contraseña
+	if err := user.BeforeCreate(nil); err != nil {
+		return err
+	}
+	if user.Role == "" {
+		user.Role = "user" // Default de la columna
+	}
+	now := time.Now()
+	user.CreatedAt, user.UpdatedAt = now, now
+	stored := *user
+	stored.Routines = nil
+	s.data.users[user.ID] = stored
+	return nil
+}
+
+func (s *MemoryUserStore) UpdateUsername(id string, username string) error {
+	s.data.mu.Lock()
+	defer s.data.mu.Unlock()
+	user, ok := s.data.users[id]
+	if !ok {
+		return ErrNotFound
+	}
+	for _, existing := range s.data.users {
+		if existing.ID != id && existing.Username == username {
+			return ErrDuplicate
+		}
+	}
+	user.Username = username
+	user.UpdatedAt = time.Now()
+	s.data.users[id] = user
+	return nil
+}
+
+func (s *MemoryUserStore) UpdatePassword(id string, hashedPassword string) error {
+	s.data.mu.Lock()
+	defer s.data.mu.Unlock()
+	user, ok := s.data.users[id]
+	if !ok {
+		return ErrNotFound
+	}
+	user.Password = hashedPassword
+	user.TokenVersion++
+	user.UpdatedAt = time.Now()
+	s.data.users[id] = user
+	return nil
+}
+
+func (s *MemoryUserStore) MarkEmailVerified(id string) error {
+	s.data.mu.Lock()
+	defer s.data.mu.Unlock()
+	user, ok := s.data.users[id]
+	if !ok {
+		return ErrNotFound
+	}
+	user.EmailVerified = true
+	user.UpdatedAt = time.Now()
+	s.data.users[id] = user
+	return nil
+}
+
+func (s *MemoryUserStore) List(search string, limit int, offset int) ([]models.User, int64, error) {
+	s.data.mu.RLock()
+	defer s.data.mu.RUnlock()
+	search = strings.ToLower(search)
+	var users []models.User
+	for _, user := range s.data.users {
+		if user.DeletedAt.Valid {
+			continue
+		}
+		if strings.Contains(strings.ToLower(user.Username), search) || strings.Contains(strings.ToLower(user.Email), search) {
+			users = append(users, user)
+		}
+	}
+	// Los más recientes primero
+	sort.Slice(users, func(i, j int) bool { return users[i].CreatedAt.After(users[j].CreatedAt) })
+
+	total := int64(len(users))
+	if offset < 0 {
+		offset = 0
+	}
+	if offset >= len(users) {
+		return nil, total, nil
+	}
+	users = users[offset:]
+	if limit > 0 && limit < len(users) {
+		users = users[:limit]
+	}
+	return users, total, nil
+}
+
+func (s *MemoryUserStore) SetDisabled(id string, disabled bool) error {
+	s.data.mu.Lock()
+	defer s.data.mu.Unlock()
+	user, ok := s.data.users[id]
+	if !ok || user.DeletedAt.Valid {
+		return ErrNotFound
+	}
+	user.Disabled = disabled
+	user.UpdatedAt = time.Now()
+	s.data.users[id] = user
+	return nil
+}
+
+func (s *MemoryUserStore) FindIdentity(provider string, subject string) (models.UserIdentity, error) {
+	s.data.mu.RLock()
+	defer s.data.mu.RUnlock()
+	for _, identity := range s.data.identities {
+		if identity.Provider == provider && identity.Subject == subject {
+			return identity, nil
+		}
+	}
+	return models.UserIdentity{}, ErrNotFound
+}
+
+func (s *MemoryUserStore) CreateIdentity(identity *models.UserIdentity) error {
+	s.data.mu.Lock()
+	defer s.data.mu.Unlock()
+	for _, existing := range s.data.identities {
+		if existing.Provider == identity.Provider && existing.Subject == identity.Subject {
+			return ErrDuplicate
+		}
+	}
+	identity.ID = uint(len(s.data.identities) + 1)
+	identity.CreatedAt = time.Now()
+	s.data.identities = append(s.data.identities, *identity)
+	return nil
+}
+
+// Rutinas
+
+func (s *MemoryRoutineStore) List(q RoutineQuery) (RoutinePage, error) {
+	s.data.mu.RLock()
+	defer s.data.mu.RUnlock()
+
+	hasSearch := len(searchWords(q.Search)) > 0
+	sortBy, err := q.resolveSort(hasSearch)
+	if err != nil {
+		return RoutinePage{}, err
+	}
+
+	matches := s.data.filterRoutines(q)
+	// Sin ts_rank la relevancia empata y desempata el ID
+	sort.SliceStable(matches, func(i, j int) bool {
+		return cursorBefore(routineCursor(sortBy, matches[i]), routineCursor(sortBy, matches[j]))
+	})
+
+	page := RoutinePage{Total: int64(len(matches))}
+	if q.Cursor != nil {
+		start := len(matches)
+		for i, routine := range matches {
+			if cursorBefore(*q.Cursor, routineCursor(sortBy, routine)) {
+				start = i
+				break
+			}
+		}
+		matches = matches[start:]
+	} else {
+		matches = paginate(matches, 0, q.Offset)
+	}
+	if q.Limit > 0 && len(matches) > q.Limit {
+		matches = matches[:q.Limit]
+		next := routineCursor(sortBy, matches[len(matches)-1])
+		page.Next = &next
+	}
+	page.Routines = matches
+	return page, nil
+}
+
+func (s *MemoryRoutineStore) Facets(q RoutineQuery) (RoutineFacets, error) {
+	s.data.mu.RLock()
+	defer s.data.mu.RUnlock()
+
+	muscles := map[string]int64{}
+	equipment := map[string]int64{}
+	var exercises, durations []int
+	for _, routine := range s.data.filterRoutines(q) {
+		// Cada valor cuenta una vez por rutina
+		routineMuscles := map[string]bool{}
+		routineEquipment := map[string]bool{}
+		for _, exercise := range routine.Exercises {
+			if exercise.Definition == nil {
+				continue
+			}
+			for _, muscle := range append(append([]string{}, exercise.Definition.PrimaryMuscles...), exercise.Definition.SecondaryMuscles...) {
+				routineMuscles[muscle] = true
+			}
+			for _, item := range exercise.Definition.Equipment {
+				routineEquipment[item] = true
+			}
+		}
+		for muscle := range routineMuscles {
+			muscles[muscle]++
+		}
+		for item := range routineEquipment {
+			equipment[item]++
+		}
+		exercises = append(exercises, len(routine.Exercises))
+		durations = append(durations, estimatedMinutes(routine))
+	}
+
+	return RoutineFacets{
+		Muscles:   facetCounts(muscles),
+		Equipment: facetCounts(equipment),
+		Exercises: facetRanges(exerciseBuckets, exercises),
+		Duration:  facetRanges(durationBuckets, durations),
+	}, nil
+}
+
+// filterRoutines devuelve las rutinas que cumplen el filtro del listado, por ID
+func (d *memoryData) filterRoutines(q RoutineQuery) []models.Routine {
+	hasSearch := len(searchWords(q.Search)) > 0
+	var matches []models.Routine
+	for _, id := range d.sortedRoutineIDs() {
+		if q.UserID != "" && !contains(d.routineUsers[id], q.UserID) {
+			continue
+		}
+		routine := d.routine(id)
+		if q.PublicOnly && (!routine.Public || routine.Hidden || len(routine.Exercises) == 0 || len(routine.Users) == 0) {
+			continue // Igual que los EXISTS de la consulta en Postgres
+		}
+		if q.VisibleOnly && routine.Hidden {
+			continue
+		}
+		if hasSearch && !routineMatches(routine, q.Search) {
+			continue
+		}
+		if !routineFiltersMatch(routine, q.Filters) {
+			continue
+		}
+		matches = append(matches, routine)
+	}
+	return matches
+}
+
+// routineFiltersMatch aplica los filtros por facetas igual que GormRoutineStore.filter
+func routineFiltersMatch(routine models.Routine, f RoutineFilters) bool {
+	if f.MinExercises > 0 && len(routine.Exercises) < f.MinExercises {
+		return false
+	}
+	if f.MaxExercises > 0 && len(routine.Exercises) > f.MaxExercises {
+		return false
+	}
+	for _, muscle := range f.Muscles {
+		found := false
+		for _, exercise := range routine.Exercises {
+			if exercise.Definition != nil && (contains(exercise.Definition.PrimaryMuscles, muscle) || contains(exercise.Definition.SecondaryMuscles, muscle)) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if len(f.Equipment) > 0 {
+		for _, exercise := range routine.Exercises {
+			if exercise.Definition == nil {
+				continue
+			}
+			for _, item := range exercise.Definition.Equipment {
+				if !contains(f.Equipment, item) {
+					return false
+				}
+			}
+		}
+	}
+	minutes := estimatedMinutes(routine)
+	if f.MinDuration > 0 && minutes < f.MinDuration {
+		return false
+	}
+	if f.MaxDuration > 0 && minutes > f.MaxDuration {
+		return false
+	}
+	if f.Author != "" {
+		found := false
+		for _, user := range routine.Users {
+			if user.Username == f.Author {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if f.CreatedAfter != nil && !routine.CreatedAt.After(*f.CreatedAfter) {
+		return false
+	}
+	return true
+}
+
+// routineMatches aproxima la búsqueda de texto completo de Postgres: sin distinguir acentos,
+// cada palabra buscada debe ser el prefijo de alguna palabra de la rutina (sin stemming)
+func routineMatches(routine models.Routine, search string) bool {
+	fields := []string{routine.Name, routine.Description}
+	for _, exercise := range routine.Exercises {
+		fields = append(fields, exercise.Name)
+	}
+	for _, user := range routine.Users {
+		fields = append(fields, user.Username)
+	}
+	words := searchWords(strings.Join(fields, " "))
+	for _, term := range searchWords(search) {
+		found := false
+		for _, word := range words {
+			if strings.HasPrefix(word, term) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// accentFolder quita los acentos más comunes, como unaccent
+var accentFolder = strings.NewReplacer("á", "a", "é", "e", "í", "i", "ó", "o", "ú", "u", "ü", "u", "ñ", "n", "à", "a", "è", "e", "ì", "i", "ò", "o", "ù", "u", "ç", "c")
+
+// searchWords separa el texto en palabras en minúsculas y sin acentos
+func searchWords(text string) []string {
+	return strings.FieldsFunc(accentFolder.Replace(strings.ToLower(text)), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+func (s *MemoryRoutineStore) FindByID(id uint) (models.Routine, error) {
+	s.data.mu.RLock()
+	defer s.data.mu.RUnlock()
+	if !s.data.routineExists(id) {
+		return models.Routine{}, ErrNotFound
+	}
+	return s.data.routine(id), nil
+}
+
+func (s *MemoryRoutineStore) ListForks(id uint, limit int, offset int) ([]models.Routine, error) {
+	s.data.mu.RLock()
+	defer s.data.mu.RUnlock()
+	var forks []models.Routine
+	for _, forkID := range s.data.sortedRoutineIDs() {
+		fork := s.data.routines[forkID]
+		if fork.Public && !fork.Hidden && fork.ForkedFromID != nil && *fork.ForkedFromID == id {
+			forks = append(forks, s.data.routine(forkID))
+		}
+	}
+	// Las más recientes primero
+	sort.SliceStable(forks, func(i, j int) bool { return forks[i].CreatedAt.After(forks[j].CreatedAt) })
+	return paginate(forks, limit, offset), nil
+}
+
+func (s *MemoryRoutineStore) CountForks(id uint) (int64, error) {
+	s.data.mu.RLock()
+	defer s.data.mu.RUnlock()
+	return s.data.forkCount(id), nil
+}
+
+func (s *MemoryRoutineStore) Like(userID string, routineID uint) error {
+	s.data.mu.Lock()
+	defer s.data.mu.Unlock()
+	if !s.data.routineExists(routineID) {
+		return ErrNotFound
+	}
+	if s.data.likes[routineID] == nil {
+		s.data.likes[routineID] = map[string]time.Time{}
+	}
+	if _, ok := s.data.likes[routineID][userID]; !ok {
+		s.data.likes[routineID][userID] = time.Now()
+	}
+	return nil
+}
+
+func (s *MemoryRoutineStore) Unlike(userID string, routineID uint) error {
+	s.data.mu.Lock()
+	defer s.data.mu.Unlock()
+	delete(s.data.likes[routineID], userID)
+	return nil
+}
+
+func (s *MemoryRoutineStore) CountLikes(id uint) (int64, error) {
+	s.data.mu.RLock()
+	defer s.data.mu.RUnlock()
+	return int64(len(s.data.likes[id])), nil
+}
+
+func (s *MemoryRoutineStore) IsOwner(userID string, routineID uint) (bool, error) {
+	s.data.mu.RLock()
+	defer s.data.mu.RUnlock()
+	return contains(s.data.routineUsers[routineID], userID), nil
+}
+
+func (s *MemoryRoutineStore) NameTaken(ownerID string, name string, excludeID uint) (bool, error) {
+	s.data.mu.RLock()
+	defer s.data.mu.RUnlock()
+	return s.data.routineNameTaken(ownerID, name, excludeID), nil
+}
+
+func (s *MemoryRoutineStore) FindIDByName(ownerID string, name string) (uint, error) {
+	s.data.mu.RLock()
+	defer s.data.mu.RUnlock()
+	for id, routine := range s.data.routines {
+		if !routine.DeletedAt.Valid && routine.OwnerID == ownerID && routine.Name == name {
+			return id, nil
+		}
+	}
+	return 0, ErrNotFound
+}
+
+func (s *MemoryRoutineStore) Create(routine *models.Routine, userID string) error {
+	s.data.mu.Lock()
+	defer s.data.mu.Unlock()
+	if _, ok := s.data.users[userID]; !ok {
+		return ErrNotFound
+	}
+	if routine.OwnerID != "" && s.data.routineNameTaken(routine.OwnerID, routine.Name, 0) {
+		return ErrDuplicate
+	}
+
+	now := time.Now()
+	s.data.lastRoutineID++
+	routine.ID = s.data.lastRoutineID
+	routine.CreatedAt, routine.UpdatedAt = now, now
+
+	for i := range routine.Exercises {
+		s.data.createExercise(&routine.Exercises[i], now)
+		s.data.routineExercises[routine.ID] = append(s.data.routineExercises[routine.ID], routine.Exercises[i].ID)
+	}
+
+	stored := *routine
+	stored.Exercises, stored.Users = nil, nil
+	s.data.routines[routine.ID] = stored
+	s.data.routineUsers[routine.ID] = append(s.data.routineUsers[routine.ID], userID)
+	return nil
+}
+
+func (s *MemoryRoutineStore) UpdateName(id uint, name string) error {
+	s.data.mu.Lock()
+	defer s.data.mu.Unlock()
+	if !s.data.routineExists(id) {
+		return ErrNotFound
+	}
+	routine := s.data.routines[id]
+	if routine.OwnerID != "" && s.data.routineNameTaken(routine.OwnerID, name, id) {
+		return ErrDuplicate
+	}
+	routine.Name = name
+	routine.UpdatedAt = time.Now()
+	s.data.routines[id] = routine
+	return nil
+}
+
+func (s *MemoryRoutineStore) UpdateDescription(id uint, description string) error {
+	return s.update(id, func(routine *models.Routine) { routine.Description = description })
+}
+
+func (s *MemoryRoutineStore) UpdatePublic(id uint, public bool) error {
+	return s.update(id, func(routine *models.Routine) { routine.Public = public })
+}
+
+func (s *MemoryRoutineStore) UpdateHidden(id uint, hidden bool) error {
+	return s.update(id, func(routine *models.Routine) { routine.Hidden = hidden })
+}
+
+func (s *MemoryRoutineStore) update(id uint, change func(*models.Routine)) error {
+	s.data.mu.Lock()
+	defer s.data.mu.Unlock()
+	if !s.data.routineExists(id) {
+		return ErrNotFound
+	}
+	routine := s.data.routines[id]
+	change(&routine)
+	routine.UpdatedAt = time.Now()
+	s.data.routines[id] = routine
+	return nil
+}
+
+func (s *MemoryRoutineStore) Delete(id uint) error {
+	s.data.mu.Lock()
+	defer s.data.mu.Unlock()
+	if !s.data.routineExists(id) {
+		return ErrNotFound
+	}
+	now := time.Now()
+	for _, exerciseID := range s.data.routineExercises[id] {
+		s.data.deleteExercise(exerciseID, now)
+	}
+	routine := s.data.routines[id]
+	routine.DeletedAt.Time, routine.DeletedAt.Valid = now, true
+	s.data.routines[id] = routine
+	return nil
+}
+
+// Ejercicios
+
+func (s *MemoryExerciseStore) FindByID(id uint) (models.Exercise, error) {
+	s.data.mu.RLock()
+	defer s.data.mu.RUnlock()
+	exercise, ok := s.data.exercises[id]
+	if !ok || exercise.DeletedAt.Valid {
+		return models.Exercise{}, ErrNotFound
+	}
+	exercise.Sets = s.data.exerciseSets(id)
+	return exercise, nil
+}
+
+func (s *MemoryExerciseStore) IsOwner(userID string, exerciseID uint) (bool, error) {
+	s.data.mu.RLock()
+	defer s.data.mu.RUnlock()
+	for routineID, exerciseIDs := range s.data.routineExercises {
+		if containsID(exerciseIDs, exerciseID) && contains(s.data.routineUsers[routineID], userID) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (s *MemoryExerciseStore) Create(routineID uint, exercise *models.Exercise) error {
+	s.data.mu.Lock()
+	defer s.data.mu.Unlock()
+	if !s.data.routineExists(routineID) {
+		return ErrNotFound
+	}
+	s.data.createExercise(exercise, time.Now())
+	s.data.routineExercises[routineID] = append(s.data.routineExercises[routineID], exercise.ID)
+	return nil
+}
+
+func (s *MemoryExerciseStore) Update(exercise *models.Exercise) error {
+	s.data.mu.Lock()
+	defer s.data.mu.Unlock()
+	stored, ok := s.data.exercises[exercise.ID]
+	if !ok || stored.DeletedAt.Valid {
+		return ErrNotFound
+	}
+	stored.Name = exercise.Name
+	stored.DefinitionID = exercise.DefinitionID
+	stored.UpdatedAt = time.Now()
+	s.data.exercises[exercise.ID] = stored
+	return nil
+}
+
+func (s *MemoryExerciseStore) ReplaceSets(exerciseID uint, sets []SetInput) ([]models.Set, error) {
+	s.data.mu.Lock()
+	defer s.data.mu.Unlock()
+
+	now := time.Now()
+	current := map[uint]bool{}
+	for _, set := range s.data.exerciseSets(exerciseID) {
+		current[set.ID] = true
+	}
+
+	var finalSets []models.Set
+	for _, input := range sets {
+		if input.ID != 0 {
+			if !current[input.ID] {
+				continue
+			}
+			set := s.data.sets[input.ID]
+			// Solo cambia UpdatedAt si cambió el set, como en GORM
+			if set.Reps != input.Reps || set.Weight != input.Weight || set.Rest != input.Rest || set.Note != input.Note {
+				set.Reps, set.Weight, set.Rest, set.Note = input.Reps, input.Weight, input.Rest, input.Note
+				set.UpdatedAt = now
+			}
+			s.data.sets[set.ID] = set
+			finalSets = append(finalSets, set)
+			delete(current, set.ID)
+		} else {
+			set := models.Set{ExerciseID: exerciseID, Reps: input.Reps, Weight: input.Weight, Rest: input.Rest, Note: input.Note}
+			s.data.createSet(&set, now)
+			finalSets = append(finalSets, set)
+		}
+	}
+
+	for id := range current {
+		s.data.deleteSet(id, now)
+	}
+	return finalSets, nil
+}
+
+func (s *MemoryExerciseStore) Delete(id uint) error {
+	s.data.mu.Lock()
+	defer s.data.mu.Unlock()
+	exercise, ok := s.data.exercises[id]
+	if !ok || exercise.DeletedAt.Valid {
+		return ErrNotFound
+	}
+	s.data.deleteExercise(id, time.Now())
+	return nil
+}
+
+// AddDefinitions agrega ejercicios al catálogo en memoria
+func (s *MemoryExerciseStore) AddDefinitions(definitions ...models.ExerciseDefinition) {
+	s.data.mu.Lock()
+	defer s.data.mu.Unlock()
+	s.data.definitions = append(s.data.definitions, definitions...)
+}
+
+func (s *MemoryExerciseStore) ResolveDefinition(definitionID uint, name string) (*uint, error) {
+	s.data.mu.RLock()
+	defer s.data.mu.RUnlock()
+	normalized := strings.ToLower(strings.TrimSpace(name))
+	for i := range s.data.definitions {
+		definition := &s.data.definitions[i]
+		if definitionID != 0 {
+			if definition.ID == definitionID {
+				return &definition.ID, nil
+			}
+			continue
+		}
+		if normalized == "" {
+			return nil, nil
+		}
+		names := append([]string{definition.NameEn, definition.NameEs}, definition.Aliases...)
+		for _, candidate := range names {
+			if strings.ToLower(candidate) == normalized {
+				return &definition.ID, nil
+			}
+		}
+	}
+	if definitionID != 0 {
+		return nil, ErrNotFound
+	}
+	return nil, nil
+}
+
+func (s *MemoryExerciseStore) FindInRoutine(routineID uint, exerciseID uint) (models.Exercise, error) {
+	s.data.mu.RLock()
+	defer s.data.mu.RUnlock()
+	exercise, ok := s.data.exercises[exerciseID]
+	if !ok || exercise.DeletedAt.Valid || !containsID(s.data.routineExercises[routineID], exerciseID) {
+		return models.Exercise{}, ErrNotFound
+	}
+	exercise.Sets = s.data.exerciseSets(exerciseID)
+	return exercise, nil
+}
+
+// ListDefinitions aproxima los ILIKE y los @> de Postgres: el texto buscado puede estar en
+// cualquier parte del nombre o de un alias, sin distinguir mayúsculas
+func (s *MemoryExerciseStore) ListDefinitions(q CatalogQuery) ([]models.ExerciseDefinition, int64, error) {
+	s.data.mu.RLock()
+	defer s.data.mu.RUnlock()
+	search := strings.ToLower(q.Search)
+	var matches []models.ExerciseDefinition
+	for _, definition := range s.data.definitions {
+		if search != "" {
+			names := append([]string{definition.NameEn, definition.NameEs}, definition.Aliases...)
+			found := false
+			for _, name := range names {
+				if strings.Contains(strings.ToLower(name), search) {
+					found = true
+					break
+				}
+			}
+			if !found {
+				continue
+			}
+		}
+		if q.Muscle != "" && !contains(definition.PrimaryMuscles, q.Muscle) && !contains(definition.SecondaryMuscles, q.Muscle) {
+			continue
+		}
+		if q.Equipment != "" && !contains(definition.Equipment, q.Equipment) {
+			continue
+		}
+		if q.Pattern != "" && definition.MovementPattern != q.Pattern {
+			continue
+		}
+		matches = append(matches, definition)
+	}
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].NameEn < matches[j].NameEn })
+
+	total := int64(len(matches))
+	if q.Offset >= len(matches) {
+		return []models.ExerciseDefinition{}, total, nil
+	}
+	matches = matches[q.Offset:]
+	if q.Limit > 0 && len(matches) > q.Limit {
+		matches = matches[:q.Limit]
+	}
+	return matches, total, nil
+}
+
+func (s *MemoryExerciseStore) FindDefinition(id uint) (models.ExerciseDefinition, error) {
+	s.data.mu.RLock()
+	defer s.data.mu.RUnlock()
+	if definition := s.data.definition(&id); definition != nil {
+		return *definition, nil
+	}
+	return models.ExerciseDefinition{}, ErrNotFound
+}
+
+// Tokens de renovación
+
+func (s *MemoryTokenStore) Create(token *models.RefreshToken) error {
+	s.data.mu.Lock()
+	defer s.data.mu.Unlock()
+	for _, existing := range s.data.refreshTokens {
+		if existing.TokenHash == token.TokenHash {
+			return ErrDuplicate
+		}
+	}
+	s.data.lastTokenID++
+	token.ID = s.data.lastTokenID
+	token.CreatedAt = time.Now()
+	s.data.refreshTokens[token.ID] = *token
+	return nil
+}
+
+func (s *MemoryTokenStore) FindByHash(hash string) (models.RefreshToken, error) {
+	s.data.mu.RLock()
+	defer s.data.mu.RUnlock()
+	for _, token := range s.data.refreshTokens {
+		if token.TokenHash == hash {
+			return token, nil
+		}
+	}
+	return models.RefreshToken{}, ErrNotFound
+}
+
+func (s *MemoryTokenStore) MarkUsed(id uint) (bool, error) {
+	s.data.mu.Lock()
+	defer s.data.mu.Unlock()
+	token, ok := s.data.refreshTokens[id]
+	if !ok {
+		return false, ErrNotFound
+	}
+	if token.UsedAt != nil {
+		return false, nil
+	}
+	now := time.Now()
+	token.UsedAt = &now
+	s.data.refreshTokens[id] = token
+	return true, nil
+}
+
+func (s *MemoryTokenStore) RevokeFamily(familyID string) error {
+	s.data.mu.Lock()
+	defer s.data.mu.Unlock()
+	now := time.Now()
+	for id, token := range s.data.refreshTokens {
+		if token.FamilyID == familyID && token.RevokedAt == nil {
+			token.RevokedAt = &now
+			s.data.refreshTokens[id] = token
+		}
+	}
+	return nil
+}
+
+func (s *MemoryTokenStore) FamilyActive(familyID string) (bool, error) {
+	s.data.mu.RLock()
+	defer s.data.mu.RUnlock()
+	for _, token := range s.data.refreshTokens {
+		if token.FamilyID == familyID && token.RevokedAt == nil {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (s *MemoryTokenStore) RevokeUser(userID string) error {
+	s.data.mu.Lock()
+	defer s.data.mu.Unlock()
+	now := time.Now()
+	for id, token := range s.data.refreshTokens {
+		if token.UserID == userID && token.RevokedAt == nil {
+			token.RevokedAt = &now
+			s.data.refreshTokens[id] = token
+		}
+	}
+	return nil
+}
+
+func (s *MemoryTokenStore) CreateUserToken(token *models.UserToken) error {
+	s.data.mu.Lock()
+	defer s.data.mu.Unlock()
+	for _, existing := range s.data.userTokens {
+		if existing.TokenHash == token.TokenHash {
+			return ErrDuplicate
+		}
+	}
+	token.ID = uint(len(s.data.userTokens) + 1)
+	token.CreatedAt = time.Now()
+	s.data.userTokens = append(s.data.userTokens, *token)
+	return nil
+}
+
+func (s *MemoryTokenStore) ConsumeUserToken(purpose string, hash string) (models.UserToken, error) {
+	s.data.mu.Lock()
+	defer s.data.mu.Unlock()
+	now := time.Now()
+	for i, token := range s.data.userTokens {
+		if token.TokenHash != hash || token.Purpose != purpose {
+			continue
+		}
+		if token.UsedAt != nil || now.After(token.ExpiresAt) {
+			return models.UserToken{}, ErrNotFound
+		}
+		token.UsedAt = &now
+		s.data.userTokens[i] = token
+		return token, nil
+	}
+	return models.UserToken{}, ErrNotFound
+}
+
+func (s *MemoryTokenStore) InvalidateUserTokens(userID string, purpose string) error {
+	s.data.mu.Lock()
+	defer s.data.mu.Unlock()
+	now := time.Now()
+	for i, token := range s.data.userTokens {
+		if token.UserID == userID && token.Purpose == purpose && token.UsedAt == nil {
+			token.UsedAt = &now
+			s.data.userTokens[i] = token
+		}
+	}
+	return nil
+}
+
+func (s *MemoryTokenStore) CreateEmailRequest(request *models.EmailRequest) error {
+	s.data.mu.Lock()
+	defer s.data.mu.Unlock()
+	request.ID = uint(len(s.data.emailRequests) + 1)
+	request.CreatedAt = time.Now()
+	s.data.emailRequests = append(s.data.emailRequests, *request)
+	return nil
+}
+
+func (s *MemoryTokenStore) CountEmailRequests(email string, purpose string, since time.Time) (int64, error) {
+	s.data.mu.RLock()
+	defer s.data.mu.RUnlock()
+	var count int64
+	for _, request := range s.data.emailRequests {
+		if request.Email == email && request.Purpose == purpose && request.CreatedAt.After(since) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// Moderación
+
+func (s *MemoryModerationStore) CreateReport(report *models.RoutineReport) error {
+	s.data.mu.Lock()
+	defer s.data.mu.Unlock()
+	report.ID = uint(len(s.data.reports) + 1)
+	report.CreatedAt = time.Now()
+	if report.Status == "" {
+		report.Status = models.ReportPending // Default de la columna
+	}
+	stored := *report
+	stored.Routine = nil
+	s.data.reports = append(s.data.reports, stored)
+	return nil
+}
+
+func (s *MemoryModerationStore) HasPendingReport(routineID uint, reporterID string) (bool, error) {
+	s.data.mu.RLock()
+	defer s.data.mu.RUnlock()
+	for _, report := range s.data.reports {
+		if report.RoutineID == routineID && report.ReporterID == reporterID && report.Status == models.ReportPending {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (s *MemoryModerationStore) ListReports(status string, limit int, offset int) ([]models.RoutineReport, int64, error) {
+	s.data.mu.RLock()
+	defer s.data.mu.RUnlock()
+	var reports []models.RoutineReport
+	for _, report := range s.data.reports {
+		if status == "" || report.Status == status {
+			reports = append(reports, s.data.report(report))
+		}
+	}
+
+	total := int64(len(reports))
+	if offset < 0 {
+		offset = 0
+	}
+	if offset >= len(reports) {
+		return nil, total, nil
+	}
+	reports = reports[offset:]
+	if limit > 0 && limit < len(reports) {
+		reports = reports[:limit]
+	}
+	return reports, total, nil
+}
+
+func (s *MemoryModerationStore) FindReport(id uint) (models.RoutineReport, error) {
+	s.data.mu.RLock()
+	defer s.data.mu.RUnlock()
+	if id == 0 || int(id) > len(s.data.reports) {
+		return models.RoutineReport{}, ErrNotFound
+	}
+	return s.data.report(s.data.reports[id-1]), nil
+}
+
+func (s *MemoryModerationStore) Apply(change *ModerationChange) error {
+	if err := change.validate(); err != nil {
+		return err
+	}
+	s.data.mu.Lock()
+	defer s.data.mu.Unlock()
+	entry := &change.Entry
+
+	// Primero se comprueba todo para no dejar cambios a medias
+	if (change.RoutinePublic != nil || change.RoutineHidden != nil) && !s.data.routineExists(*entry.RoutineID) {
+		return ErrNotFound
+	}
+	if change.UserDisabled != nil {
+		if user, ok := s.data.users[*entry.TargetUserID]; !ok || user.DeletedAt.Valid {
+			return ErrNotFound
+		}
+	}
+
+	now := time.Now()
+	if change.RoutinePublic != nil || change.RoutineHidden != nil {
+		routine := s.data.routines[*entry.RoutineID]
+		if change.RoutinePublic != nil {
+			routine.Public = *change.RoutinePublic
+		}
+		if change.RoutineHidden != nil {
+			routine.Hidden = *change.RoutineHidden
+		}
+		routine.UpdatedAt = now
+		s.data.routines[routine.ID] = routine
+	}
+
+	if change.UserDisabled != nil {
+		user := s.data.users[*entry.TargetUserID]
+		user.Disabled = *change.UserDisabled
+		user.UpdatedAt = now
+		s.data.users[user.ID] = user
+		if *change.UserDisabled {
+			for id, token := range s.data.refreshTokens {
+				if token.UserID == user.ID && token.RevokedAt == nil {
+					token.RevokedAt = &now
+					s.data.refreshTokens[id] = token
+				}
+			}
+		}
+	}
+
+	if change.ReportStatus != "" {
+		moderatorID := entry.ModeratorID
+		for i, report := range s.data.reports {
+			if report.RoutineID == *entry.RoutineID && report.Status == models.ReportPending {
+				report.Status, report.Action = change.ReportStatus, entry.Action
+				report.ResolvedBy, report.ResolvedAt = &moderatorID, &now
+				s.data.reports[i] = report
+			}
+		}
+	}
+
+	entry.ID = uint(len(s.data.moderationActions) + 1)
+	entry.CreatedAt = now
+	s.data.moderationActions = append(s.data.moderationActions, *entry)
+	return nil
+}
+
+func (s *MemoryModerationStore) ListActions(limit int, offset int) ([]models.ModerationAction, int64, error) {
+	s.data.mu.RLock()
+	defer s.data.mu.RUnlock()
+	total := int64(len(s.data.moderationActions))
+	// Lo más reciente primero
+	var actions []models.ModerationAction
+	for i := len(s.data.moderationActions) - 1; i >= 0; i-- {
+		actions = append(actions, s.data.moderationActions[i])
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	if offset >= len(actions) {
+		return nil, total, nil
+	}
+	actions = actions[offset:]
+	if limit > 0 && limit < len(actions) {
+		actions = actions[:limit]
+	}
+	return actions, total, nil
+}
+
+// Sesiones de entrenamiento
+
+func (s *MemoryWorkoutStore) ListByUser(userID string) ([]models.WorkoutSession, error) {
+	s.data.mu.RLock()
+	defer s.data.mu.RUnlock()
+	workouts := []models.WorkoutSession{}
+	for id, workout := range s.data.workouts {
+		if workout.UserID == userID && !workout.DeletedAt.Valid {
+			workouts = append(workouts, s.data.workout(id))
+		}
+	}
+	sort.Slice(workouts, func(i, j int) bool { return workouts[i].StartedAt.After(workouts[j].StartedAt) })
+	return workouts, nil
+}
+
+func (s *MemoryWorkoutStore) FindByID(id uint) (models.WorkoutSession, error) {
+	s.data.mu.RLock()
+	defer s.data.mu.RUnlock()
+	workout, ok := s.data.workouts[id]
+	if !ok || workout.DeletedAt.Valid {
+		return models.WorkoutSession{}, ErrNotFound
+	}
+	return s.data.workout(id), nil
+}
+
+func (s *MemoryWorkoutStore) Create(workout *models.WorkoutSession) error {
+	s.data.mu.Lock()
+	defer s.data.mu.Unlock()
+	s.data.lastWorkoutID++
+	workout.ID = s.data.lastWorkoutID
+	now := time.Now()
+	workout.CreatedAt, workout.UpdatedAt = now, now
+	stored := *workout
+	stored.Sets = nil
+	s.data.workouts[workout.ID] = stored
+	return nil
+}
+
+func (s *MemoryWorkoutStore) LogSet(userID string, set *models.WorkoutSet) error {
+	s.data.mu.Lock()
+	defer s.data.mu.Unlock()
+	s.data.lastWorkoutSetID++
+	set.ID = s.data.lastWorkoutSetID
+	now := time.Now()
+	set.CreatedAt, set.UpdatedAt = now, now
+
+	var history []models.PersonalRecord
+	for _, record := range s.data.personalRecords {
+		if record.UserID == userID && record.ExerciseID == set.ExerciseID {
+			history = append(history, record)
+		}
+	}
+	newRecords := records.Detect(records.Current(history), *set)
+	for i := range newRecords {
+		newRecords[i].ID = uint(len(s.data.personalRecords) + 1)
+		newRecords[i].UserID = userID
+		newRecords[i].CreatedAt = now
+		s.data.personalRecords = append(s.data.personalRecords, newRecords[i])
+	}
+	set.Records = newRecords
+
+	stored := *set
+	stored.Records = nil
+	s.data.workoutSets[set.ID] = stored
+	return nil
+}
+
+func (s *MemoryWorkoutStore) Finish(workout *models.WorkoutSession) error {
+	s.data.mu.Lock()
+	defer s.data.mu.Unlock()
+	stored, ok := s.data.workouts[workout.ID]
+	if !ok || stored.DeletedAt.Valid {
+		return ErrNotFound
+	}
+	stored.FinishedAt, stored.DurationSeconds, stored.Notes = workout.FinishedAt, workout.DurationSeconds, workout.Notes
+	stored.UpdatedAt = time.Now()
+	s.data.workouts[workout.ID] = stored
+	return nil
+}
+
+func (s *MemoryWorkoutStore) Delete(id uint) error {
+	s.data.mu.Lock()
+	defer s.data.mu.Unlock()
+	workout, ok := s.data.workouts[id]
+	if !ok || workout.DeletedAt.Valid {
+		return ErrNotFound
+	}
+	now := time.Now()
+	for setID, set := range s.data.workoutSets {
+		if set.WorkoutSessionID == id && !set.DeletedAt.Valid {
+			set.DeletedAt.Time, set.DeletedAt.Valid = now, true
+			s.data.workoutSets[setID] = set
+		}
+	}
+	// PersonalRecord no tiene DeletedAt: se borran
+	var kept []models.PersonalRecord
+	for _, record := range s.data.personalRecords {
+		if record.WorkoutSessionID != id {
+			kept = append(kept, record)
+		}
+	}
+	s.data.personalRecords = kept
+	workout.DeletedAt.Time, workout.DeletedAt.Valid = now, true
+	s.data.workouts[id] = workout
+	return nil
+}
+
+func (s *MemoryWorkoutStore) ListRecords(userID string, exerciseID uint) ([]models.PersonalRecord, error) {
+	s.data.mu.RLock()
+	defer s.data.mu.RUnlock()
+	history := []models.PersonalRecord{}
+	for _, record := range s.data.personalRecords {
+		if record.UserID == userID && (exerciseID == 0 || record.ExerciseID == exerciseID) {
+			history = append(history, record)
+		}
+	}
+	sort.SliceStable(history, func(i, j int) bool { return history[i].AchievedAt.After(history[j].AchievedAt) })
+	return history, nil
+}
+
+// Estadísticas
+
+func (s *MemoryStatsStore) VolumeEntries(userID string, source string, from time.Time, to time.Time) ([]analytics.SetEntry, error) {
+	s.data.mu.RLock()
+	defer s.data.mu.RUnlock()
+	var entries []analytics.SetEntry
+	if source == analytics.SourcePlanned {
+		for _, workout := range s.data.workouts {
+			if workout.UserID != userID || workout.DeletedAt.Valid || workout.StartedAt.Before(from) || workout.StartedAt.After(to) {
+				continue
+			}
+			for _, exerciseID := range s.data.routineExercises[workout.RoutineID] {
+				exercise, ok := s.data.exercises[exerciseID]
+				if !ok || !existedAt(exercise.CreatedAt, exercise.DeletedAt, workout.StartedAt) {
+					continue
+				}
+				for _, set := range s.data.plannedSets(exerciseID, workout.StartedAt) {
+					entries = append(entries, analytics.SetEntry{
+						PerformedAt: workout.StartedAt,
+						Exercise:    exercise.Name,
+						Muscles:     s.data.primaryMuscles(exercise.DefinitionID),
+						Reps:        set.Reps,
+						Weight:      set.Weight,
+					})
+				}
+			}
+		}
+	} else {
+		for _, set := range s.data.loggedSets(userID) {
+			if set.PerformedAt.Before(from) || set.PerformedAt.After(to) {
+				continue
+			}
+			// Como el LEFT JOIN de Postgres, también con el ejercicio eliminado
+			entries = append(entries, analytics.SetEntry{
+				PerformedAt: set.PerformedAt,
+				Exercise:    set.ExerciseName,
+				Muscles:     s.data.primaryMuscles(s.data.exercises[set.ExerciseID].DefinitionID),
+				Reps:        set.Reps,
+				Weight:      set.Weight,
+			})
+		}
+	}
+	return entries, nil
+}
+
+func (s *MemoryStatsStore) ExerciseEntries(userID string, exerciseID uint, source string, to time.Time) ([]analytics.SetEntry, error) {
+	s.data.mu.RLock()
+	defer s.data.mu.RUnlock()
+	var entries []analytics.SetEntry
+	if source == analytics.SourcePlanned {
+		exercise, ok := s.data.exercises[exerciseID]
+		if !ok {
+			return nil, nil
+		}
+		for _, workout := range s.data.workouts {
+			if workout.UserID != userID || workout.DeletedAt.Valid || workout.StartedAt.After(to) || !containsID(s.data.routineExercises[workout.RoutineID], exerciseID) {
+				continue
+			}
+			if !existedAt(exercise.CreatedAt, exercise.DeletedAt, workout.StartedAt) {
+				continue
+			}
+			for _, set := range s.data.plannedSets(exerciseID, workout.StartedAt) {
+				entries = append(entries, analytics.SetEntry{PerformedAt: workout.StartedAt, Exercise: exercise.Name, Reps: set.Reps, Weight: set.Weight})
+			}
+		}
+	} else {
+		for _, set := range s.data.loggedSets(userID) {
+			if set.ExerciseID == exerciseID && !set.PerformedAt.After(to) {
+				entries = append(entries, analytics.SetEntry{PerformedAt: set.PerformedAt, Exercise: set.ExerciseName, Reps: set.Reps, Weight: set.Weight})
+			}
+		}
+	}
+	sortEntries(entries)
+	return entries, nil
+}
+
+// Auxiliares (se llaman con el mutex tomado)
+
+// report agrega la rutina al reporte, como el Preload("Routine")
+func (d *memoryData) report(report models.RoutineReport) models.RoutineReport {
+	if routine, ok := d.routines[report.RoutineID]; ok && !routine.DeletedAt.Valid {
+		report.Routine = &routine
+	}
+	return report
+}
+
+func (d *memoryData) routineExists(id uint) bool {
+	routine, ok := d.routines[id]
+	return ok && !routine.DeletedAt.Valid
+}
+
+// sortedRoutineIDs devuelve los IDs de las rutinas vigentes en orden de creación
+func (d *memoryData) sortedRoutineIDs() []uint {
+	ids := make([]uint, 0, len(d.routines))
+	for id, routine := range d.routines {
+		if !routine.DeletedAt.Valid {
+			ids = append(ids, id)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
+
+// routine arma la rutina con sus ejercicios, sets, usuarios y cantidad de copias
+func (d *memoryData) routine(id uint) models.Routine {
+	routine := d.routines[id]
+	routine.ForkCount = d.forkCount(id)
+	routine.LikeCount = int64(len(d.likes[id]))
+	for _, exerciseID := range d.routineExercises[id] {
+		exercise, ok := d.exercises[exerciseID]
+		if !ok || exercise.DeletedAt.Valid {
+			continue
+		}
+		exercise.Sets = d.exerciseSets(exerciseID)
+		exercise.Definition = d.definition(exercise.DefinitionID)
+		routine.Exercises = append(routine.Exercises, exercise)
+	}
+	for _, userID := range d.routineUsers[id] {
+		if user, ok := d.users[userID]; ok {
+			routine.Users = append(routine.Users, user)
+		}
+	}
+	return routine
+}
+
+// definition devuelve el ejercicio del catálogo con ese ID, o nil
+func (d *memoryData) definition(id *uint) *models.ExerciseDefinition {
+	if id == nil {
+		return nil
+	}
+	for i := range d.definitions {
+		if d.definitions[i].ID == *id {
+			definition := d.definitions[i]
+			return &definition
+		}
+	}
+	return nil
+}
+
+// routineNameTaken indica si el dueño tiene otra rutina sin eliminar con ese nombre, como el
+// índice idx_routines_owner_name
+func (d *memoryData) routineNameTaken(ownerID string, name string, excludeID uint) bool {
+	for id, routine := range d.routines {
+		if id != excludeID && !routine.DeletedAt.Valid && routine.OwnerID == ownerID && routine.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func (d *memoryData) forkCount(id uint) int64 {
+	var count int64
+	for _, routine := range d.routines {
+		if !routine.DeletedAt.Valid && routine.ForkedFromID != nil && *routine.ForkedFromID == id {
+			count++
+		}
+	}
+	return count
+}
+
+func (d *memoryData) exerciseSets(exerciseID uint) []models.Set {
+	var sets []models.Set
+	for _, set := range d.sets {
+		if set.ExerciseID == exerciseID && !set.DeletedAt.Valid {
+			sets = append(sets, set)
+		}
+	}
+	sort.Slice(sets, func(i, j int) bool { return sets[i].ID < sets[j].ID })
+	return sets
+}
+
+// plannedSets devuelve los sets del ejercicio que regían en at, como existedAtSession y
+// plannedSetAtSession en GORM: creados y modificados por última vez antes, y no eliminados antes
+func (d *memoryData) plannedSets(exerciseID uint, at time.Time) []models.Set {
+	var sets []models.Set
+	for _, set := range d.sets {
+		if set.ExerciseID == exerciseID && existedAt(set.CreatedAt, set.DeletedAt, at) && !set.UpdatedAt.After(at) {
+			sets = append(sets, set)
+		}
+	}
+	sort.Slice(sets, func(i, j int) bool { return sets[i].ID < sets[j].ID })
+	return sets
+}
+
+// existedAt indica si una fila creada en createdAt y eliminada en deletedAt existía en at
+func existedAt(createdAt time.Time, deletedAt gorm.DeletedAt, at time.Time) bool {
+	return !createdAt.After(at) && (!deletedAt.Valid || deletedAt.Time.After(at))
+}
+
+func (d *memoryData) createExercise(exercise *models.Exercise, now time.Time) {
+	d.lastExerciseID++
+	exercise.ID = d.lastExerciseID
+	exercise.CreatedAt, exercise.UpdatedAt = now, now
+	for i := range exercise.Sets {
+		exercise.Sets[i].ExerciseID = exercise.ID
+		d.createSet(&exercise.Sets[i], now)
+	}
+	stored := *exercise
+	stored.Sets, stored.Routines, stored.Definition = nil, nil, nil
+	d.exercises[exercise.ID] = stored
+}
+
+func (d *memoryData) createSet(set *models.Set, now time.Time) {
+	d.lastSetID++
+	set.ID = d.lastSetID
+	set.CreatedAt, set.UpdatedAt = now, now
+	d.sets[set.ID] = *set
+}
+
+func (d *memoryData) deleteExercise(id uint, now time.Time) {
+	for _, set := range d.exerciseSets(id) {
+		d.deleteSet(set.ID, now)
+	}
+	exercise := d.exercises[id]
+	exercise.DeletedAt.Time, exercise.DeletedAt.Valid = now, true
+	d.exercises[id] = exercise
+}
+
+func (d *memoryData) deleteSet(id uint, now time.Time) {
+	set := d.sets[id]
+	set.DeletedAt.Time, set.DeletedAt.Valid = now, true
+	d.sets[id] = set
+}
+
+// workout arma la sesión con sus sets en orden y los récords que marcó cada set
+func (d *memoryData) workout(id uint) models.WorkoutSession {
+	workout := d.workouts[id]
+	workout.Sets = []models.WorkoutSet{}
+	for _, set := range d.workoutSets {
+		if set.WorkoutSessionID != id || set.DeletedAt.Valid {
+			continue
+		}
+		set.Records = []models.PersonalRecord{}
+		for _, record := range d.personalRecords {
+			if record.WorkoutSetID == set.ID {
+				set.Records = append(set.Records, record)
+			}
+		}
+		workout.Sets = append(workout.Sets, set)
+	}
+	sort.Slice(workout.Sets, func(i, j int) bool {
+		if !workout.Sets[i].PerformedAt.Equal(workout.Sets[j].PerformedAt) {
+			return workout.Sets[i].PerformedAt.Before(workout.Sets[j].PerformedAt)
+		}
+		return workout.Sets[i].ID < workout.Sets[j].ID
+	})
+	return workout
+}
+
+// loggedSets devuelve los sets vigentes de las sesiones vigentes del usuario
+func (d *memoryData) loggedSets(userID string) []models.WorkoutSet {
+	var sets []models.WorkoutSet
+	for _, set := range d.workoutSets {
+		workout, ok := d.workouts[set.WorkoutSessionID]
+		if ok && workout.UserID == userID && !workout.DeletedAt.Valid && !set.DeletedAt.Valid {
+			sets = append(sets, set)
+		}
+	}
+	sort.Slice(sets, func(i, j int) bool { return sets[i].ID < sets[j].ID })
+	return sets
+}
+
+// primaryMuscles devuelve los músculos principales del ejercicio del catálogo, o nil
+func (d *memoryData) primaryMuscles(definitionID *uint) []string {
+	if definition := d.definition(definitionID); definition != nil {
+		return definition.PrimaryMuscles
+	}
+	return nil
+}
+
+func paginate(routines []models.Routine, limit int, offset int) []models.Routine {
+	if offset < 0 {
+		offset = 0
+	}
+	if offset >= len(routines) {
+		return []models.Routine{}
+	}
+	end := len(routines)
+	if limit > 0 && offset+limit < end {
+		end = offset + limit
+	}
+	return routines[offset:end]
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func containsID(values []uint, value uint) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,226 @@
+// Package store define el acceso a datos de usuarios, rutinas, ejercicios y entrenamientos detrás
+// de interfaces, con una implementación sobre GORM/Postgres y otra en memoria para las pruebas de
+// los handlers. La de memoria aproxima las consultas de Postgres: la búsqueda de texto completo
+// compara prefijos de palabras sin stemming ni ranking, así que las pruebas que dependan de esos
+// detalles deben usar Postgres.
+package store
+
+import (
+	"errors"
+	"time"
+
+	"github.com/danilsgit/gym-stats-backend/analytics"
+	"github.com/danilsgit/gym-stats-backend/models"
+)
+
+// ErrNotFound se devuelve cuando el registro buscado no existe (o está eliminado)
+var ErrNotFound = errors.New("registro no encontrado")
+
+// ErrDuplicate se devuelve cuando se viola una restricción única
+var ErrDuplicate = errors.New("registro duplicado")
+
+// SetInput son los datos de un set al reemplazar los sets de un ejercicio.
+// Si ID es distinto de cero se actualiza el set existente, si no se crea uno nuevo.
+type SetInput struct {
+	ID     uint
+	Reps   int
+	Weight float64
+	Rest   float64
+	Note   string
+}
+
+// CatalogQuery es la búsqueda en el catálogo global de ejercicios; los campos vacíos no filtran
+type CatalogQuery struct {
+	Search    string // Nombre en inglés o español, o alguno de sus alias
+	Muscle    string // Músculo principal o secundario
+	Equipment string
+	Pattern   string // Patrón de movimiento
+	Limit     int
+	Offset    int
+}
+
+// UserStore accede a los usuarios
+type UserStore interface {
+	FindByID(id string) (models.User, error)
+	FindByEmail(email string) (models.User, error)
+	FindByUsername(username string) (models.User, error)
+	// Create guarda el usuario; la contraseña se hashea en models.User.BeforeCreate
+	Create(user *models.User) error
+	UpdateUsername(id string, username string) error
+	// UpdatePassword guarda la contraseña ya hasheada y aumenta la versión de los tokens del usuario
+	UpdatePassword(id string, hashedPassword string) error
+	MarkEmailVerified(id string) error
+	// List busca usuarios por username o correo y devuelve la página pedida junto con el total
+	List(search string, limit int, offset int) ([]models.User, int64, error)
+	SetDisabled(id string, disabled bool) error
+	// FindIdentity busca la cuenta de un proveedor de inicio de sesión social
+	FindIdentity(provider string, subject string) (models.UserIdentity, error)
+	// CreateIdentity vincula la cuenta del proveedor con el usuario
+	CreateIdentity(identity *models.UserIdentity) error
+}
+
+// RoutineStore accede a las rutinas con sus ejercicios y sets
+type RoutineStore interface {
+	// List devuelve una página de rutinas según el filtro, el orden y el cursor, junto con el total
+	// de rutinas que cumplen el filtro. La búsqueda es de texto completo sobre el nombre, los
+	// ejercicios, la descripción y el autor, sin distinguir acentos.
+	List(query RoutineQuery) (RoutinePage, error)
+	// Facets cuenta las rutinas del listado por músculo, equipamiento, cantidad de ejercicios y
+	// duración; ignora el orden y la paginación
+	Facets(query RoutineQuery) (RoutineFacets, error)
+	// FindByID devuelve la rutina con sus ejercicios, sets y usuarios
+	FindByID(id uint) (models.Routine, error)
+	// ListForks devuelve las copias públicas y no ocultas de una rutina
+	ListForks(id uint, limit int, offset int) ([]models.Routine, error)
+	// CountForks cuenta las copias (públicas y privadas) de una rutina
+	CountForks(id uint) (int64, error)
+	// Like y Unlike marcan o desmarcan el me gusta del usuario; repetirlos no es un error
+	Like(userID string, routineID uint) error
+	Unlike(userID string, routineID uint) error
+	CountLikes(id uint) (int64, error)
+	// IsOwner indica si la rutina pertenece al usuario según user_make_routine
+	IsOwner(userID string, routineID uint) (bool, error)
+	// NameTaken indica si el dueño tiene otra rutina vigente con ese nombre
+	NameTaken(ownerID string, name string, excludeID uint) (bool, error)
+	// FindIDByName devuelve el ID de la rutina vigente del dueño con ese nombre (único por dueño)
+	FindIDByName(ownerID string, name string) (uint, error)
+	// Create guarda la rutina con sus ejercicios y sets y la asocia al usuario en una transacción
+	Create(routine *models.Routine, userID string) error
+	UpdateName(id uint, name string) error
+	UpdateDescription(id uint, description string) error
+	UpdatePublic(id uint, public bool) error
+	// UpdateHidden oculta la rutina (o la vuelve a mostrar) por moderación
+	UpdateHidden(id uint, hidden bool) error
+	// Delete elimina la rutina, sus ejercicios y sus sets en una transacción
+	Delete(id uint) error
+}
+
+// ExerciseStore accede a los ejercicios de las rutinas con sus sets y al catálogo global
+type ExerciseStore interface {
+	// FindByID devuelve el ejercicio con sus sets
+	FindByID(id uint) (models.Exercise, error)
+	// IsOwner indica si el ejercicio pertenece a alguna rutina del usuario
+	IsOwner(userID string, exerciseID uint) (bool, error)
+	// Create guarda el ejercicio con sus sets y lo asocia a la rutina en una transacción
+	Create(routineID uint, exercise *models.Exercise) error
+	// Update guarda el nombre y el ejercicio del catálogo
+	Update(exercise *models.Exercise) error
+	// ReplaceSets actualiza, crea y elimina los sets del ejercicio para que queden los enviados.
+	// Los IDs que no pertenecen al ejercicio se ignoran. Es una sola transacción.
+	ReplaceSets(exerciseID uint, sets []SetInput) ([]models.Set, error)
+	// Delete elimina el ejercicio y sus sets en una transacción
+	Delete(id uint) error
+	// ResolveDefinition devuelve el ID del catálogo para un ejercicio: definitionID si se envía
+	// (ErrNotFound si no existe) o el que coincida con el nombre o alguno de sus alias (nil si ninguno)
+	ResolveDefinition(definitionID uint, name string) (*uint, error)
+	// FindInRoutine devuelve el ejercicio con sus sets si pertenece a la rutina (ErrNotFound si no)
+	FindInRoutine(routineID uint, exerciseID uint) (models.Exercise, error)
+	// ListDefinitions busca en el catálogo, ordenado por el nombre en inglés, y devuelve el total
+	ListDefinitions(query CatalogQuery) ([]models.ExerciseDefinition, int64, error)
+	FindDefinition(id uint) (models.ExerciseDefinition, error)
+}
+
+// WorkoutStore accede a las sesiones de entrenamiento, sus sets y los récords personales
+type WorkoutStore interface {
+	// ListByUser devuelve las sesiones del usuario, las más recientes primero, con sus sets en
+	// orden y los récords que marcó cada set
+	ListByUser(userID string) ([]models.WorkoutSession, error)
+	// FindByID devuelve la sesión con sus sets en orden y los récords que marcó cada set
+	FindByID(id uint) (models.WorkoutSession, error)
+	Create(workout *models.WorkoutSession) error
+	// LogSet guarda el set y los récords personales del usuario que supera en una transacción;
+	// los récords nuevos quedan en set.Records
+	LogSet(userID string, set *models.WorkoutSet) error
+	// Finish guarda la fecha de fin, la duración y las notas
+	Finish(workout *models.WorkoutSession) error
+	// Delete elimina la sesión, sus sets y los récords que marcó en una transacción
+	Delete(id uint) error
+	// ListRecords devuelve el historial de récords del usuario, lo más reciente primero. Si
+	// exerciseID es distinto de cero solo los de ese ejercicio.
+	ListRecords(userID string, exerciseID uint) ([]models.PersonalRecord, error)
+}
+
+// StatsStore obtiene los sets de un usuario para las estadísticas de entrenamiento
+type StatsStore interface {
+	// VolumeEntries devuelve los sets entre from y to con los músculos principales de su
+	// ejercicio en el catálogo. Los registrados (analytics.SourceLogged) se fechan cuando se
+	// hicieron; los planificados (analytics.SourcePlanned) son los sets de la rutina que regían
+	// cuando empezó cada sesión (creados y editados por última vez antes, y no eliminados antes),
+	// con la fecha de la sesión. Un set editado después de una sesión deja de contar en ella.
+	VolumeEntries(userID string, source string, from time.Time, to time.Time) ([]analytics.SetEntry, error)
+	// ExerciseEntries devuelve los sets del ejercicio hasta to, en orden cronológico, con los
+	// mismos orígenes que VolumeEntries: los que el usuario registró en sus sesiones, o los sets
+	// del ejercicio que regían en cada sesión del usuario con su rutina.
+	ExerciseEntries(userID string, exerciseID uint, source string, to time.Time) ([]analytics.SetEntry, error)
+}
+
+// TokenStore accede a los tokens de renovación
+type TokenStore interface {
+	Create(token *models.RefreshToken) error
+	// FindByHash devuelve el token con ese hash (aunque esté usado, revocado o vencido)
+	FindByHash(hash string) (models.RefreshToken, error)
+	// MarkUsed marca el token como rotado; devuelve false si ya estaba usado
+	MarkUsed(id uint) (bool, error)
+	// RevokeFamily revoca todos los tokens de una sesión
+	RevokeFamily(familyID string) error
+	// FamilyActive indica si la sesión tiene algún token sin revocar
+	FamilyActive(familyID string) (bool, error)
+	// RevokeUser revoca todas las sesiones del usuario
+	RevokeUser(userID string) error
+
+	// CreateUserToken guarda un token de un solo uso enviado por correo
+	CreateUserToken(token *models.UserToken) error
+	// ConsumeUserToken marca como usado el token vigente con ese propósito y hash y lo devuelve.
+	// Devuelve ErrNotFound si no existe, ya se usó o venció.
+	ConsumeUserToken(purpose string, hash string) (models.UserToken, error)
+	// InvalidateUserTokens marca como usados los tokens pendientes del usuario con ese propósito
+	InvalidateUserTokens(userID string, purpose string) error
+
+	// CreateEmailRequest registra un correo pedido para una dirección
+	CreateEmailRequest(request *models.EmailRequest) error
+	// CountEmailRequests cuenta los correos con ese propósito pedidos para la dirección desde since
+	CountEmailRequests(email string, purpose string, since time.Time) (int64, error)
+}
+
+// ModerationChange es una decisión de moderación: sus efectos y su entrada en el registro de
+// auditoría. Los efectos en nil (o vacíos) no se aplican.
+type ModerationChange struct {
+	Entry models.ModerationAction
+	// RoutinePublic y RoutineHidden cambian la rutina de Entry.RoutineID
+	RoutinePublic *bool
+	RoutineHidden *bool
+	// UserDisabled deshabilita (o vuelve a habilitar) al usuario de Entry.TargetUserID; al
+	// deshabilitarlo también se revocan sus sesiones
+	UserDisabled *bool
+	// ReportStatus cierra con ese estado los reportes pendientes de Entry.RoutineID
+	ReportStatus string
+}
+
+// validate comprueba que la entrada indique la rutina y el usuario sobre los que actúan los efectos
+func (c *ModerationChange) validate() error {
+	if (c.RoutinePublic != nil || c.RoutineHidden != nil || c.ReportStatus != "") && c.Entry.RoutineID == nil {
+		return errors.New("la acción de moderación no indica la rutina")
+	}
+	if c.UserDisabled != nil && c.Entry.TargetUserID == nil {
+		return errors.New("la acción de moderación no indica el usuario")
+	}
+	return nil
+}
+
+// ModerationStore accede a los reportes de rutinas y al registro de auditoría de moderación
+type ModerationStore interface {
+	CreateReport(report *models.RoutineReport) error
+	// HasPendingReport indica si el usuario ya tiene un reporte pendiente sobre la rutina
+	HasPendingReport(routineID uint, reporterID string) (bool, error)
+	// ListReports devuelve los reportes con ese estado (todos si está vacío) junto con su rutina,
+	// los más antiguos primero, y el total
+	ListReports(status string, limit int, offset int) ([]models.RoutineReport, int64, error)
+	FindReport(id uint) (models.RoutineReport, error)
+
+	// Apply aplica los efectos de la acción de moderación, cierra los reportes y agrega la entrada
+	// al registro de auditoría en una transacción: si algo falla no se aplica nada. Devuelve
+	// ErrNotFound si la rutina o el usuario afectados no existen.
+	Apply(change *ModerationChange) error
+	// ListActions devuelve el registro de auditoría, lo más reciente primero, y el total
+	ListActions(limit int, offset int) ([]models.ModerationAction, int64, error)
+}